@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"blockchain/api/router"
+	"blockchain/internal/archive"
+	bc "blockchain/internal/blockchain"
+	"blockchain/pkg/config"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+type ArchiveController struct {
+	chain *bc.Blockchain
+}
+
+// NewArchiveController creates a new ArchiveController instance
+func NewArchiveController(chain *bc.Blockchain) *ArchiveController {
+	return &ArchiveController{chain: chain}
+}
+
+// HandleExport 以归档格式导出本节点的链: GET ?from=高度&snapshot=true
+func (c *ArchiveController) HandleExport(w http.ResponseWriter, r *http.Request) {
+	opts := archive.ExportOptions{Snapshot: r.URL.Query().Get("snapshot") == "true"}
+	if v := r.URL.Query().Get("from"); v != "" {
+		from, err := strconv.Atoi(v)
+		if err != nil {
+			router.WriteError(w, http.StatusBadRequest, "invalid_argument", "from must be an integer")
+			return
+		}
+		opts.From = from
+	}
+	if opts.Snapshot {
+		if _, ok := c.chain.LatestSnapshot(); !ok {
+			router.WriteError(w, http.StatusConflict, "conflict", archive.ErrNoSnapshot.Error())
+			return
+		}
+	}
+
+	// 完整的链归档可能很大，不受服务器默认写超时约束
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(config.ArchiveTimeout))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=chain-%d.bca", time.Now().Unix()))
+	if _, err := archive.Export(w, c.chain, opts); err != nil {
+		// 响应头已发出，只能中断输出，客户端读到截断的归档会校验失败
+		log.Printf("[归档] 导出失败: %v", err)
+	}
+}
+
+// HandleImport 导入请求体中的归档: POST ?from=续传高度
+func (c *ArchiveController) HandleImport(w http.ResponseWriter, r *http.Request) {
+	var opts archive.ImportOptions
+	if v := r.URL.Query().Get("from"); v != "" {
+		from, err := strconv.Atoi(v)
+		if err != nil {
+			router.WriteError(w, http.StatusBadRequest, "invalid_argument", "from must be an integer")
+			return
+		}
+		opts.From = from
+	}
+
+	// 上传归档可能持续很久，延长读写超时，同时单独限制归档大小
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Now().Add(config.ArchiveTimeout))
+	rc.SetWriteDeadline(time.Now().Add(config.ArchiveTimeout))
+	body := http.MaxBytesReader(w, r.Body, config.ArchiveMaxBytes)
+
+	// 导入前要先校验整个归档的校验和，请求体先落到临时文件以便回读
+	tmp, err := os.CreateTemp("", "chain-import-*.bca")
+	if err != nil {
+		log.Printf("[归档] 创建临时文件失败: %v", err)
+		router.WriteError(w, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if _, err := io.Copy(tmp, body); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			router.WriteError(w, http.StatusRequestEntityTooLarge, "too_large", fmt.Sprintf("archive exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		router.WriteError(w, http.StatusBadRequest, "invalid_argument", "failed to read archive")
+		return
+	}
+
+	result, err := archive.Import(tmp, c.chain, opts)
+	if err != nil {
+		status, code := http.StatusBadRequest, "invalid_argument"
+		if errors.Is(err, archive.ErrGenesisMismatch) || errors.Is(err, archive.ErrDiverged) {
+			status, code = http.StatusConflict, "conflict"
+		}
+		log.Printf("[归档] 导入失败: 已导入 %d 个区块，%v", result.Imported, err)
+		router.WriteError(w, status, code, err.Error())
+		return
+	}
+	log.Printf("[归档] 导入完成: 导入 %d 个区块，跳过 %d 个，当前高度 %d", result.Imported, result.Skipped, result.Height)
+
+	router.WriteJSON(w, http.StatusOK, result)
+}
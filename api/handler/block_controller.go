@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"blockchain/api/router"
+	"blockchain/internal/hash"
+	"blockchain/internal/storage"
+	"blockchain/pkg/config"
+	"blockchain/service"
+	"errors"
+	"net/http"
+)
+
+type BlockController struct{}
+
+// NewBlockController creates a new BlockController instance
+func NewBlockController() *BlockController {
+	return &BlockController{}
+}
+
+// ReplicaView 区块的一个副本所在节点
+type ReplicaView struct {
+	NodeID  string `json:"nodeId"`
+	Address string `json:"address,omitempty"`
+	Zone    string `json:"zone,omitempty"`
+}
+
+// PlacementView 区块的存放位置: 完整副本或纠删码分片，第一个副本为主副本
+type PlacementView struct {
+	BlockHash string                  `json:"blockHash"`
+	Primary   string                  `json:"primary"`
+	Replicas  []ReplicaView           `json:"replicas,omitempty"`
+	Shards    []storage.ShardLocation `json:"shards,omitempty"`
+}
+
+// HandleFetchBlock 从副本读取区块并按主链校验，返回区块和提供区块的节点
+func (c *BlockController) HandleFetchBlock(w http.ResponseWriter, r *http.Request) {
+	block, nodeID, err := storage.FetchBlock(r.PathValue("hash"))
+	switch {
+	case errors.Is(err, storage.ErrNotCanonical):
+		router.WriteError(w, http.StatusNotFound, "not_found", err.Error())
+		return
+	case errors.Is(err, storage.ErrNoCanonicalChain):
+		router.WriteError(w, http.StatusServiceUnavailable, "unavailable", err.Error())
+		return
+	case err != nil:
+		router.WriteError(w, http.StatusBadGateway, "unavailable", "failed to fetch block: "+err.Error())
+		return
+	}
+	router.WriteJSON(w, http.StatusOK, map[string]interface{}{"block": block, "nodeId": nodeID})
+}
+
+// HandleBlockReplicas 返回区块所有副本所在的节点，第一个为主副本
+func (c *BlockController) HandleBlockReplicas(w http.ResponseWriter, r *http.Request) {
+	blockHash := r.PathValue("hash")
+	view := PlacementView{BlockHash: blockHash}
+	if shards := storage.ShardLocations(blockHash); len(shards) > 0 {
+		// 纠删码区块没有完整副本，只返回分片位置
+		view.Primary = shards[0].NodeID
+		view.Shards = shards
+		router.WriteJSON(w, http.StatusOK, view)
+		return
+	}
+
+	replicaIDs := replicaOrder(blockHash)
+	if len(replicaIDs) == 0 {
+		router.WriteError(w, http.StatusNotFound, "not_found", "no node found for the given block hash")
+		return
+	}
+
+	view.Primary = replicaIDs[0]
+	for _, id := range replicaIDs {
+		replica := ReplicaView{NodeID: id}
+		if node := service.GetNodeByID(id); node != nil {
+			replica.Address = node.Address
+			replica.Zone = node.Zone
+		}
+		view.Replicas = append(view.Replicas, replica)
+	}
+	router.WriteJSON(w, http.StatusOK, view)
+}
+
+// replicaOrder 返回区块副本所在节点，第一个为主副本: 优先使用放置时记录的顺序，
+// 没有记录时按环上顺序排列实际保存了区块的节点，其余持有者排在最后；都没有时返回环上的位置
+func replicaOrder(blockHash string) []string {
+	if ids, ok := storage.Placement(blockHash); ok && len(ids) > 0 {
+		return ids
+	}
+	ringOrder, _ := hash.GetReplicas(blockHash, len(hash.Members()), service.NodeZone)
+	holders := storage.Locate(blockHash)
+	if len(holders) == 0 {
+		if len(ringOrder) > config.ReplicationFactor {
+			ringOrder = ringOrder[:config.ReplicationFactor]
+		}
+		return ringOrder
+	}
+	ordered := make([]string, 0, len(holders))
+	for _, id := range ringOrder {
+		if contains(holders, id) {
+			ordered = append(ordered, id)
+		}
+	}
+	for _, id := range holders {
+		if !contains(ordered, id) {
+			ordered = append(ordered, id)
+		}
+	}
+	return ordered
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,81 +1,212 @@
 package handler
 
 import (
+	"blockchain/api/router"
 	"blockchain/global"
 	"blockchain/internal/consensus"
-	"blockchain/internal/hash"
 	"blockchain/internal/network"
+	"blockchain/internal/storage"
+	"blockchain/pkg/config"
+	"blockchain/service"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
-	"sync"
-)
-
-var (
-	nodes []*network.Node
-	mu    sync.Mutex
+	"strconv"
 )
 
 type NodeController struct {
+	rebalancer *storage.Rebalancer
+	anchors    *consensus.AnchorManager
 }
 
 // NewNodeController creates a new NodeController instance
-func NewNodeController() *NodeController {
-	return &NodeController{}
+func NewNodeController(rebalancer *storage.Rebalancer, anchors *consensus.AnchorManager) *NodeController {
+	return &NodeController{rebalancer: rebalancer, anchors: anchors}
 }
 
+// HandleAddNode 将地址加入地址簿并立即拨号，握手成功后由RegisterPeer完成节点登记
 func (n *NodeController) HandleAddNode(w http.ResponseWriter, r *http.Request) {
-	id := r.URL.Query().Get("id")
-	if id == "" {
-		http.Error(w, "id is required", http.StatusBadRequest)
+	addr := r.URL.Query().Get("addr")
+	if addr == "" {
+		router.WriteError(w, http.StatusBadRequest, "invalid_argument", "addr is required")
+		return
+	}
+	if global.Discovery == nil {
+		router.WriteError(w, http.StatusServiceUnavailable, "unavailable", "peer discovery is not enabled")
 		return
 	}
 
-	no := network.NewNode(id)
+	if err := global.Discovery.AddAddress(addr); err != nil {
+		router.WriteError(w, http.StatusBadGateway, "unavailable", "failed to connect: "+err.Error())
+		return
+	}
+	router.WriteJSON(w, http.StatusAccepted, map[string]string{"addr": addr, "status": "connected"})
+}
+
+// RegisterPeer 对端握手完成后登记为节点并重新选举锚节点
+func (n *NodeController) RegisterPeer(p *network.Peer) {
+	no := network.NewRemoteNode(p.ID, p.PublicKey, p.Addr, p.Stats)
+	no.Zone = p.Zone
+	for k, v := range p.Labels {
+		no.Labels[k] = v
+	}
 	no.CalculateScore(no)
 
-	mu.Lock()
-	global.NodesMap[id] = no
-	nodes = append(nodes, no)
-	hash.AddNode(id)
-	mu.Unlock()
+	// 新节点加入环后接管的键范围由迁移器从旧节点迁移过来
+	var err error
+	n.rebalancer.OnMembershipChange(func() {
+		err = service.RegisterNode(no)
+	})
+	if err != nil {
+		if !errors.Is(err, service.ErrNodeExists) {
+			log.Printf("[节点登记] 拒绝节点 %s: %v", p.ID, err)
+		}
+		return
+	}
 
-	rf := consensus.NewRaft()
-	anchor := rf.ElectAnchor(nodes)
+	n.anchors.Elect()
+}
 
-	// 启动锚节点监听器
-	if anchor != nil {
-		rf.StartAnchorListener(anchor.ID, global.BlockAssignChan)
+// HandleElection 返回最近一次VRF选举结果，任何人都可以据此验证锚节点的VRF证明和抽签
+func (n *NodeController) HandleElection(w http.ResponseWriter, r *http.Request) {
+	result := n.anchors.Election()
+	if result == nil {
+		router.WriteError(w, http.StatusNotFound, "not_found", "no VRF election has been held")
+		return
 	}
+	router.WriteJSON(w, http.StatusOK, result)
 }
 
-func (n *NodeController) HandleListNodes(w http.ResponseWriter, r *http.Request) {
-	mu.Lock()
-	defer mu.Unlock()
-	json.NewEncoder(w).Encode(global.NodesMap)
+// HandleRemoveNode 下线节点: 默认先排空并迁移副本，迁完后删除；force=true时立即删除
+func (n *NodeController) HandleRemoveNode(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	node := service.GetNodeByID(id)
+	if node == nil {
+		router.WriteError(w, http.StatusNotFound, "not_found", "node "+id+" not found")
+		return
+	}
+	// 强制下线后节点已不在节点表中，需在下线前确认它是否为锚节点
+	wasAnchor := service.IsCurrentNodeAnchor(id)
+	if err := n.rebalancer.Decommission(id, force); err != nil {
+		router.WriteServiceError(w, err)
+		return
+	}
+	if wasAnchor {
+		n.reelectAnchor()
+	}
+
+	status := "draining"
+	if force {
+		status = "removed"
+	}
+	router.WriteJSON(w, http.StatusAccepted, map[string]interface{}{
+		"id":      id,
+		"status":  status,
+		"pending": storage.NodeLoad(id),
+	})
 }
 
-func (n *NodeController) HandleQueryNode(w http.ResponseWriter, r *http.Request) {
-	blockHash := r.URL.Query().Get("block_hash")
-	if blockHash == "" {
-		http.Error(w, "block_hash is required", http.StatusBadRequest)
+// HandleDrainNode 排空节点: 不再分配新区块，已有副本迁移到其他节点
+func (n *NodeController) HandleDrainNode(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := n.rebalancer.Drain(id); err != nil {
+		router.WriteServiceError(w, err)
 		return
 	}
-	targetNodeID, _ := hash.GetNode(blockHash)
-	if targetNodeID == "" {
-		http.Error(w, "no node found for the given block hash", http.StatusNotFound)
+	if service.IsCurrentNodeAnchor(id) {
+		n.reelectAnchor()
+	}
+	writeNode(w, http.StatusAccepted, id)
+}
+
+// HandleUndrainNode 取消排空，节点重新参与区块分配
+func (n *NodeController) HandleUndrainNode(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := n.rebalancer.Undrain(id); err != nil {
+		router.WriteServiceError(w, err)
 		return
 	}
+	// 所有节点都曾排空时没有锚节点，节点恢复后重新选举
+	if n.anchors.Anchor() == nil {
+		n.reelectAnchor()
+	}
+	writeNode(w, http.StatusOK, id)
+}
 
-	rsp := map[string]interface{}{
-		"block_hash": blockHash,
-		"node_id":    targetNodeID,
+// writeNode 返回节点的只读副本
+func writeNode(w http.ResponseWriter, status int, id string) {
+	view, err := service.ViewNode(id)
+	if err != nil {
+		router.WriteServiceError(w, err)
+		return
+	}
+	router.WriteJSON(w, status, view)
+}
+
+// HandleUpdateNode 修改节点的故障域、标签和容量权重，影响放置的修改会触发副本迁移
+func (n *NodeController) HandleUpdateNode(w http.ResponseWriter, r *http.Request) {
+	var spec service.NodeSpec
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, config.APIMaxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&spec); err != nil {
+		router.WriteError(w, http.StatusBadRequest, "invalid_argument", "invalid request body: "+err.Error())
+		return
+	}
+
+	var node network.NodeView
+	var err error
+	n.rebalancer.OnMembershipChange(func() {
+		node, err = service.UpdateNode(r.PathValue("id"), spec)
+	})
+	if err != nil {
+		router.WriteServiceError(w, err)
+		return
 	}
+	router.WriteJSON(w, http.StatusOK, node)
+}
+
+// HandleRescoreNode 立即重新检查节点健康状态并计算评分
+func (n *NodeController) HandleRescoreNode(w http.ResponseWriter, r *http.Request) {
+	var node network.NodeView
+	var err error
+	n.rebalancer.OnMembershipChange(func() {
+		node, err = service.RescoreNode(r.PathValue("id"))
+	})
+	if err != nil {
+		router.WriteServiceError(w, err)
+		return
+	}
+	router.WriteJSON(w, http.StatusOK, node)
+}
+
+// HandleNodeBlocks 返回节点当前保存的区块
+func (n *NodeController) HandleNodeBlocks(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if service.GetNodeByID(id) == nil {
+		router.WriteError(w, http.StatusNotFound, "not_found", "node "+id+" not found")
+		return
+	}
+	blocks := storage.HeldBlocks(id)
+	total := 0
+	for _, b := range blocks {
+		total += b.Bytes
+	}
+	router.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"nodeId": id,
+		"count":  len(blocks),
+		"bytes":  total,
+		"blocks": blocks,
+		"shards": storage.NodeShards(id),
+	})
+}
 
-	// 5. 返回 JSON 响应
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(rsp); err != nil {
-		log.Printf("Failed to encode response: %v", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+// reelectAnchor 锚节点排空或下线后在其余节点中重新选举，旧监听器由AnchorManager停止
+func (n *NodeController) reelectAnchor() {
+	if anchor := n.anchors.Elect(); anchor != nil {
+		log.Printf("[节点管理] 锚节点已重新选举为 %s", anchor.ID)
+	} else {
+		log.Printf("[节点管理] 没有可接任的锚节点，区块分发暂停")
 	}
 }
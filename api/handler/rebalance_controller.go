@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"blockchain/api/router"
+	"blockchain/internal/storage"
+	"net/http"
+)
+
+type RebalanceController struct {
+	rebalancer *storage.Rebalancer
+}
+
+// NewRebalanceController creates a new RebalanceController instance
+func NewRebalanceController(rebalancer *storage.Rebalancer) *RebalanceController {
+	return &RebalanceController{rebalancer: rebalancer}
+}
+
+// HandleRebalanceStatus 返回数据迁移进度
+func (c *RebalanceController) HandleRebalanceStatus(w http.ResponseWriter, r *http.Request) {
+	router.WriteJSON(w, http.StatusOK, c.rebalancer.Status())
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"blockchain/api/router"
+	"blockchain/internal/storage"
+	"net/http"
+)
+
+type RepairController struct {
+	antiEntropy *storage.AntiEntropy
+	challenger  *storage.Challenger
+}
+
+// NewRepairController creates a new RepairController instance
+func NewRepairController(antiEntropy *storage.AntiEntropy, challenger *storage.Challenger) *RepairController {
+	return &RepairController{antiEntropy: antiEntropy, challenger: challenger}
+}
+
+// HandleRepairStatus 返回反熵修复指标、存储证明挑战统计和待修复的损坏副本
+func (c *RepairController) HandleRepairStatus(w http.ResponseWriter, r *http.Request) {
+	router.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"stats":      c.antiEntropy.Stats(),
+		"challenges": c.challenger.Stats(),
+		"corrupt":    storage.CorruptReplicas(),
+	})
+}
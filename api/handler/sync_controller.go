@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"blockchain/api/router"
+	"blockchain/internal/network"
+	"net/http"
+)
+
+type SyncController struct {
+	syncer *network.Syncer
+}
+
+// NewSyncController creates a new SyncController instance
+func NewSyncController(syncer *network.Syncer) *SyncController {
+	return &SyncController{syncer: syncer}
+}
+
+// HandleSyncStatus 返回同步状态: 是否同步中、目标高度和进度
+func (s *SyncController) HandleSyncStatus(w http.ResponseWriter, r *http.Request) {
+	if s.syncer == nil {
+		router.WriteError(w, http.StatusServiceUnavailable, "unavailable", "sync is not enabled")
+		return
+	}
+	router.WriteJSON(w, http.StatusOK, s.syncer.Status())
+}
@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"blockchain/pkg/config"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid api key")
+	ErrForbidden          = errors.New("insufficient role")
+)
+
+// Role 调用方角色，高级别角色拥有低级别角色的全部权限
+type Role int
+
+const (
+	RoleNone      Role = iota // 无需认证
+	RoleReadOnly              // 查询链、交易、节点
+	RoleSubmitter             // 提交交易
+	RoleOperator              // 管理节点、导出链
+	RoleAdmin                 // 导入链等破坏性操作
+)
+
+var roleNames = map[Role]string{
+	RoleNone:      "none",
+	RoleReadOnly:  "read-only",
+	RoleSubmitter: "submitter",
+	RoleOperator:  "operator",
+	RoleAdmin:     "admin",
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("role(%d)", int(r))
+}
+
+// ParseRole 按名称解析角色
+func ParseRole(name string) (Role, error) {
+	for r, n := range roleNames {
+		if n == name && r != RoleNone {
+			return r, nil
+		}
+	}
+	return RoleNone, fmt.Errorf("unknown role %q", name)
+}
+
+// 认证方式
+const (
+	MethodAnonymous = "anonymous"
+	MethodAPIKey    = "api-key"
+	MethodJWT       = "jwt"
+)
+
+// Principal 已认证的调用方
+type Principal struct {
+	Name   string `json:"name"`
+	Role   Role   `json:"role"`
+	Method string `json:"method"`
+}
+
+// Anonymous 未携带凭证的调用方
+func (p Principal) Anonymous() bool {
+	return p.Method == MethodAnonymous
+}
+
+type principalKey struct{}
+
+// PrincipalFrom 返回请求的调用方，未经过认证中间件时为匿名且无任何角色
+func PrincipalFrom(ctx context.Context) Principal {
+	if p, ok := ctx.Value(principalKey{}).(Principal); ok {
+		return p
+	}
+	return Principal{Method: MethodAnonymous}
+}
+
+func withPrincipal(r *http.Request, p Principal) *http.Request {
+	if entry, ok := r.Context().Value(accessKey{}).(*accessEntry); ok && !p.Anonymous() {
+		entry.principal = p.Name
+	}
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, p))
+}
+
+// APIKeyHeader 携带API密钥的请求头
+const APIKeyHeader = "X-API-Key"
+
+type apiKey struct {
+	name string
+	role Role
+}
+
+// Authenticator 校验API密钥和JWT；密钥文件只保存密钥的SHA-256，不保存明文
+type Authenticator struct {
+	mu       sync.RWMutex
+	keysPath string
+	keys     map[string]apiKey // 密钥SHA-256 -> 名称和角色
+	secret   []byte            // JWT签名密钥，为空时不接受JWT
+}
+
+// NewAuthenticator 加载密钥文件和JWT签名密钥，文件不存在时视为未配置
+func NewAuthenticator(keysPath, secretPath string) (*Authenticator, error) {
+	a := &Authenticator{keysPath: keysPath, keys: make(map[string]apiKey)}
+	if err := a.Reload(); err != nil {
+		return nil, err
+	}
+	if secretPath != "" {
+		secret, err := os.ReadFile(secretPath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("read jwt secret: %w", err)
+		}
+		a.secret = []byte(strings.TrimSpace(string(secret)))
+	}
+	return a, nil
+}
+
+// Reload 重新读取密钥文件，每行 "<名称> <角色> <密钥SHA-256>"，#开头为注释
+func (a *Authenticator) Reload() error {
+	if a.keysPath == "" {
+		return nil
+	}
+	f, err := os.Open(a.keysPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	keys := make(map[string]apiKey)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return fmt.Errorf("invalid api key entry %q", line)
+		}
+		role, err := ParseRole(fields[1])
+		if err != nil {
+			return err
+		}
+		keys[strings.ToLower(fields[2])] = apiKey{name: fields[0], role: role}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.keys = keys
+	a.mu.Unlock()
+	return nil
+}
+
+// AddKey 在内存中登记一个API密钥
+func (a *Authenticator) AddKey(name string, role Role, key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.keys[HashKey(key)] = apiKey{name: name, role: role}
+}
+
+// SetSecret 设置JWT签名密钥
+func (a *Authenticator) SetSecret(secret []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.secret = secret
+}
+
+// Authenticate 依次检查X-API-Key和Authorization: Bearer，都没有时返回匿名调用方；
+// 匿名调用方在 config.APIAnonymousRead 开启时拥有只读角色
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		a.mu.RLock()
+		k, ok := a.keys[HashKey(key)]
+		a.mu.RUnlock()
+		if !ok {
+			return Principal{}, ErrInvalidCredentials
+		}
+		return Principal{Name: k.name, Role: k.role, Method: MethodAPIKey}, nil
+	}
+
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, token, _ := strings.Cut(auth, " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			return Principal{}, fmt.Errorf("%w: unsupported authorization scheme %q", ErrInvalidToken, scheme)
+		}
+		a.mu.RLock()
+		secret := a.secret
+		a.mu.RUnlock()
+		if len(secret) == 0 {
+			return Principal{}, fmt.Errorf("%w: jwt authentication is not configured", ErrInvalidToken)
+		}
+		claims, err := VerifyToken(secret, strings.TrimSpace(token))
+		if err != nil {
+			return Principal{}, err
+		}
+		role, err := ParseRole(claims.Role)
+		if err != nil {
+			return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+		}
+		return Principal{Name: claims.Subject, Role: role, Method: MethodJWT}, nil
+	}
+
+	p := Principal{Method: MethodAnonymous}
+	if config.APIAnonymousRead {
+		p.Role = RoleReadOnly
+	}
+	return p, nil
+}
+
+// HashKey 返回密钥的SHA-256，用于生成密钥文件条目
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+type accessKey struct{}
+
+// accessEntry 一条访问日志，认证中间件在其中记录调用方
+type accessEntry struct {
+	principal string
+}
+
+// AccessLog 请求结束后记录方法、路径、状态码、响应大小、耗时、请求ID和调用方
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &accessEntry{principal: "-"}
+		rec := &recorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), accessKey{}, entry)))
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		log.Printf("[API] %s %s %s %d %dB %s id=%s user=%s",
+			ClientIP(r), r.Method, r.URL.Path, status, rec.bytes,
+			time.Since(start).Round(time.Microsecond), RequestIDFrom(r.Context()), entry.principal)
+	})
+}
+
+// recorder 记录响应状态码和大小；实现Flush、Hijack和Unwrap，
+// 使SSE推送、WebSocket升级和http.ResponseController在中间件之后仍然可用
+type recorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(p)
+	rec.bytes += n
+	return n, err
+}
+
+func (rec *recorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rec *recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	conn, rw, err := hj.Hijack()
+	if err == nil && rec.status == 0 {
+		rec.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+func (rec *recorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// wroteHeader 判断是否已经开始写响应
+func (rec *recorder) wroteHeader() bool {
+	return rec.status != 0
+}
@@ -0,0 +1,36 @@
+// Package middleware 提供API的请求ID、访问日志、异常恢复、认证鉴权和限流中间件
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"blockchain/pkg/config"
+)
+
+// Middleware 包装一个http.Handler
+type Middleware func(http.Handler) http.Handler
+
+// Chain 按顺序包装handler，第一个中间件在最外层
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// ClientIP 返回客户端IP，config.APITrustProxy开启时取X-Forwarded-For中的第一个地址
+func ClientIP(r *http.Request) string {
+	if config.APITrustProxy {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			first, _, _ := strings.Cut(fwd, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"blockchain/api/router"
+	"blockchain/api/rpc"
+	"blockchain/pkg/config"
+)
+
+// Limit 令牌桶参数，零值使用config中的默认值，Rate为负数表示不限流
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Rule 一条路由的访问策略，Pattern与http.ServeMux的模式语法相同
+type Rule struct {
+	Pattern string // 如 "POST /api/v1/transactions"、"/ui/"
+	Role    Role   // 最低角色，RoleNone表示无需认证
+	PerIP   Limit  // 每个客户端IP的限流
+	PerKey  Limit  // 每个API密钥或JWT主体的限流，匿名请求只受PerIP限制
+}
+
+// DefaultRules 默认策略: 查询接口只读，提交交易需要submitter，节点管理和导出链需要operator，导入链需要admin
+var DefaultRules = []Rule{
+	{Pattern: "/ui/", Role: RoleNone},
+	{Pattern: "GET " + router.Prefix + "/", Role: RoleReadOnly},
+	{Pattern: "POST " + router.Prefix + "/transactions", Role: RoleSubmitter, PerKey: Limit{Rate: 10, Burst: 20}},
+	{Pattern: "POST " + router.Prefix + "/rpc", Role: RoleReadOnly}, // 具体方法由RPCAuthorizer检查
+	{Pattern: "POST " + router.Prefix + "/nodes", Role: RoleOperator},
+	{Pattern: "DELETE " + router.Prefix + "/nodes/{id}", Role: RoleOperator},
+	{Pattern: "PATCH " + router.Prefix + "/nodes/{id}", Role: RoleOperator},
+	{Pattern: "POST " + router.Prefix + "/nodes/{id}/drain", Role: RoleOperator},
+	{Pattern: "DELETE " + router.Prefix + "/nodes/{id}/drain", Role: RoleOperator},
+	{Pattern: "POST " + router.Prefix + "/nodes/{id}/rescore", Role: RoleOperator},
+	{Pattern: "GET " + router.Prefix + "/repair", Role: RoleOperator}, // 损坏副本位置等修复细节只对运维可见
+	{Pattern: "GET " + router.Prefix + "/chain/export", Role: RoleOperator},
+	{Pattern: "POST " + router.Prefix + "/chain/import", Role: RoleAdmin},
+	{Pattern: "/", Role: RoleOperator}, // 其余路由
+}
+
+// RPCMethodRoutes JSON-RPC方法对应的REST路由，调用该方法按路由的规则检查角色，
+// 并与REST接口共用同一个令牌桶；未列出的方法只需只读
+var RPCMethodRoutes = map[string]string{
+	"tx_send": "POST " + router.Prefix + "/transactions",
+}
+
+// route 一条规则及其限流器，每条规则的令牌桶相互独立
+type route struct {
+	rule Rule
+	ip   *Limiter
+	key  *Limiter
+}
+
+// Policy 按路由匹配访问策略，依次执行IP限流、认证、密钥限流和角色检查
+type Policy struct {
+	auth     *Authenticator
+	mux      *http.ServeMux
+	routes   map[string]*route
+	fallback *route
+}
+
+// NewPolicy 按规则创建策略，没有"/"规则时未匹配的请求需要operator角色；
+// 规则模式冲突时返回错误
+func NewPolicy(auth *Authenticator, rules []Rule) (p *Policy, err error) {
+	p = &Policy{
+		auth:     auth,
+		mux:      http.NewServeMux(),
+		routes:   make(map[string]*route),
+		fallback: newRoute(Rule{Pattern: "/", Role: RoleOperator}),
+	}
+	defer func() {
+		// ServeMux对冲突的模式直接panic
+		if v := recover(); v != nil {
+			p, err = nil, fmt.Errorf("invalid rule: %v", v)
+		}
+	}()
+	for _, rule := range rules {
+		p.mux.Handle(rule.Pattern, http.NotFoundHandler())
+		p.routes[rule.Pattern] = newRoute(rule)
+	}
+	return p, nil
+}
+
+func newRoute(rule Rule) *route {
+	return &route{
+		rule: rule,
+		ip:   NewLimiter(orDefault(rule.PerIP, config.RateLimitPerIP, config.RateBurstPerIP)),
+		key:  NewLimiter(orDefault(rule.PerKey, config.RateLimitPerKey, config.RateBurstPerKey)),
+	}
+}
+
+func orDefault(l Limit, rate float64, burst int) (float64, int) {
+	if l.Rate == 0 {
+		return rate, burst
+	}
+	return l.Rate, l.Burst
+}
+
+// match 返回请求匹配的规则
+func (p *Policy) match(r *http.Request) *route {
+	if _, pattern := p.mux.Handler(r); pattern != "" {
+		if rt, ok := p.routes[pattern]; ok {
+			return rt
+		}
+	}
+	return p.fallback
+}
+
+// Handler 返回执行该策略的中间件，认证通过后调用方可由PrincipalFrom取得
+func (p *Policy) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rt := p.match(r)
+		if !allow(w, rt.ip, ClientIP(r)) {
+			return
+		}
+		if rt.rule.Role == RoleNone {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, err := p.auth.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			router.WriteError(w, http.StatusUnauthorized, "unauthenticated", err.Error())
+			return
+		}
+		if !principal.Anonymous() && !allow(w, rt.key, principal.Method+":"+principal.Name) {
+			return
+		}
+		if principal.Role < rt.rule.Role {
+			if principal.Anonymous() {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				router.WriteError(w, http.StatusUnauthorized, "unauthenticated", "authentication required")
+				return
+			}
+			router.WriteError(w, http.StatusForbidden, "forbidden",
+				fmt.Sprintf("%s requires role %s, have %s", r.URL.Path, rt.rule.Role, principal.Role))
+			return
+		}
+		next.ServeHTTP(w, withPrincipal(r, principal))
+	})
+}
+
+// allow 从令牌桶取令牌，写入限流响应头；被拒绝时返回429
+func allow(w http.ResponseWriter, l *Limiter, key string) bool {
+	ok, remaining, retry := l.Allow(key)
+	if rate, burst := l.Limit(); rate > 0 {
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(burst))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	}
+	if !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+		router.WriteError(w, http.StatusTooManyRequests, "rate_limited", "too many requests")
+	}
+	return ok
+}
+
+// RPCAuthorizer 返回JSON-RPC方法级别的权限检查，批量请求和WebSocket消息中的每次调用
+// 都单独检查角色并从对应路由的调用方令牌桶扣减；路由不存在时按未匹配规则处理
+func (p *Policy) RPCAuthorizer(methods map[string]string) rpc.Authorizer {
+	return func(ctx context.Context, method string) error {
+		principal := PrincipalFrom(ctx)
+		pattern, listed := methods[method]
+		if !listed {
+			if principal.Role < RoleReadOnly {
+				return fmt.Errorf("%w: %s requires role %s, have %s", ErrForbidden, method, RoleReadOnly, principal.Role)
+			}
+			return nil
+		}
+		rt, ok := p.routes[pattern]
+		if !ok {
+			rt = p.fallback
+		}
+		if principal.Role < rt.rule.Role {
+			return fmt.Errorf("%w: %s requires role %s, have %s", ErrForbidden, method, rt.rule.Role, principal.Role)
+		}
+		if !principal.Anonymous() {
+			if ok, _, retry := rt.key.Allow(principal.Method + ":" + principal.Name); !ok {
+				return fmt.Errorf("%w: %s, retry after %s", rpc.ErrRateLimited, method, retry.Round(time.Millisecond))
+			}
+		}
+		return nil
+	}
+}
+
+// MessageLimiter 返回WebSocket消息的限流，每条消息按pattern路由的规则扣减客户端IP和调用方的令牌，
+// 与HTTP请求共用令牌桶
+func (p *Policy) MessageLimiter(pattern string) rpc.MessageLimiter {
+	rt, ok := p.routes[pattern]
+	if !ok {
+		rt = p.fallback
+	}
+	return func(r *http.Request) error {
+		if ok, _, retry := rt.ip.Allow(ClientIP(r)); !ok {
+			return fmt.Errorf("%w: retry after %s", rpc.ErrRateLimited, retry.Round(time.Millisecond))
+		}
+		if principal := PrincipalFrom(r.Context()); !principal.Anonymous() {
+			if ok, _, retry := rt.key.Allow(principal.Method + ":" + principal.Name); !ok {
+				return fmt.Errorf("%w: retry after %s", rpc.ErrRateLimited, retry.Round(time.Millisecond))
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"blockchain/pkg/config"
+)
+
+// bucket 令牌桶，tokens按rate匀速补充，最多burst个
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter 按键(API密钥、JWT主体或IP)分别维护令牌桶
+type Limiter struct {
+	rate  float64 // 每秒补充的令牌数
+	burst int
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewLimiter 创建限流器，rate<=0时不限流
+func NewLimiter(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+	return &Limiter{rate: rate, burst: burst, buckets: make(map[string]*bucket), lastSweep: time.Now()}
+}
+
+// Allow 从key的令牌桶取一个令牌；被拒绝时返回需要等待的时间，remaining为剩余令牌数
+func (l *Limiter) Allow(key string) (ok bool, remaining int, retryAfter time.Duration) {
+	if l == nil || l.rate <= 0 {
+		return true, 0, 0
+	}
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, 0, wait
+	}
+	b.tokens--
+	return true, int(b.tokens), 0
+}
+
+// Limit 每秒请求数和突发数
+func (l *Limiter) Limit() (float64, int) {
+	return l.rate, l.burst
+}
+
+// sweep 回收闲置超过 config.RateLimitIdle 的令牌桶，闲置这么久的桶早已补满，删除不影响限流结果；
+// 调用方需持有l.mu
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < config.RateLimitIdle {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > config.RateLimitIdle {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
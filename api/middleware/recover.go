@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"blockchain/api/router"
+)
+
+// Recover 捕获处理函数中的panic并记录调用栈，尚未写响应时返回500，避免整个进程退出
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec, ok := w.(*recorder)
+		if !ok {
+			rec = &recorder{ResponseWriter: w}
+		}
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err) // 由net/http静默中断连接
+			}
+			log.Printf("[API] 请求 %s %s 处理异常 id=%s: %v\n%s", r.Method, r.URL.Path, RequestIDFrom(r.Context()), err, debug.Stack())
+			if !rec.wroteHeader() {
+				router.WriteError(rec, http.StatusInternalServerError, "internal", "internal server error")
+			}
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
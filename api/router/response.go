@@ -0,0 +1,69 @@
+package router
+
+import (
+	"blockchain/service"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// ErrorBody 所有接口统一的错误响应
+type ErrorBody struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail 错误码为机器可读的短字符串，消息面向调用方
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+func WriteError(w http.ResponseWriter, status int, code, message string) {
+	WriteJSON(w, status, ErrorBody{Error: ErrorDetail{Code: code, Message: message}})
+}
+
+// WriteServiceError 按服务层错误分类映射状态码
+func WriteServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		WriteError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, service.ErrInvalidArgument):
+		WriteError(w, http.StatusBadRequest, "invalid_argument", err.Error())
+	case errors.Is(err, service.ErrConflict):
+		WriteError(w, http.StatusConflict, "conflict", err.Error())
+	case errors.Is(err, service.ErrPruned):
+		WriteError(w, http.StatusGone, "pruned", err.Error())
+	case errors.Is(err, service.ErrUnavailable):
+		WriteError(w, http.StatusServiceUnavailable, "unavailable", err.Error())
+	default:
+		log.Printf("[API] 内部错误: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal", "internal server error")
+	}
+}
+
+// parsePage 解析offset和limit查询参数
+func parsePage(r *http.Request) (service.Page, error) {
+	var p service.Page
+	var err error
+	if v := r.URL.Query().Get("offset"); v != "" {
+		if p.Offset, err = strconv.Atoi(v); err != nil || p.Offset < 0 {
+			return p, errors.New("offset must be a non-negative integer")
+		}
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if p.Limit, err = strconv.Atoi(v); err != nil || p.Limit <= 0 {
+			return p, errors.New("limit must be a positive integer")
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,194 @@
+package router
+
+import (
+	bc "blockchain/internal/blockchain"
+	"blockchain/pkg/config"
+	"blockchain/service"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Prefix 当前版本API的路径前缀
+const Prefix = "/api/v1"
+
+// Router /api/v1接口，处理函数只做参数解析和响应编码，业务逻辑在service层
+type Router struct {
+	svc *service.ChainService
+	mux *http.ServeMux
+}
+
+// New 创建路由并注册全部接口
+func New(svc *service.ChainService) *Router {
+	rt := &Router{svc: svc, mux: http.NewServeMux()}
+	rt.handle("GET /chain", rt.chainInfo)
+	rt.handle("GET /blocks", rt.listBlocks)
+	rt.handle("GET /blocks/{height}", rt.blockByHeight)
+	rt.handle("GET /blocks/hash/{hash}", rt.blockByHash)
+	rt.handle("GET /transactions/{id}", rt.transaction)
+	rt.handle("POST /transactions", rt.submitTransaction)
+	rt.handle("GET /mempool", rt.mempool)
+	rt.handle("GET /accounts/{address}", rt.account)
+	rt.handle("GET /accounts/{address}/transactions", rt.accountHistory)
+	rt.handle("GET /nodes", rt.listNodes)
+	rt.handle("GET /nodes/{id}", rt.node)
+	rt.handle("GET /nodes/{id}/health", rt.nodeHealth)
+	rt.handle("GET /anchor", rt.anchor)
+	rt.handle("GET /ring", rt.ring)
+	return rt
+}
+
+// handle 注册带版本前缀的路由，pattern形如"GET /blocks"
+func (rt *Router) handle(pattern string, h http.HandlerFunc) {
+	method, path, _ := strings.Cut(pattern, " ")
+	rt.mux.HandleFunc(method+" "+Prefix+path, h)
+}
+
+// Handle 在版本前缀下注册其他模块的路由
+func (rt *Router) Handle(pattern string, h http.Handler) {
+	method, path, _ := strings.Cut(pattern, " ")
+	rt.mux.Handle(method+" "+Prefix+path, h)
+}
+
+// ServeHTTP 未匹配的请求返回JSON格式的404或405，而不是ServeMux默认的纯文本
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, pattern := rt.mux.Handler(r); pattern != "" {
+		rt.mux.ServeHTTP(w, r)
+		return
+	}
+	var allowed []string
+	for _, m := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		probe := r.Clone(r.Context())
+		probe.Method = m
+		if _, pattern := rt.mux.Handler(probe); pattern != "" {
+			allowed = append(allowed, m)
+		}
+	}
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", r.Method+" is not allowed on "+r.URL.Path)
+		return
+	}
+	WriteError(w, http.StatusNotFound, "not_found", "no route for "+r.URL.Path)
+}
+
+func (rt *Router) chainInfo(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, rt.svc.Info())
+}
+
+func (rt *Router) listBlocks(w http.ResponseWriter, r *http.Request) {
+	p, err := parsePage(r)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_argument", err.Error())
+		return
+	}
+	WriteJSON(w, http.StatusOK, rt.svc.Blocks(p))
+}
+
+func (rt *Router) blockByHeight(w http.ResponseWriter, r *http.Request) {
+	height, err := strconv.Atoi(r.PathValue("height"))
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_argument", "height must be an integer")
+		return
+	}
+	block, err := rt.svc.BlockByHeight(height)
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, block)
+}
+
+func (rt *Router) blockByHash(w http.ResponseWriter, r *http.Request) {
+	block, err := rt.svc.BlockByHash(r.PathValue("hash"))
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, block)
+}
+
+func (rt *Router) transaction(w http.ResponseWriter, r *http.Request) {
+	tx, err := rt.svc.Transaction(r.PathValue("id"))
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, tx)
+}
+
+// submitTransaction 提交已签名的交易，成功返回202和交易ID，交易上链后可按ID查询状态
+func (rt *Router) submitTransaction(w http.ResponseWriter, r *http.Request) {
+	var tx bc.Transaction
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, config.APIMaxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&tx); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+	id, err := rt.svc.SubmitTransaction(tx)
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+	w.Header().Set("Location", Prefix+"/transactions/"+id)
+	WriteJSON(w, http.StatusAccepted, map[string]string{"id": id, "status": "pending"})
+}
+
+func (rt *Router) mempool(w http.ResponseWriter, r *http.Request) {
+	p, err := parsePage(r)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_argument", err.Error())
+		return
+	}
+	WriteJSON(w, http.StatusOK, rt.svc.Mempool(p))
+}
+
+func (rt *Router) account(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, rt.svc.Account(r.PathValue("address")))
+}
+
+func (rt *Router) accountHistory(w http.ResponseWriter, r *http.Request) {
+	p, err := parsePage(r)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_argument", err.Error())
+		return
+	}
+	WriteJSON(w, http.StatusOK, rt.svc.AccountHistory(r.PathValue("address"), p))
+}
+
+func (rt *Router) listNodes(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, rt.svc.Nodes())
+}
+
+func (rt *Router) node(w http.ResponseWriter, r *http.Request) {
+	node, err := rt.svc.Node(r.PathValue("id"))
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, node)
+}
+
+func (rt *Router) nodeHealth(w http.ResponseWriter, r *http.Request) {
+	samples, err := rt.svc.NodeHealth(r.PathValue("id"))
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, samples)
+}
+
+func (rt *Router) anchor(w http.ResponseWriter, r *http.Request) {
+	node, err := rt.svc.Anchor()
+	if err != nil {
+		WriteServiceError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, node)
+}
+
+func (rt *Router) ring(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, rt.svc.Ring())
+}
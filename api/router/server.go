@@ -0,0 +1,92 @@
+package router
+
+import (
+	"blockchain/internal/security"
+	"blockchain/pkg/config"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+)
+
+// Server API服务器，启用TLS时与节点间通信使用同一套证书做双向认证
+type Server struct {
+	srv  *http.Server
+	ln   net.Listener
+	stop chan struct{} // 关闭时停止重新读取吊销名单
+}
+
+// NewServer 创建监听addr的服务器
+func NewServer(addr string, handler http.Handler) *Server {
+	return &Server{
+		srv: &http.Server{
+			Addr:         addr,
+			Handler:      handler,
+			ReadTimeout:  config.APIReadTimeout,
+			WriteTimeout: config.APIWriteTimeout,
+		},
+		stop: make(chan struct{}),
+	}
+}
+
+// Start 开始监听并在后台处理请求，监听失败时返回错误
+func (s *Server) Start() error {
+	if config.TLSEnabled {
+		tlsConfig := security.TLSConfig{
+			CAFile:       config.TLSCAFile,
+			CertFile:     config.TLSCertFile,
+			KeyFile:      config.TLSKeyFile,
+			DenyListFile: config.TLSDenyList,
+		}
+		server, _, deny, err := tlsConfig.Load()
+		if err != nil {
+			return err
+		}
+		s.srv.TLSConfig = server
+		// 与节点间传输层按同一周期重新读取吊销名单，吊销的客户端证书无需重启即被拒绝
+		deny.Watch(config.TLSDenyListReload, s.stop)
+	}
+
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return err
+	}
+	s.ln = ln
+
+	go func() {
+		var err error
+		if s.srv.TLSConfig != nil {
+			err = s.srv.ServeTLS(ln, "", "")
+		} else {
+			err = s.srv.Serve(ln)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[API] 服务异常退出: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Addr 返回实际监听的地址
+func (s *Server) Addr() string {
+	if s.ln == nil {
+		return s.srv.Addr
+	}
+	return s.ln.Addr().String()
+}
+
+// OnShutdown 注册关闭时调用的函数，用于结束WebSocket/SSE等长连接
+func (s *Server) OnShutdown(f func()) {
+	s.srv.RegisterOnShutdown(f)
+}
+
+// Shutdown 停止接受新连接，等待进行中的请求完成或ctx到期
+func (s *Server) Shutdown(ctx context.Context) error {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	return s.srv.Shutdown(ctx)
+}
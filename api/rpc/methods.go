@@ -0,0 +1,105 @@
+package rpc
+
+import (
+	bc "blockchain/internal/blockchain"
+	"blockchain/service"
+	"context"
+	"encoding/json"
+)
+
+// registerChainMethods 注册链、交易、账户、交易池和节点相关的方法
+func registerChainMethods(s *Server, svc *service.ChainService) {
+	s.Register("chain_getInfo", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return svc.Info(), nil
+	})
+	s.Register("chain_getBlocks", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p service.Page
+		if err := bindParams(params, 0, []string{"offset", "limit"}, &p.Offset, &p.Limit); err != nil {
+			return nil, err
+		}
+		return svc.Blocks(p), nil
+	})
+	s.Register("chain_getBlockByHeight", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var height int
+		if err := bindParams(params, 1, []string{"height"}, &height); err != nil {
+			return nil, err
+		}
+		return svc.BlockByHeight(height)
+	})
+	s.Register("chain_getBlockByHash", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var hash string
+		if err := bindParams(params, 1, []string{"hash"}, &hash); err != nil {
+			return nil, err
+		}
+		return svc.BlockByHash(hash)
+	})
+
+	s.Register("tx_send", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var tx bc.Transaction
+		if err := bindParams(params, 1, []string{"tx"}, &tx); err != nil {
+			return nil, err
+		}
+		id, err := svc.SubmitTransaction(tx)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"id": id, "status": "pending"}, nil
+	})
+	s.Register("tx_get", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var id string
+		if err := bindParams(params, 1, []string{"id"}, &id); err != nil {
+			return nil, err
+		}
+		return svc.Transaction(id)
+	})
+
+	s.Register("account_getBalance", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var address string
+		if err := bindParams(params, 1, []string{"address"}, &address); err != nil {
+			return nil, err
+		}
+		return svc.Account(address), nil
+	})
+	s.Register("account_getTransactions", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var address string
+		var p service.Page
+		if err := bindParams(params, 1, []string{"address", "offset", "limit"}, &address, &p.Offset, &p.Limit); err != nil {
+			return nil, err
+		}
+		return svc.AccountHistory(address, p), nil
+	})
+
+	s.Register("mempool_list", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var p service.Page
+		if err := bindParams(params, 0, []string{"offset", "limit"}, &p.Offset, &p.Limit); err != nil {
+			return nil, err
+		}
+		return svc.Mempool(p), nil
+	})
+
+	s.Register("node_list", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return svc.Nodes(), nil
+	})
+	s.Register("node_get", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var id string
+		if err := bindParams(params, 1, []string{"id"}, &id); err != nil {
+			return nil, err
+		}
+		return svc.Node(id)
+	})
+	s.Register("node_getAnchor", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return svc.Anchor()
+	})
+	s.Register("ring_locate", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var key string
+		var n int
+		if err := bindParams(params, 1, []string{"key", "replicas"}, &key, &n); err != nil {
+			return nil, err
+		}
+		nodes, err := svc.Locate(key, n)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"key": key, "nodes": nodes}, nil
+	})
+}
@@ -0,0 +1,55 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// bindParams 按位置(数组)或名称(对象)把参数解码到dst，names与dst一一对应；
+// 缺失的参数保持零值，required个前置参数必须提供
+func bindParams(params json.RawMessage, required int, names []string, dst ...interface{}) error {
+	params = bytes.TrimSpace(params)
+	provided := make([]bool, len(dst))
+
+	switch {
+	case len(params) == 0 || bytes.Equal(params, []byte("null")):
+	case params[0] == '[':
+		var list []json.RawMessage
+		if err := json.Unmarshal(params, &list); err != nil {
+			return InvalidParams("params must be an array or object")
+		}
+		if len(list) > len(dst) {
+			return InvalidParams("too many params: expected at most %d", len(dst))
+		}
+		for i, raw := range list {
+			if err := json.Unmarshal(raw, dst[i]); err != nil {
+				return InvalidParams("invalid %s: %v", names[i], err)
+			}
+			provided[i] = true
+		}
+	case params[0] == '{':
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(params, &obj); err != nil {
+			return InvalidParams("params must be an array or object")
+		}
+		for i, name := range names {
+			raw, ok := obj[name]
+			if !ok {
+				continue
+			}
+			if err := json.Unmarshal(raw, dst[i]); err != nil {
+				return InvalidParams("invalid %s: %v", name, err)
+			}
+			provided[i] = true
+		}
+	default:
+		return InvalidParams("params must be an array or object")
+	}
+
+	for i := 0; i < required; i++ {
+		if !provided[i] {
+			return InvalidParams("missing param %s", names[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+// Package rpc 实现JSON-RPC 2.0接口，方法背后复用REST接口的service层
+package rpc
+
+import (
+	"blockchain/pkg/config"
+	"blockchain/service"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+const Version = "2.0"
+
+// 标准错误码，以及-32000起的服务端自定义错误码
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeNotFound       = -32000
+	CodeConflict       = -32001
+	CodePruned         = -32002
+	CodeUnavailable    = -32003
+	CodeForbidden      = -32004
+	CodeRateLimited    = -32005
+)
+
+// ErrRateLimited 授权检查或消息限流返回包装了它的错误时，响应使用CodeRateLimited
+var ErrRateLimited = errors.New("rate limited")
+
+// Request JSON-RPC请求，ID为空表示通知，不返回响应
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// Response JSON-RPC响应，Result和Error只出现一个
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// Error JSON-RPC错误对象
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// InvalidParams 构造参数错误，方法实现用它报告参数缺失或类型不符
+func InvalidParams(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// Method 方法实现，params为原始参数(数组或对象)
+type Method func(ctx context.Context, params json.RawMessage) (interface{}, error)
+
+// Authorizer 调用方法前检查调用方权限，返回错误时拒绝调用
+type Authorizer func(ctx context.Context, method string) error
+
+// MessageLimiter WebSocket连接上每条消息处理前检查限流，r为升级请求
+type MessageLimiter func(r *http.Request) error
+
+// Server 方法注册表和请求分发
+type Server struct {
+	methods   map[string]Method
+	authorize Authorizer
+	limit     MessageLimiter
+}
+
+// NewServer 创建RPC服务并注册链服务的方法
+func NewServer(svc *service.ChainService) *Server {
+	s := &Server{methods: make(map[string]Method)}
+	registerChainMethods(s, svc)
+	return s
+}
+
+// Register 注册方法，同名方法会被覆盖
+func (s *Server) Register(name string, m Method) {
+	s.methods[name] = m
+}
+
+// SetAuthorizer 设置方法级别的权限检查，未设置时所有方法都可调用
+func (s *Server) SetAuthorizer(a Authorizer) {
+	s.authorize = a
+}
+
+// SetMessageLimiter 设置WebSocket消息的限流，未设置时只有升级请求受HTTP层限流
+func (s *Server) SetMessageLimiter(l MessageLimiter) {
+	s.limit = l
+}
+
+// Methods 返回已注册的方法名
+func (s *Server) Methods() []string {
+	names := make([]string, 0, len(s.methods))
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Handle 处理一条原始消息(单个请求或批量请求)，没有需要返回的响应时返回nil
+func (s *Server) Handle(ctx context.Context, raw []byte) []byte {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '[' {
+		return s.handleBatch(ctx, raw)
+	}
+
+	var req Request
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return encode(errorResponse(nil, &Error{Code: CodeParseError, Message: "parse error"}))
+	}
+	resp := s.call(ctx, req)
+	if resp == nil {
+		return nil
+	}
+	return encode(resp)
+}
+
+func (s *Server) handleBatch(ctx context.Context, raw []byte) []byte {
+	var batch []json.RawMessage
+	if err := json.Unmarshal(raw, &batch); err != nil {
+		return encode(errorResponse(nil, &Error{Code: CodeParseError, Message: "parse error"}))
+	}
+	if len(batch) == 0 {
+		return encode(errorResponse(nil, &Error{Code: CodeInvalidRequest, Message: "empty batch"}))
+	}
+	if len(batch) > config.RPCMaxBatch {
+		return encode(errorResponse(nil, &Error{Code: CodeInvalidRequest, Message: fmt.Sprintf("batch exceeds %d requests", config.RPCMaxBatch)}))
+	}
+
+	var out []*Response
+	for _, item := range batch {
+		var req Request
+		if err := json.Unmarshal(item, &req); err != nil {
+			out = append(out, errorResponse(nil, &Error{Code: CodeInvalidRequest, Message: "invalid request"}))
+			continue
+		}
+		if resp := s.call(ctx, req); resp != nil {
+			out = append(out, resp)
+		}
+	}
+	if len(out) == 0 { // 全部是通知
+		return nil
+	}
+	return encode(out)
+}
+
+// call 执行单个请求，通知返回nil
+func (s *Server) call(ctx context.Context, req Request) *Response {
+	notification := len(req.ID) == 0
+	if req.JSONRPC != Version || req.Method == "" || !validID(req.ID) {
+		return errorResponse(req.ID, &Error{Code: CodeInvalidRequest, Message: "invalid request"})
+	}
+	m, ok := s.methods[req.Method]
+	if !ok {
+		if notification {
+			return nil
+		}
+		return errorResponse(req.ID, &Error{Code: CodeMethodNotFound, Message: "method not found: " + req.Method})
+	}
+
+	if s.authorize != nil {
+		if err := s.authorize(ctx, req.Method); err != nil {
+			if notification {
+				return nil
+			}
+			code := CodeForbidden
+			if errors.Is(err, ErrRateLimited) {
+				code = CodeRateLimited
+			}
+			return errorResponse(req.ID, &Error{Code: code, Message: err.Error()})
+		}
+	}
+
+	result, err := m(ctx, req.Params)
+	if notification {
+		return nil
+	}
+	if err != nil {
+		return errorResponse(req.ID, toError(err))
+	}
+	return &Response{JSONRPC: Version, Result: result, ID: req.ID}
+}
+
+// toError 把服务层错误映射为JSON-RPC错误码
+func toError(err error) *Error {
+	var rpcErr *Error
+	switch {
+	case errors.As(err, &rpcErr):
+		return rpcErr
+	case errors.Is(err, service.ErrInvalidArgument):
+		return &Error{Code: CodeInvalidParams, Message: err.Error()}
+	case errors.Is(err, service.ErrNotFound):
+		return &Error{Code: CodeNotFound, Message: err.Error()}
+	case errors.Is(err, service.ErrConflict):
+		return &Error{Code: CodeConflict, Message: err.Error()}
+	case errors.Is(err, service.ErrPruned):
+		return &Error{Code: CodePruned, Message: err.Error()}
+	case errors.Is(err, service.ErrUnavailable):
+		return &Error{Code: CodeUnavailable, Message: err.Error()}
+	default:
+		log.Printf("[RPC] 内部错误: %v", err)
+		return &Error{Code: CodeInternalError, Message: "internal error"}
+	}
+}
+
+// validID ID只能是字符串、数字或null
+func validID(id json.RawMessage) bool {
+	if len(id) == 0 {
+		return true
+	}
+	switch id[0] {
+	case '"', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 'n':
+		return true
+	}
+	return false
+}
+
+func errorResponse(id json.RawMessage, e *Error) *Response {
+	if len(id) == 0 || !validID(id) {
+		id = json.RawMessage("null")
+	}
+	return &Response{JSONRPC: Version, Error: e, ID: id}
+}
+
+func encode(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("[RPC] 编码响应失败: %v", err)
+		data, _ = json.Marshal(errorResponse(nil, &Error{Code: CodeInternalError, Message: "internal error"}))
+	}
+	return data
+}
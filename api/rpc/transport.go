@@ -0,0 +1,80 @@
+package rpc
+
+import (
+	"blockchain/pkg/config"
+	"blockchain/pkg/ws"
+	"io"
+	"log"
+	"net/http"
+)
+
+// ServeHTTP POST请求体为单个或批量请求；带Upgrade头的GET请求升级为WebSocket，
+// 之后每条文本消息按同样规则处理，响应按请求顺序写回
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if ws.IsUpgrade(r) {
+		s.serveWebSocket(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeHTTP(w, http.StatusMethodNotAllowed, encode(errorResponse(nil, &Error{Code: CodeInvalidRequest, Message: "use POST or a websocket upgrade"})))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, config.APIMaxBodyBytes))
+	if err != nil {
+		writeHTTP(w, http.StatusRequestEntityTooLarge, encode(errorResponse(nil, &Error{Code: CodeInvalidRequest, Message: "request too large"})))
+		return
+	}
+	resp := s.Handle(r.Context(), body)
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeHTTP(w, http.StatusOK, resp)
+}
+
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := ws.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(config.APIMaxBodyBytes)
+	conn.SetIdleTimeout(config.WSIdleTimeout)
+
+	ctx := r.Context()
+	for {
+		op, msg, err := conn.ReadMessage()
+		if err != nil {
+			if err != ws.ErrClosed {
+				log.Printf("[RPC] WebSocket连接 %s 读取失败: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		if op != ws.OpText {
+			conn.CloseWithCode(ws.ClosePolicy, "text messages only")
+			return
+		}
+		// 升级只计一次HTTP请求，连接上的每条消息另外计入限流
+		if s.limit != nil {
+			if err := s.limit(r); err != nil {
+				if err := conn.WriteMessage(ws.OpText, encode(errorResponse(nil, &Error{Code: CodeRateLimited, Message: err.Error()}))); err != nil {
+					return
+				}
+				continue
+			}
+		}
+		if resp := s.Handle(ctx, msg); resp != nil {
+			if err := conn.WriteMessage(ws.OpText, resp); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeHTTP(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
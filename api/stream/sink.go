@@ -0,0 +1,74 @@
+package stream
+
+import (
+	"blockchain/internal/events"
+	"blockchain/pkg/ws"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// writeTimeout 单条消息的写超时，客户端不读时连接很快被判定为断开
+const writeTimeout = 10 * time.Second
+
+// wsSink 每个事件作为一条JSON文本消息发送
+type wsSink struct {
+	conn *ws.Conn
+}
+
+func (s *wsSink) send(ev events.Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return s.conn.WriteMessage(ws.OpText, data)
+}
+
+func (s *wsSink) keepAlive() error {
+	return s.conn.Ping()
+}
+
+func (s *wsSink) fail(e ErrorEvent) {
+	if data, err := json.Marshal(map[string]interface{}{"topic": "error", "data": e}); err == nil {
+		s.conn.WriteMessage(ws.OpText, data)
+	}
+	s.conn.CloseWithCode(ws.ClosePolicy, e.Error)
+}
+
+// sseSink 事件名为主题，带高度的事件以高度作为事件ID，浏览器重连时经Last-Event-ID带回
+type sseSink struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func (s *sseSink) send(ev events.Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	s.rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if ev.Height > 0 && (ev.Topic == events.TopicNewHeads || ev.Topic == events.TopicTransactions) {
+		fmt.Fprintf(s.w, "id: %s\n", strconv.Itoa(ev.Height))
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", ev.Topic, data); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+func (s *sseSink) keepAlive() error {
+	s.rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+func (s *sseSink) fail(e ErrorEvent) {
+	data, _ := json.Marshal(e)
+	s.rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+	fmt.Fprintf(s.w, "event: error\ndata: %s\n\n", data)
+	s.rc.Flush()
+}
@@ -0,0 +1,226 @@
+// Package stream 通过WebSocket或Server-Sent Events推送事件总线上的订阅
+package stream
+
+import (
+	"blockchain/internal/events"
+	"blockchain/pkg/config"
+	"blockchain/pkg/ws"
+	"blockchain/service"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errReplayTooLong = errors.New("resume height is too far behind, reload state over the REST API")
+
+// Handler 订阅接口: GET ?topics=newHeads,transactions&address=..&node=..&fromHeight=N，
+// 带Upgrade头的请求使用WebSocket，否则使用SSE；fromHeight或SSE的Last-Event-ID
+// 指定客户端已收到的最高区块，重连时先补发此后的区块和交易再推送实时事件
+type Handler struct {
+	svc *service.ChainService
+	bus *events.Bus
+
+	closeOnce sync.Once
+	closing   chan struct{}
+}
+
+// New 创建订阅接口
+func New(svc *service.ChainService, bus *events.Bus) *Handler {
+	return &Handler{svc: svc, bus: bus, closing: make(chan struct{})}
+}
+
+// Close 结束所有订阅连接，服务器关闭时调用；长连接不会被http.Server.Shutdown等待结束
+func (h *Handler) Close() {
+	h.closeOnce.Do(func() { close(h.closing) })
+}
+
+// ErrorEvent 订阅异常终止前发给客户端的最后一条消息
+type ErrorEvent struct {
+	Error  string `json:"error"`
+	Height int    `json:"height"` // 已推送的最高区块，客户端以此作为fromHeight重连
+}
+
+// sink WebSocket和SSE的共同写接口
+type sink interface {
+	send(ev events.Event) error
+	keepAlive() error
+	fail(e ErrorEvent)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	from := -1
+	v := r.URL.Query().Get("fromHeight")
+	if v == "" && !ws.IsUpgrade(r) {
+		v = r.Header.Get("Last-Event-ID")
+	}
+	if v != "" {
+		if from, err = strconv.Atoi(v); err != nil || from < 0 {
+			writeError(w, http.StatusBadRequest, "fromHeight must be a non-negative integer")
+			return
+		}
+	}
+
+	if ws.IsUpgrade(r) {
+		conn, err := ws.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetWriteTimeout(writeTimeout)
+		done := make(chan struct{})
+		go func() {
+			// 读取并丢弃客户端消息，以便应答ping并及时发现断开
+			defer close(done)
+			conn.SetIdleTimeout(3 * config.StreamKeepAlive)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+		h.run(&wsSink{conn: conn}, filter, from, done)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{}) // 长连接不受服务器写超时约束
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+	h.run(&sseSink{w: w, rc: rc}, filter, from, r.Context().Done())
+}
+
+// run 先订阅再补发历史，实时事件中已补发过的高度被跳过，保证不丢不重
+func (h *Handler) run(out sink, filter events.Filter, from int, done <-chan struct{}) {
+	sub := h.bus.Subscribe(filter, config.EventBufferSize)
+	defer sub.Close()
+
+	lastHead, lastTx := from, from
+	if from >= 0 {
+		var err error
+		if lastHead, lastTx, err = h.replay(out, filter, from); err != nil {
+			out.fail(ErrorEvent{Error: err.Error(), Height: lastHead})
+			return
+		}
+	}
+
+	ticker := time.NewTicker(config.StreamKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-h.closing:
+			return
+		case <-ticker.C:
+			if err := out.keepAlive(); err != nil {
+				return
+			}
+		case ev, ok := <-sub.Events():
+			if !ok {
+				if err := sub.Err(); err != nil {
+					out.fail(ErrorEvent{Error: err.Error(), Height: lastHead})
+				}
+				return
+			}
+			switch ev.Topic {
+			case events.TopicNewHeads:
+				if ev.Height <= lastHead {
+					continue
+				}
+				lastHead = ev.Height
+			case events.TopicTransactions:
+				if ev.Height <= lastTx {
+					continue
+				}
+			}
+			if err := out.send(ev); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// replay 补发from之后的区块头和交易，返回已补发的最高高度
+func (h *Handler) replay(out sink, filter events.Filter, from int) (int, int, error) {
+	chain := h.svc.Chain()
+	tip := chain.Height()
+	if tip-from > config.MaxReplayBlocks {
+		return from, from, errReplayTooLong
+	}
+	heads := filter.Match(events.Event{Topic: events.TopicNewHeads})
+	txs := filter.Match(events.Event{Topic: events.TopicTransactions, Accounts: []string{filter.Address}})
+
+	for height := from + 1; height <= tip; height++ {
+		if heads {
+			hdr := chain.GetHeaders(height, 1)
+			if len(hdr) == 0 {
+				break
+			}
+			if err := out.send(events.Event{Topic: events.TopicNewHeads, Height: height, Time: hdr[0].Timestamp, Data: hdr[0]}); err != nil {
+				return from, from, err
+			}
+		}
+		if txs {
+			// 区块体已被裁剪的高度无法补发交易
+			if block, ok := chain.GetBlockByIndex(height); ok {
+				for _, ev := range events.BlockTxEvents(block) {
+					if !filter.Match(ev) {
+						continue
+					}
+					if err := out.send(ev); err != nil {
+						return from, from, err
+					}
+				}
+			}
+		}
+	}
+	return tip, tip, nil
+}
+
+// parseFilter 解析主题、地址和节点过滤条件，未指定主题时订阅全部
+func parseFilter(r *http.Request) (events.Filter, error) {
+	q := r.URL.Query()
+	f := events.Filter{Address: q.Get("address"), NodeID: q.Get("node")}
+	if v := q.Get("topics"); v != "" {
+		for _, name := range strings.Split(v, ",") {
+			topic, ok := lookupTopic(strings.TrimSpace(name))
+			if !ok {
+				return f, fmt.Errorf("unknown topic %q", name)
+			}
+			f.Topics = append(f.Topics, topic)
+		}
+	}
+	return f, nil
+}
+
+func lookupTopic(name string) (events.Topic, bool) {
+	for _, t := range events.Topics {
+		if string(t) == name {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"code": "invalid_argument", "message": message},
+	})
+}
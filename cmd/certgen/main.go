@@ -0,0 +1,133 @@
+package main
+
+import (
+	"blockchain/internal/network"
+	"blockchain/internal/security"
+	"blockchain/pkg/config"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// certgen 生成本地CA、签发节点证书以及维护吊销名单
+//
+//	certgen ca     -dir data/certs
+//	certgen node   -dir data/certs -identity data/node.key -hosts 127.0.0.1,localhost
+//	certgen revoke -deny data/certs/deny.list -node node-xxxx
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "ca":
+		err = runCA(os.Args[2:])
+	case "node":
+		err = runNode(os.Args[2:])
+	case "revoke":
+		err = runRevoke(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "用法: certgen <ca|node|revoke> [参数]")
+}
+
+func runCA(args []string) error {
+	fs := flag.NewFlagSet("ca", flag.ExitOnError)
+	dir := fs.String("dir", filepath.Dir(config.TLSCAFile), "证书输出目录")
+	cn := fs.String("cn", "block_chain local CA", "CA名称")
+	days := fs.Int("days", 3650, "有效期(天)")
+	fs.Parse(args)
+
+	ca, err := security.GenerateCA(*cn, time.Duration(*days)*24*time.Hour)
+	if err != nil {
+		return err
+	}
+	certFile := filepath.Join(*dir, "ca.pem")
+	keyFile := filepath.Join(*dir, "ca.key")
+	if err := ca.Save(certFile, keyFile); err != nil {
+		return err
+	}
+	fmt.Printf("已生成CA: %s, %s\n", certFile, keyFile)
+	return nil
+}
+
+func runNode(args []string) error {
+	fs := flag.NewFlagSet("node", flag.ExitOnError)
+	dir := fs.String("dir", filepath.Dir(config.TLSCAFile), "CA所在目录及证书输出目录")
+	identity := fs.String("identity", "", "节点身份私钥文件，节点ID由其公钥派生")
+	id := fs.String("id", "", "节点ID，未指定-identity时使用")
+	hosts := fs.String("hosts", "127.0.0.1,localhost", "证书包含的IP或域名，逗号分隔")
+	name := fs.String("name", "node", "输出文件名(不含扩展名)")
+	validity := fs.Duration("validity", config.CertValidity*time.Second, "有效期")
+	fs.Parse(args)
+
+	nodeID := *id
+	if *identity != "" {
+		ident, err := network.LoadOrCreateIdentity(*identity)
+		if err != nil {
+			return err
+		}
+		nodeID = ident.NodeID()
+	}
+	if nodeID == "" {
+		return fmt.Errorf("需要指定 -identity 或 -id")
+	}
+
+	ca, err := security.LoadCA(filepath.Join(*dir, "ca.pem"), filepath.Join(*dir, "ca.key"))
+	if err != nil {
+		return err
+	}
+	certPEM, keyPEM, err := ca.IssueNodeCert(nodeID, strings.Split(*hosts, ","), *validity)
+	if err != nil {
+		return err
+	}
+
+	certFile := filepath.Join(*dir, *name+".pem")
+	keyFile := filepath.Join(*dir, *name+".key")
+	if err := security.WriteFile(certFile, certPEM, false); err != nil {
+		return err
+	}
+	if err := security.WriteFile(keyFile, keyPEM, true); err != nil {
+		return err
+	}
+	fmt.Printf("已为节点 %s 签发证书: %s, %s\n", nodeID, certFile, keyFile)
+	return nil
+}
+
+func runRevoke(args []string) error {
+	fs := flag.NewFlagSet("revoke", flag.ExitOnError)
+	deny := fs.String("deny", config.TLSDenyList, "吊销名单文件")
+	node := fs.String("node", "", "吊销的节点ID")
+	serial := fs.String("serial", "", "吊销的证书序列号(十六进制)")
+	fs.Parse(args)
+
+	switch {
+	case *node != "":
+		if err := security.AppendEntry(*deny, "node", *node); err != nil {
+			return err
+		}
+		fmt.Printf("已吊销节点 %s\n", *node)
+	case *serial != "":
+		if err := security.AppendEntry(*deny, "serial", strings.ToLower(*serial)); err != nil {
+			return err
+		}
+		fmt.Printf("已吊销证书 %s\n", *serial)
+	default:
+		return fmt.Errorf("需要指定 -node 或 -serial")
+	}
+	return nil
+}
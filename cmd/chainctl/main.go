@@ -0,0 +1,259 @@
+package main
+
+import (
+	"blockchain/api/middleware"
+	"blockchain/api/router"
+	"blockchain/internal/archive"
+	bc "blockchain/internal/blockchain"
+	"blockchain/pkg/config"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// chainctl 导出、导入和校验链归档
+//
+//	chainctl export -node http://localhost:8080 -out chain.bca [-from 100] [-snapshot] [-key operator密钥]
+//	chainctl import -node http://localhost:8080 -in chain.bca [-from 100] [-key admin密钥]
+//	chainctl verify -in chain.bca [-replay -difficulty 4]
+//	chainctl apikey -name ops -role operator
+//	chainctl token -sub alice -role submitter [-ttl 24h]
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = runExport(os.Args[2:])
+	case "import":
+		err = runImport(os.Args[2:])
+	case "verify":
+		err = runVerify(os.Args[2:])
+	case "apikey":
+		err = runAPIKey(os.Args[2:])
+	case "token":
+		err = runToken(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "用法: chainctl <export|import|verify|apikey|token> [参数]")
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	node := fs.String("node", "http://localhost:8080", "节点HTTP地址")
+	out := fs.String("out", "chain.bca", "归档输出文件")
+	from := fs.Int("from", 0, "从该高度开始导出")
+	snapshot := fs.Bool("snapshot", false, "附带最近的状态快照")
+	key := fs.String("key", os.Getenv("API_KEY"), "operator角色的API密钥，默认读取环境变量API_KEY")
+	fs.Parse(args)
+
+	q := url.Values{}
+	q.Set("from", strconv.Itoa(*from))
+	q.Set("snapshot", strconv.FormatBool(*snapshot))
+	req, err := newRequest(http.MethodGet, *node+router.Prefix+"/chain/export?"+q.Encode(), nil, *key)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	tmp := *out + ".part"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// 下载完成后校验，避免把截断的归档当作成功
+	meta, err := verifyFile(tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, *out); err != nil {
+		return err
+	}
+	fmt.Printf("已导出区块 %d-%d 到 %s", meta.From, meta.To, *out)
+	if meta.SnapshotHeight > 0 {
+		fmt.Printf("，含高度 %d 的状态快照", meta.SnapshotHeight)
+	}
+	fmt.Println()
+	return nil
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	node := fs.String("node", "http://localhost:8080", "节点HTTP地址")
+	in := fs.String("in", "chain.bca", "归档文件")
+	from := fs.Int("from", 0, "从该高度续传，默认跳过节点已有的区块")
+	key := fs.String("key", os.Getenv("API_KEY"), "admin角色的API密钥，默认读取环境变量API_KEY")
+	fs.Parse(args)
+
+	if _, err := verifyFile(*in); err != nil {
+		return err
+	}
+	f, err := os.Open(*in)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	req, err := newRequest(http.MethodPost, *node+router.Prefix+"/chain/import?from="+strconv.Itoa(*from), f, *key)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	fmt.Printf("导入完成: %s", body)
+	return nil
+}
+
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	in := fs.String("in", "chain.bca", "归档文件")
+	replay := fs.Bool("replay", false, "在空链上完整重放并校验每个区块")
+	difficulty := fs.Int("difficulty", 4, "重放时使用的挖矿难度")
+	fs.Parse(args)
+
+	meta, err := verifyFile(*in)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("归档格式v%d，创世区块 %s，区块 %d-%d，快照高度 %d，校验和正确\n",
+		meta.Version, meta.GenesisHash, meta.From, meta.To, meta.SnapshotHeight)
+	if !*replay {
+		return nil
+	}
+
+	f, err := os.Open(*in)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	chain := bc.NewBlockchain()
+	chain.Difficulty = *difficulty
+	result, err := archive.Import(f, chain, archive.ImportOptions{})
+	if err != nil {
+		return fmt.Errorf("重放到高度 %d 时失败: %w", chain.Height(), err)
+	}
+	fmt.Printf("重放完成: 导入 %d 个区块，高度 %d，状态根 %s\n", result.Imported, result.Height, chain.State().Root())
+	return nil
+}
+
+// runAPIKey 生成随机API密钥，输出密钥明文和需要追加到密钥文件的条目
+func runAPIKey(args []string) error {
+	fs := flag.NewFlagSet("apikey", flag.ExitOnError)
+	name := fs.String("name", "", "密钥名称，出现在访问日志中")
+	roleName := fs.String("role", "read-only", "角色: read-only、submitter、operator、admin")
+	fs.Parse(args)
+
+	if *name == "" {
+		return fmt.Errorf("-name is required")
+	}
+	role, err := middleware.ParseRole(*roleName)
+	if err != nil {
+		return err
+	}
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	key := hex.EncodeToString(b)
+	fmt.Printf("密钥(只显示一次): %s\n", key)
+	fmt.Printf("追加到 %s:\n%s %s %s\n", config.APIKeysFile, *name, role, middleware.HashKey(key))
+	return nil
+}
+
+// runToken 用JWT签名密钥签发令牌
+func runToken(args []string) error {
+	fs := flag.NewFlagSet("token", flag.ExitOnError)
+	secretPath := fs.String("secret", config.JWTSecretFile, "JWT签名密钥文件")
+	sub := fs.String("sub", "", "令牌主体")
+	roleName := fs.String("role", "read-only", "角色: read-only、submitter、operator、admin")
+	ttl := fs.Duration("ttl", 24*time.Hour, "有效期")
+	fs.Parse(args)
+
+	if *sub == "" {
+		return fmt.Errorf("-sub is required")
+	}
+	role, err := middleware.ParseRole(*roleName)
+	if err != nil {
+		return err
+	}
+	secret, err := os.ReadFile(*secretPath)
+	if err != nil {
+		return err
+	}
+	token, err := middleware.IssueToken([]byte(strings.TrimSpace(string(secret))), *sub, role, *ttl)
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	return nil
+}
+
+func verifyFile(path string) (archive.Meta, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return archive.Meta{}, err
+	}
+	defer f.Close()
+	return archive.Verify(f)
+}
+
+// newRequest 创建带API密钥的请求，key为空时匿名访问
+func newRequest(method, target string, body io.Reader, key string) (*http.Request, error) {
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		req.Header.Set(middleware.APIKeyHeader, key)
+	}
+	return req, nil
+}
+
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return fmt.Errorf("%s: %s", resp.Status, body)
+}
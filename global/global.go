@@ -2,6 +2,7 @@ package global
 
 import (
 	block_chain "blockchain/internal/blockchain"
+	"blockchain/internal/events"
 	"blockchain/internal/network"
 )
 
@@ -10,4 +11,8 @@ var (
 	Blockpool       = make([]block_chain.Block, 0)   // 全局区块池，用于存储待处理的区块
 	AnchorNode      *network.Node                    // 当前锚节点
 	BlockAssignChan chan network.BlockAssignInfo
+	Host            *network.Host      // 本进程节点的网络层
+	Discovery       *network.Discovery // 节点发现服务
+	Syncer          *network.Syncer    // 链同步服务
+	Events          *events.Bus        // 订阅接口使用的事件总线
 )
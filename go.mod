@@ -2,10 +2,7 @@ module blockchain
 
 go 1.24
 
-require (
-	github.com/shirou/gopsutil v3.21.11+incompatible
-	github.com/stathat/consistent v1.0.0
-)
+require github.com/shirou/gopsutil v3.21.11+incompatible
 
 require (
 	github.com/go-ole/go-ole v1.2.6 // indirect
@@ -14,5 +11,4 @@ require (
 	github.com/tklauser/numcpus v0.10.0 // indirect
 	github.com/yusufpapurcu/wmi v1.2.4 // indirect
 	golang.org/x/sys v0.34.0 // indirect
-	stathat.com/c/consistent v1.0.0 // indirect
 )
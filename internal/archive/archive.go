@@ -0,0 +1,262 @@
+package archive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"blockchain/internal/blockchain"
+)
+
+var (
+	ErrNoSnapshot      = errors.New("chain has no state snapshot")
+	ErrPruned          = errors.New("block body has been pruned, export with a snapshot")
+	ErrGenesisMismatch = errors.New("archive belongs to a different chain")
+	ErrGap             = errors.New("archive does not continue the local chain")
+	ErrDiverged        = errors.New("archive conflicts with the local chain")
+)
+
+// headerBatch 导出快照以下区块头时每次读取的数量
+const headerBatch = 500
+
+// Source 导出时读取的链，由*block_chain.Blockchain实现
+type Source interface {
+	Height() int
+	GetBlockByIndex(index int) (block_chain.Block, bool)
+	GetHeaders(from, count int) []block_chain.BlockHeader
+	LatestSnapshot() (block_chain.StateSnapshot, bool)
+}
+
+// Target 导入时写入的链，由*block_chain.Blockchain实现
+type Target interface {
+	Height() int
+	GetHeaders(from, count int) []block_chain.BlockHeader
+	AcceptBlock(block block_chain.Block) error
+	ImportSnapshot(snap block_chain.StateSnapshot, headers []block_chain.BlockHeader) error
+}
+
+// ExportOptions 导出参数
+type ExportOptions struct {
+	From     int  // 从该高度开始导出区块，0表示从创世区块之后开始
+	Snapshot bool // 附带最近的状态快照及其高度以下的区块头，之后只导出快照高度以上的区块
+}
+
+// ImportOptions 导入参数
+type ImportOptions struct {
+	From int // 从该高度续传，其下的区块必须已在本地链上，不再逐个比对
+}
+
+// ImportResult 导入结果
+type ImportResult struct {
+	Meta     Meta `json:"meta"`
+	Snapshot bool `json:"snapshot"` // 是否导入了状态快照
+	Imported int  `json:"imported"`
+	Skipped  int  `json:"skipped"`
+	Height   int  `json:"height"`
+}
+
+// Export 将链写为归档，导出范围以开始时的链高度为准
+func Export(w io.Writer, src Source, opts ExportOptions) (Meta, error) {
+	genesis, ok := src.GetBlockByIndex(0)
+	if !ok {
+		return Meta{}, errors.New("chain has no genesis block")
+	}
+	meta := Meta{
+		Created:     time.Now().Unix(),
+		GenesisHash: genesis.Hash,
+		From:        opts.From,
+		To:          src.Height(),
+	}
+	if meta.From < 1 {
+		meta.From = 1
+	}
+
+	var snap block_chain.StateSnapshot
+	if opts.Snapshot {
+		if snap, ok = src.LatestSnapshot(); !ok {
+			return Meta{}, ErrNoSnapshot
+		}
+		meta.SnapshotHeight = snap.Height
+		if meta.From <= snap.Height {
+			meta.From = snap.Height + 1
+		}
+	}
+
+	aw, err := NewWriter(w, meta)
+	if err != nil {
+		return Meta{}, err
+	}
+	if opts.Snapshot {
+		if err := writeJSON(aw, KindSnapshot, snap); err != nil {
+			return Meta{}, err
+		}
+		for from := 1; from <= snap.Height; from += headerBatch {
+			count := headerBatch
+			if from+count > snap.Height+1 {
+				count = snap.Height + 1 - from
+			}
+			for _, h := range src.GetHeaders(from, count) {
+				if err := writeJSON(aw, KindHeader, h); err != nil {
+					return Meta{}, err
+				}
+			}
+		}
+	}
+
+	for i := meta.From; i <= meta.To; i++ {
+		block, ok := src.GetBlockByIndex(i)
+		if !ok {
+			return Meta{}, fmt.Errorf("block %d: %w", i, ErrPruned)
+		}
+		data, err := block_chain.EncodeBlock(block)
+		if err != nil {
+			return Meta{}, err
+		}
+		if err := aw.WriteRecord(KindBlock, data); err != nil {
+			return Meta{}, err
+		}
+	}
+	return meta, aw.Close()
+}
+
+// Verify 完整读取归档并校验结构和校验和，不写入任何链
+func Verify(r io.Reader) (Meta, error) {
+	ar, err := NewReader(r)
+	if err != nil {
+		return Meta{}, err
+	}
+	for {
+		if _, err := ar.Next(); err == io.EOF {
+			return ar.Meta(), nil
+		} else if err != nil {
+			return Meta{}, err
+		}
+	}
+}
+
+// Import 先校验整个归档的校验和，再回到开头逐个区块完整校验后上链。
+// 本地链只有创世区块且归档带快照时先导入快照；本地链已有的区块与归档比对后跳过，
+// 因此中断后对同一条链重新导入即从断点续传
+func Import(r io.ReadSeeker, dst Target, opts ImportOptions) (ImportResult, error) {
+	if opts.From > dst.Height()+1 {
+		return ImportResult{}, fmt.Errorf("%w: resume height %d is above local height %d", ErrGap, opts.From, dst.Height())
+	}
+	if _, err := Verify(r); err != nil {
+		return ImportResult{}, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return ImportResult{}, err
+	}
+	ar, err := NewReader(r)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	result := ImportResult{Meta: ar.Meta()}
+
+	local := dst.GetHeaders(0, 1)
+	if len(local) == 0 || local[0].Hash != result.Meta.GenesisHash {
+		return result, ErrGenesisMismatch
+	}
+
+	var snap *block_chain.StateSnapshot
+	var headers []block_chain.BlockHeader
+	for {
+		rec, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, err
+		}
+
+		switch rec.Kind {
+		case KindSnapshot:
+			snap = new(block_chain.StateSnapshot)
+			if err := json.Unmarshal(rec.Data, snap); err != nil {
+				return result, fmt.Errorf("%w: snapshot: %v", ErrMalformed, err)
+			}
+		case KindHeader:
+			var h block_chain.BlockHeader
+			if err := json.Unmarshal(rec.Data, &h); err != nil {
+				return result, fmt.Errorf("%w: header: %v", ErrMalformed, err)
+			}
+			headers = append(headers, h)
+		case KindBlock:
+			if snap != nil {
+				if err := importSnapshot(dst, *snap, headers); err != nil {
+					return result, err
+				}
+				result.Snapshot = dst.Height() == snap.Height
+				snap, headers = nil, nil
+			}
+			block, err := block_chain.DecodeBlock(rec.Data)
+			if err != nil {
+				return result, fmt.Errorf("%w: block: %v", ErrMalformed, err)
+			}
+			imported, err := importBlock(dst, block, opts.From)
+			if err != nil {
+				return result, err
+			}
+			if imported {
+				result.Imported++
+			} else {
+				result.Skipped++
+			}
+		default:
+			return result, fmt.Errorf("%w: unknown record kind %d", ErrMalformed, rec.Kind)
+		}
+	}
+	if snap != nil { // 只有快照没有区块
+		if err := importSnapshot(dst, *snap, headers); err != nil {
+			return result, err
+		}
+		result.Snapshot = dst.Height() == snap.Height
+	}
+	result.Height = dst.Height()
+	return result, nil
+}
+
+// importSnapshot 本地链为空时导入快照；本地链已经达到快照高度时只比对区块头
+func importSnapshot(dst Target, snap block_chain.StateSnapshot, headers []block_chain.BlockHeader) error {
+	if dst.Height() == 0 {
+		return dst.ImportSnapshot(snap, headers)
+	}
+	if dst.Height() < snap.Height {
+		return fmt.Errorf("%w: local height %d is below snapshot height %d", ErrGap, dst.Height(), snap.Height)
+	}
+	if local := dst.GetHeaders(snap.Height, 1); len(local) == 0 || local[0].Hash != snap.BlockHash {
+		return fmt.Errorf("%w at snapshot height %d", ErrDiverged, snap.Height)
+	}
+	return nil
+}
+
+// importBlock 导入一个区块，返回false表示该区块被跳过
+func importBlock(dst Target, block block_chain.Block, from int) (bool, error) {
+	height := dst.Height()
+	if block.Index < from {
+		return false, nil
+	}
+	if block.Index <= height {
+		if local := dst.GetHeaders(block.Index, 1); len(local) == 0 || local[0].Hash != block.Hash {
+			return false, fmt.Errorf("%w at height %d", ErrDiverged, block.Index)
+		}
+		return false, nil
+	}
+	if block.Index != height+1 {
+		return false, fmt.Errorf("%w: next block is %d, local height %d", ErrGap, block.Index, height)
+	}
+	if err := dst.AcceptBlock(block); err != nil {
+		return false, fmt.Errorf("block %d: %w", block.Index, err)
+	}
+	return true, nil
+}
+
+func writeJSON(aw *Writer, kind Kind, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return aw.WriteRecord(kind, data)
+}
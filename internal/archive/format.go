@@ -0,0 +1,185 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// 归档文件格式:
+//
+//	魔数"BCAR" | 1字节格式版本 | 记录...
+//	记录: 1字节类型 | 4字节大端负载长度 | 负载
+//
+// 第一条记录是JSON编码的元信息，之后依次为可选的状态快照、快照高度以下的区块头、
+// 按高度升序的区块(block_chain.EncodeBlock编码)，最后一条记录是此前所有字节的SHA-256
+const (
+	Version = 1
+
+	maxRecordSize = 64 << 20
+)
+
+var magic = [4]byte{'B', 'C', 'A', 'R'}
+
+// Kind 记录类型
+type Kind byte
+
+const (
+	KindMeta     Kind = 1
+	KindSnapshot Kind = 2
+	KindHeader   Kind = 3
+	KindBlock    Kind = 4
+	KindChecksum Kind = 0xFF
+)
+
+var (
+	ErrBadMagic           = errors.New("not a chain archive")
+	ErrUnsupportedVersion = errors.New("unsupported archive version")
+	ErrChecksum           = errors.New("archive checksum mismatch")
+	ErrTruncated          = errors.New("archive is truncated")
+	ErrMalformed          = errors.New("malformed archive")
+)
+
+// Meta 归档元信息
+type Meta struct {
+	Version        int    `json:"version"`
+	Created        int64  `json:"created"`
+	GenesisHash    string `json:"genesis_hash"`
+	From           int    `json:"from"`            // 第一个区块的高度
+	To             int    `json:"to"`              // 最后一个区块的高度
+	SnapshotHeight int    `json:"snapshot_height"` // 0表示不含状态快照
+}
+
+// Record 归档中的一条记录
+type Record struct {
+	Kind Kind
+	Data []byte
+}
+
+// Writer 顺序写入归档记录，Close时追加校验和
+type Writer struct {
+	w   io.Writer
+	sum hash.Hash
+}
+
+// NewWriter 写入文件头和元信息
+func NewWriter(w io.Writer, meta Meta) (*Writer, error) {
+	meta.Version = Version
+	aw := &Writer{sum: sha256.New()}
+	aw.w = io.MultiWriter(w, aw.sum)
+	if _, err := aw.w.Write(append(magic[:], Version)); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	if err := aw.WriteRecord(KindMeta, data); err != nil {
+		return nil, err
+	}
+	return aw, nil
+}
+
+// WriteRecord 写入一条记录
+func (aw *Writer) WriteRecord(kind Kind, data []byte) error {
+	if len(data) > maxRecordSize {
+		return fmt.Errorf("record of %d bytes exceeds limit", len(data))
+	}
+	var head [5]byte
+	head[0] = byte(kind)
+	binary.BigEndian.PutUint32(head[1:], uint32(len(data)))
+	if _, err := aw.w.Write(head[:]); err != nil {
+		return err
+	}
+	_, err := aw.w.Write(data)
+	return err
+}
+
+// Close 写入校验和记录，不关闭底层Writer
+func (aw *Writer) Close() error {
+	return aw.WriteRecord(KindChecksum, aw.sum.Sum(nil))
+}
+
+// Reader 顺序读取归档记录，读到校验和记录时校验此前的全部内容
+type Reader struct {
+	r    io.Reader
+	sum  hash.Hash
+	meta Meta
+	done bool
+}
+
+// NewReader 读取并检查文件头和元信息
+func NewReader(r io.Reader) (*Reader, error) {
+	ar := &Reader{sum: sha256.New()}
+	ar.r = io.TeeReader(r, ar.sum)
+
+	var head [5]byte
+	if _, err := io.ReadFull(ar.r, head[:]); err != nil {
+		return nil, ErrBadMagic
+	}
+	if !bytes.Equal(head[:4], magic[:]) {
+		return nil, ErrBadMagic
+	}
+	if head[4] != Version {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, head[4])
+	}
+
+	rec, err := ar.Next()
+	if err != nil {
+		return nil, err
+	}
+	if rec.Kind != KindMeta {
+		return nil, fmt.Errorf("%w: first record is %d, want meta", ErrMalformed, rec.Kind)
+	}
+	if err := json.Unmarshal(rec.Data, &ar.meta); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return ar, nil
+}
+
+// Meta 返回归档元信息
+func (ar *Reader) Meta() Meta {
+	return ar.meta
+}
+
+// Next 返回下一条记录，校验和通过后返回io.EOF
+func (ar *Reader) Next() (Record, error) {
+	if ar.done {
+		return Record{}, io.EOF
+	}
+	expected := ar.sum.Sum(nil) // 校验和记录覆盖其之前的全部字节
+
+	var head [5]byte
+	if _, err := io.ReadFull(ar.r, head[:]); err != nil {
+		return Record{}, truncated(err)
+	}
+	size := binary.BigEndian.Uint32(head[1:])
+	if size > maxRecordSize {
+		return Record{}, fmt.Errorf("%w: record of %d bytes", ErrMalformed, size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(ar.r, data); err != nil {
+		return Record{}, truncated(err)
+	}
+
+	if Kind(head[0]) == KindChecksum {
+		ar.done = true
+		if !bytes.Equal(data, expected) {
+			return Record{}, ErrChecksum
+		}
+		return Record{}, io.EOF
+	}
+	return Record{Kind: Kind(head[0]), Data: data}, nil
+}
+
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	return err
+}
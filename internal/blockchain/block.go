@@ -1,5 +1,7 @@
 package block_chain
 
+import "encoding/json"
+
 type Block struct {
 	Index        int           `json:"index"`
 	Timestamp    int64         `json:"timestamp"`
@@ -8,8 +10,45 @@ type Block struct {
 	Hash         string        `json:"hash"`
 	Nonce        int           `json:"nonce"`
 	Miner        string        `json:"miner"`
+	StateRoot    string        `json:"stateRoot,omitempty"` // 执行本区块后的状态根
+}
+
+// BlockHeader 区块头，同步时先下载区块头再按哈希拉取区块体
+type BlockHeader struct {
+	Index     int    `json:"index"`
+	Timestamp int64  `json:"timestamp"`
+	PrevHash  string `json:"prevHash"`
+	Hash      string `json:"hash"`
+	Nonce     int    `json:"nonce"`
+	Miner     string `json:"miner"`
+	StateRoot string `json:"stateRoot,omitempty"`
+}
+
+// Header 返回区块头
+func (b Block) Header() BlockHeader {
+	return BlockHeader{
+		Index:     b.Index,
+		Timestamp: b.Timestamp,
+		PrevHash:  b.PrevHash,
+		Hash:      b.Hash,
+		Nonce:     b.Nonce,
+		Miner:     b.Miner,
+		StateRoot: b.StateRoot,
+	}
 }
 
 var (
 	Blocks []Block
 )
+
+// EncodeBlock 把区块编码为存储和校验使用的字节序列
+func EncodeBlock(block Block) ([]byte, error) {
+	return json.Marshal(block)
+}
+
+// DecodeBlock 从EncodeBlock的结果还原区块
+func DecodeBlock(data []byte) (Block, error) {
+	var block Block
+	err := json.Unmarshal(data, &block)
+	return block, err
+}
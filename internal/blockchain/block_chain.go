@@ -27,6 +27,19 @@ type Blockchain struct {
 	Addresses     []string       `json:"addresses"`
 	Products      []string       `json:"products"`
 	Contributions map[string]int `json:"contributions"`
+
+	OnNewTransaction func(tx Transaction) `json:"-"` // 新交易进入交易池后回调
+	OnNewBlock       func(block Block)    `json:"-"` // 新区块上链后回调
+	CanMine          func() bool          `json:"-"` // 为nil或返回true时矿工才出块，同步落后时暂停挖矿
+
+	Pruning    bool `json:"pruning"`    // 开启后只保留最近KeepBlocks个区块体，更早的只保留区块头
+	KeepBlocks int  `json:"keepBlocks"` // 裁剪模式下保留区块体的数量
+
+	state       *State          // 链尾的账户状态
+	snapshots   []StateSnapshot // 最近的状态快照，按高度升序
+	prunedBelow int             // 高度小于该值的区块体已被裁剪
+
+	mu sync.RWMutex
 }
 
 func NewBlockchain() *Blockchain {
@@ -48,6 +61,9 @@ func NewBlockchain() *Blockchain {
 		bc.Contributions[addr] = 1 // 初始化每个地址的贡献值为1
 	}
 
+	bc.Pruning = config.PruningEnabled
+	bc.KeepBlocks = config.PruneKeepBlocks
+	bc.state = NewState()
 	bc.CreateGenesisBlock()
 	return bc
 }
@@ -55,7 +71,7 @@ func NewBlockchain() *Blockchain {
 func (bc *Blockchain) CreateGenesisBlock() {
 	genesisBlock := Block{
 		Index:        0,
-		Timestamp:    time.Now().Unix(),
+		Timestamp:    config.GenesisTimestamp,
 		Transactions: []Transaction{},
 		PrevHash:     "0",
 		Nonce:        0,
@@ -86,7 +102,13 @@ func (bc *Blockchain) GenerateRandomTransaction() Transaction {
 
 // AddTransaction 添加交易到待处理交易池
 func (bc *Blockchain) AddTransaction(tx Transaction) {
+	bc.mu.Lock()
 	bc.PendingTx = append(bc.PendingTx, tx)
+	bc.mu.Unlock()
+
+	if bc.OnNewTransaction != nil {
+		bc.OnNewTransaction(tx)
+	}
 }
 
 // CalculateHash 优化后的哈希计算函数
@@ -98,6 +120,7 @@ func (bc *Blockchain) CalculateHash(block Block) string {
 		Transactions []Transaction
 		PrevHash     string
 		Nonce        int
+		StateRoot    string `json:",omitempty"` // 为空时不参与序列化，旧区块哈希不变
 	}
 
 	tb := tempBlock{
@@ -106,6 +129,7 @@ func (bc *Blockchain) CalculateHash(block Block) string {
 		Transactions: block.Transactions,
 		PrevHash:     block.PrevHash,
 		Nonce:        block.Nonce,
+		StateRoot:    block.StateRoot,
 	}
 
 	blockBytes, _ := json.Marshal(tb)
@@ -115,6 +139,8 @@ func (bc *Blockchain) CalculateHash(block Block) string {
 
 // GetRandomMinerByContribution 权重随机矿工
 func (bc *Blockchain) GetRandomMinerByContribution() string {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
 	total := 0
 	for _, v := range bc.Contributions {
 		total += v
@@ -130,8 +156,31 @@ func (bc *Blockchain) GetRandomMinerByContribution() string {
 	return bc.Addresses[rand.Intn(len(bc.Addresses))] // 默认返回随机地址
 }
 
-// ProofOfWorkParallel 多线程挖矿
-func (bc *Blockchain) ProofOfWorkParallel(lastBlock Block, workerCount int) (Block, string) {
+// selectTransactions 在读锁下取链尾快照，并按顺序从交易池挑选能在链尾状态上依次执行的交易，
+// nonce不连续或余额不足的交易留在交易池中等待后续区块
+func (bc *Blockchain) selectTransactions(limit int) (Block, []Transaction, *State) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	tip := bc.Chain[len(bc.Chain)-1]
+	base := bc.state.Clone()
+	check := bc.state.Clone()
+	var txs []Transaction
+	for _, tx := range bc.PendingTx {
+		if len(txs) >= limit {
+			break
+		}
+		if err := check.CheckTx(tx); err != nil {
+			continue
+		}
+		check.applyTx(tx)
+		txs = append(txs, tx)
+	}
+	return tip, txs, base
+}
+
+// ProofOfWorkParallel 多线程挖矿，txs为打包的交易，base为lastBlock之后的状态，用于计算状态根
+func (bc *Blockchain) ProofOfWorkParallel(lastBlock Block, txs []Transaction, base *State, workerCount int) (Block, string) {
 
 	resultChan := make(chan PowResult, 1)
 	stopChan := make(chan struct{})
@@ -144,7 +193,7 @@ func (bc *Blockchain) ProofOfWorkParallel(lastBlock Block, workerCount int) (Blo
 			block := Block{
 				Index:        lastBlock.Index + 1,
 				Timestamp:    time.Now().Unix(),
-				Transactions: append([]Transaction(nil), bc.PendingTx[:3]...),
+				Transactions: append([]Transaction(nil), txs...),
 				PrevHash:     lastBlock.Hash,
 				Nonce:        rand.Intn(10000), // 随机初始Nonce值
 				Miner:        bc.GetRandomMinerByContribution(),
@@ -159,6 +208,9 @@ func (bc *Blockchain) ProofOfWorkParallel(lastBlock Block, workerCount int) (Blo
 				Description: "Mining reward",
 			}
 			block.Transactions = append(block.Transactions, rewardTx)
+			next := base.Clone()
+			next.Apply(block)
+			block.StateRoot = next.Root()
 
 			for {
 				select {
@@ -190,22 +242,55 @@ func (bc *Blockchain) ProofOfWorkParallel(lastBlock Block, workerCount int) (Blo
 	}
 }
 
-// MineBlock 挖矿生成新区块
+// MineBlock 挖矿生成新区块；挖矿期间链尾被其他节点的区块推进时放弃本次结果
 func (bc *Blockchain) MineBlock() Block {
-	lastBlock := bc.Chain[len(bc.Chain)-1]
-	newBlock, hash := bc.ProofOfWorkParallel(lastBlock, 4) // 使用4个工作线程进行挖矿
+	lastBlock, txs, base := bc.selectTransactions(config.MaxTxPerBlock)
+	if len(txs) == 0 {
+		fmt.Println("交易池中没有可打包的交易")
+		return Block{}
+	}
+	newBlock, hash := bc.ProofOfWorkParallel(lastBlock, txs, base, 4) // 使用4个工作线程进行挖矿
 	if hash == "" {
 		fmt.Println("挖矿失败")
 		return Block{}
 	}
 
 	newBlock.Hash = hash
+	bc.mu.Lock()
+	if tip := bc.Chain[len(bc.Chain)-1]; tip.Hash != lastBlock.Hash {
+		bc.mu.Unlock()
+		fmt.Printf("链尾已变为区块 %d，放弃挖出的区块 %d\n", tip.Index, newBlock.Index)
+		return Block{}
+	}
+	next := bc.state.Clone()
+	if err := next.ApplyChecked(newBlock); err != nil {
+		bc.mu.Unlock()
+		fmt.Printf("挖出的区块 %d 校验失败: %v\n", newBlock.Index, err)
+		return Block{}
+	}
+	included := make(map[string]bool, len(txs))
+	for _, tx := range txs {
+		included[tx.ID] = true
+	}
+	pending := bc.PendingTx[:0]
+	for _, tx := range bc.PendingTx {
+		if !included[tx.ID] {
+			pending = append(pending, tx)
+		}
+	}
+	bc.PendingTx = pending // 只移除已打包的交易，挖矿期间新到的交易保留
 	bc.Chain = append(bc.Chain, newBlock)
-	bc.PendingTx = bc.PendingTx[3:] // 移除已打包的交易
 	bc.Contributions[newBlock.Miner]++
+	bc.state = next
+	bc.afterAppend(newBlock)
+	bc.mu.Unlock()
 
 	AddBlock(newBlock)
 
+	if bc.OnNewBlock != nil {
+		bc.OnNewBlock(newBlock)
+	}
+
 	return newBlock
 }
 
@@ -253,8 +338,8 @@ func (bc *Blockchain) StartMiner(stop <-chan struct{}) {
 			case <-stop:
 				return
 			default:
-				if len(bc.PendingTx) >= config.MinTxToMine {
-					fmt.Printf("\n[矿工检测] 当前交易池中有 %d 笔交易，开始挖矿...\n", len(bc.PendingTx))
+				if pending := bc.PendingCount(); pending >= config.MinTxToMine && (bc.CanMine == nil || bc.CanMine()) {
+					fmt.Printf("\n[矿工检测] 当前交易池中有 %d 笔交易，开始挖矿...\n", pending)
 					start := time.Now()
 					block := bc.MineBlock()
 					if block.Index > 0 {
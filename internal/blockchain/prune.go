@@ -0,0 +1,177 @@
+package block_chain
+
+import (
+	"errors"
+	"fmt"
+
+	"blockchain/pkg/config"
+)
+
+var (
+	ErrSnapshotNotApplicable = errors.New("snapshot can only be imported into a fresh chain")
+	ErrRollbackTooDeep       = errors.New("cannot roll back past pruned blocks")
+)
+
+// afterAppend 区块上链后按间隔生成状态快照并裁剪旧区块体，调用方需持有bc.mu写锁
+func (bc *Blockchain) afterAppend(block Block) {
+	if block.StateRoot != "" && block.Index%config.SnapshotInterval == 0 {
+		bc.snapshots = append(bc.snapshots, StateSnapshot{
+			Height:    block.Index,
+			BlockHash: block.Hash,
+			StateRoot: block.StateRoot,
+			State:     bc.state.Clone(),
+		})
+		if len(bc.snapshots) > config.SnapshotsRetained {
+			bc.snapshots = bc.snapshots[len(bc.snapshots)-config.SnapshotsRetained:]
+		}
+	}
+	if bc.Pruning {
+		bc.prune()
+	}
+}
+
+// prune 裁剪链尾KeepBlocks个之前的区块体，只裁剪到最近的快照高度为止，
+// 保证裁剪掉的区块都能由快照恢复状态；调用方需持有bc.mu写锁
+func (bc *Blockchain) prune() {
+	if len(bc.snapshots) == 0 {
+		return
+	}
+	limit := bc.Chain[len(bc.Chain)-1].Index - bc.KeepBlocks + 1
+	if snap := bc.snapshots[len(bc.snapshots)-1].Height + 1; snap < limit {
+		limit = snap
+	}
+	if bc.prunedBelow < 1 {
+		bc.prunedBelow = 1 // 创世区块不裁剪
+	}
+	for i := bc.prunedBelow; i < limit && i < len(bc.Chain); i++ {
+		bc.Chain[i].Transactions = nil
+	}
+	if limit > bc.prunedBelow {
+		bc.prunedBelow = limit
+	}
+}
+
+// PrunedBelow 返回最低的仍保留区块体的高度
+func (bc *Blockchain) PrunedBelow() int {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.prunedBelow
+}
+
+// State 返回链尾状态的拷贝
+func (bc *Blockchain) State() *State {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.state.Clone()
+}
+
+// LatestSnapshot 返回最近的状态快照
+func (bc *Blockchain) LatestSnapshot() (StateSnapshot, bool) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	if len(bc.snapshots) == 0 {
+		return StateSnapshot{}, false
+	}
+	snap := bc.snapshots[len(bc.snapshots)-1]
+	snap.State = snap.State.Clone()
+	return snap, true
+}
+
+// ImportSnapshot 新节点从快照启动: headers为高度1到快照高度的区块头，
+// 校验区块头链接关系和快照状态根后，以这些区块头为链、以快照为当前状态
+func (bc *Blockchain) ImportSnapshot(snap StateSnapshot, headers []BlockHeader) error {
+	if len(headers) == 0 || headers[len(headers)-1].Index != snap.Height {
+		return fmt.Errorf("%w: headers do not reach height %d", ErrSnapshotMismatch, snap.Height)
+	}
+	if err := snap.Verify(headers[len(headers)-1]); err != nil {
+		return err
+	}
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if len(bc.Chain) != 1 {
+		return ErrSnapshotNotApplicable
+	}
+	prev := bc.Chain[0].Header()
+	for _, h := range headers {
+		if err := bc.ValidateHeader(prev, h); err != nil {
+			return err
+		}
+		prev = h
+	}
+
+	for _, h := range headers {
+		bc.Chain = append(bc.Chain, Block{
+			Index:     h.Index,
+			Timestamp: h.Timestamp,
+			PrevHash:  h.PrevHash,
+			Hash:      h.Hash,
+			Nonce:     h.Nonce,
+			Miner:     h.Miner,
+			StateRoot: h.StateRoot,
+		})
+	}
+	bc.state = snap.State.Clone()
+	bc.Contributions = make(map[string]int, len(snap.State.Contributions))
+	for k, v := range snap.State.Contributions {
+		bc.Contributions[k] = v
+	}
+	snap.State = snap.State.Clone()
+	bc.snapshots = []StateSnapshot{snap}
+	bc.prunedBelow = snap.Height + 1
+	return nil
+}
+
+// Rollback 回滚到height高度，用于切换到更长的分叉链: 从不高于height的最近快照
+// (没有快照时从创世状态)重放区块恢复状态，回滚区块中的普通交易放回交易池；
+// 重放所需的区块体已被裁剪时返回ErrRollbackTooDeep
+func (bc *Blockchain) Rollback(height int) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	tip := len(bc.Chain) - 1
+	if height < 0 || height > tip {
+		return fmt.Errorf("rollback height %d out of range 0-%d", height, tip)
+	}
+	if height == tip {
+		return nil
+	}
+
+	base, state := 0, NewState()
+	for i := len(bc.snapshots) - 1; i >= 0; i-- {
+		if bc.snapshots[i].Height <= height {
+			base, state = bc.snapshots[i].Height, bc.snapshots[i].State.Clone()
+			break
+		}
+	}
+	for i := base + 1; i <= height; i++ {
+		if i < bc.prunedBelow {
+			return fmt.Errorf("%w: block %d body pruned", ErrRollbackTooDeep, i)
+		}
+		state.Apply(bc.Chain[i])
+	}
+
+	known := make(map[string]bool, len(bc.PendingTx))
+	for _, tx := range bc.PendingTx {
+		known[tx.ID] = true
+	}
+	for _, block := range bc.Chain[height+1:] {
+		for _, tx := range block.Transactions {
+			if tx.Sender != "System" && !known[tx.ID] {
+				bc.PendingTx = append(bc.PendingTx, tx)
+				known[tx.ID] = true
+			}
+		}
+		bc.Contributions[block.Miner]--
+	}
+	bc.Chain = bc.Chain[:height+1]
+	bc.state = state
+
+	kept := bc.snapshots[:0]
+	for _, snap := range bc.snapshots {
+		if snap.Height <= height {
+			kept = append(kept, snap)
+		}
+	}
+	bc.snapshots = kept
+	return nil
+}
@@ -0,0 +1,71 @@
+package block_chain
+
+// LocatedTx 交易及其所在区块，交易池中的交易不带区块信息
+type LocatedTx struct {
+	Transaction
+	Height    int    `json:"height,omitempty"`
+	BlockHash string `json:"blockHash,omitempty"`
+}
+
+// Account 账户在链尾状态下的余额、已确认交易数和出块数
+type Account struct {
+	Address string  `json:"address"`
+	Balance float64 `json:"balance"`
+	Nonce   uint64  `json:"nonce"`
+	Blocks  int     `json:"blocks"`
+}
+
+// PendingTransactions 返回交易池的拷贝
+func (bc *Blockchain) PendingTransactions() []Transaction {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return append([]Transaction(nil), bc.PendingTx...)
+}
+
+// PendingCount 返回交易池中的交易数
+func (bc *Blockchain) PendingCount() int {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return len(bc.PendingTx)
+}
+
+// FindTransaction 在链上按ID查找交易，从链尾向前搜索，已裁剪的区块体不在搜索范围内
+func (bc *Blockchain) FindTransaction(id string) (LocatedTx, bool) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	for i := len(bc.Chain) - 1; i >= 0; i-- {
+		for _, tx := range bc.Chain[i].Transactions {
+			if tx.ID == id {
+				return LocatedTx{Transaction: tx, Height: bc.Chain[i].Index, BlockHash: bc.Chain[i].Hash}, true
+			}
+		}
+	}
+	return LocatedTx{}, false
+}
+
+// TransactionsOf 返回与地址相关的已上链交易，按高度从新到旧
+func (bc *Blockchain) TransactionsOf(address string) []LocatedTx {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	var txs []LocatedTx
+	for i := len(bc.Chain) - 1; i >= 0; i-- {
+		for _, tx := range bc.Chain[i].Transactions {
+			if tx.Sender == address || tx.Recipient == address {
+				txs = append(txs, LocatedTx{Transaction: tx, Height: bc.Chain[i].Index, BlockHash: bc.Chain[i].Hash})
+			}
+		}
+	}
+	return txs
+}
+
+// AccountOf 返回地址在链尾状态下的账户信息
+func (bc *Blockchain) AccountOf(address string) Account {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return Account{
+		Address: address,
+		Balance: bc.state.Balances[address],
+		Nonce:   bc.state.Nonces[address],
+		Blocks:  bc.state.Contributions[address],
+	}
+}
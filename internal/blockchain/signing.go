@@ -0,0 +1,87 @@
+package block_chain
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid transaction signature")
+	ErrSenderMismatch   = errors.New("sender does not match public key")
+	ErrTxIDMismatch     = errors.New("transaction id does not match its content")
+	ErrStaleNonce       = errors.New("transaction nonce already used")
+	ErrNonceGap         = errors.New("transaction nonce skips ahead of the account")
+	ErrInsufficientFund = errors.New("insufficient balance")
+	ErrUnsignedAccount  = errors.New("transactions from signed accounts must be signed")
+)
+
+// addressPrefix 签名账户地址前缀，后接公钥哈希的前40个十六进制字符
+const addressPrefix = "addr-"
+
+// AddressFromPublicKey 由公钥派生账户地址
+func AddressFromPublicKey(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return addressPrefix + hex.EncodeToString(sum[:])[:40]
+}
+
+// SigningBytes 交易中参与签名的字段的规范编码，不含ID、公钥和签名本身
+func (tx Transaction) SigningBytes() []byte {
+	data, _ := json.Marshal(struct {
+		Sender      string  `json:"sender"`
+		Recipient   string  `json:"recipient"`
+		Amount      float64 `json:"amount"`
+		Timestamp   int64   `json:"timestamp"`
+		Description string  `json:"description"`
+		Nonce       uint64  `json:"nonce"`
+	}{tx.Sender, tx.Recipient, tx.Amount, tx.Timestamp, tx.Description, tx.Nonce})
+	return data
+}
+
+// SignedTxID 签名交易的ID为签名内容的哈希，同一内容不会以不同ID重复提交
+func (tx Transaction) SignedTxID() string {
+	sum := sha256.Sum256(tx.SigningBytes())
+	return hex.EncodeToString(sum[:])
+}
+
+// isAccountAddress 判断地址是否由公钥派生，这类账户只能通过签名交易转出
+func isAccountAddress(addr string) bool {
+	return strings.HasPrefix(addr, addressPrefix)
+}
+
+// IsSigned 判断交易是否携带签名
+func (tx Transaction) IsSigned() bool {
+	return tx.Signature != "" || tx.PublicKey != ""
+}
+
+// SignTransaction 用私钥签名交易，同时填写发送方地址、公钥和ID
+func SignTransaction(tx Transaction, priv ed25519.PrivateKey) Transaction {
+	pub := priv.Public().(ed25519.PublicKey)
+	tx.Sender = AddressFromPublicKey(pub)
+	tx.PublicKey = hex.EncodeToString(pub)
+	tx.ID = tx.SignedTxID()
+	tx.Signature = hex.EncodeToString(ed25519.Sign(priv, tx.SigningBytes()))
+	return tx
+}
+
+// VerifySignature 校验签名交易: 发送方由公钥派生、ID与内容一致、签名有效
+func VerifySignature(tx Transaction) error {
+	pub, err := hex.DecodeString(tx.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return ErrInvalidSignature
+	}
+	if tx.Sender != AddressFromPublicKey(pub) {
+		return ErrSenderMismatch
+	}
+	if tx.ID != tx.SignedTxID() {
+		return ErrTxIDMismatch
+	}
+	sig, err := hex.DecodeString(tx.Signature)
+	if err != nil || !ed25519.Verify(pub, tx.SigningBytes(), sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
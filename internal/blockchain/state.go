@@ -0,0 +1,152 @@
+package block_chain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+var ErrSnapshotMismatch = errors.New("snapshot does not match block header")
+
+// State 执行到某一高度后的账户状态: 余额、已发送交易数(nonce)和矿工贡献表
+type State struct {
+	Balances      map[string]float64 `json:"balances"`
+	Nonces        map[string]uint64  `json:"nonces"`
+	Contributions map[string]int     `json:"contributions"`
+}
+
+// NewState 创建空状态
+func NewState() *State {
+	return &State{
+		Balances:      make(map[string]float64),
+		Nonces:        make(map[string]uint64),
+		Contributions: make(map[string]int),
+	}
+}
+
+// Clone 返回状态的深拷贝
+func (s *State) Clone() *State {
+	c := NewState()
+	for k, v := range s.Balances {
+		c.Balances[k] = v
+	}
+	for k, v := range s.Nonces {
+		c.Nonces[k] = v
+	}
+	for k, v := range s.Contributions {
+		c.Contributions[k] = v
+	}
+	return c
+}
+
+// Apply 执行区块中的交易: System发出的奖励只增加接收方余额，其余交易转账；
+// 签名交易把发送方nonce记为该交易的nonce，未签名交易递增nonce
+func (s *State) Apply(block Block) {
+	for _, tx := range block.Transactions {
+		s.applyTx(tx)
+	}
+	if block.Index > 0 {
+		s.Contributions[block.Miner]++
+	}
+}
+
+// ApplyChecked 逐笔校验并执行区块中的交易，签名交易的nonce必须连续且余额充足；
+// 返回错误时状态已部分修改，调用方应在拷贝上执行
+func (s *State) ApplyChecked(block Block) error {
+	for _, tx := range block.Transactions {
+		if err := s.CheckTx(tx); err != nil {
+			return fmt.Errorf("transaction %s: %w", tx.ID, err)
+		}
+		s.applyTx(tx)
+	}
+	if block.Index > 0 {
+		s.Contributions[block.Miner]++
+	}
+	return nil
+}
+
+// CheckTx 检查交易能否在当前状态上执行: 签名交易的nonce必须是发送方的下一个nonce，
+// 且余额足够；由公钥派生的账户不接受未签名交易
+func (s *State) CheckTx(tx Transaction) error {
+	if tx.Sender == "System" {
+		return nil
+	}
+	if !tx.IsSigned() {
+		if isAccountAddress(tx.Sender) {
+			return ErrUnsignedAccount
+		}
+		return nil
+	}
+	used := s.Nonces[tx.Sender]
+	switch {
+	case tx.Nonce <= used:
+		return fmt.Errorf("%w: nonce %d, confirmed %d", ErrStaleNonce, tx.Nonce, used)
+	case tx.Nonce > used+1:
+		return fmt.Errorf("%w: nonce %d, expected %d", ErrNonceGap, tx.Nonce, used+1)
+	}
+	if s.Balances[tx.Sender] < tx.Amount {
+		return fmt.Errorf("%w: balance %.2f, amount %.2f", ErrInsufficientFund, s.Balances[tx.Sender], tx.Amount)
+	}
+	return nil
+}
+
+func (s *State) applyTx(tx Transaction) {
+	if tx.Sender != "System" {
+		s.Balances[tx.Sender] -= tx.Amount
+		if tx.IsSigned() {
+			s.Nonces[tx.Sender] = tx.Nonce
+		} else {
+			s.Nonces[tx.Sender]++
+		}
+	}
+	s.Balances[tx.Recipient] += tx.Amount
+}
+
+// Root 状态根: 对按键排序后的各表逐行哈希，结果与map遍历顺序无关
+func (s *State) Root() string {
+	h := sha256.New()
+	for _, k := range sortedKeys(s.Balances) {
+		fmt.Fprintf(h, "b:%s=%s\n", k, strconv.FormatFloat(s.Balances[k], 'g', -1, 64))
+	}
+	for _, k := range sortedKeys(s.Nonces) {
+		fmt.Fprintf(h, "n:%s=%d\n", k, s.Nonces[k])
+	}
+	for _, k := range sortedKeys(s.Contributions) {
+		fmt.Fprintf(h, "c:%s=%d\n", k, s.Contributions[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// StateSnapshot 某一高度的状态快照，可对照该高度区块头中的StateRoot校验
+type StateSnapshot struct {
+	Height    int    `json:"height"`
+	BlockHash string `json:"block_hash"`
+	StateRoot string `json:"state_root"`
+	State     *State `json:"state"`
+}
+
+// Verify 校验快照与对应高度的区块头一致
+func (snap StateSnapshot) Verify(header BlockHeader) error {
+	if snap.State == nil {
+		return fmt.Errorf("%w: empty state", ErrSnapshotMismatch)
+	}
+	if header.Index != snap.Height || header.Hash != snap.BlockHash {
+		return fmt.Errorf("%w: snapshot is for block %d (%s)", ErrSnapshotMismatch, snap.Height, snap.BlockHash)
+	}
+	if header.StateRoot == "" || header.StateRoot != snap.StateRoot || snap.State.Root() != snap.StateRoot {
+		return fmt.Errorf("%w: state root differs at height %d", ErrSnapshotMismatch, snap.Height)
+	}
+	return nil
+}
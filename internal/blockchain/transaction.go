@@ -13,6 +13,9 @@ type Transaction struct {
 	Amount      float64 `json:"amount"`
 	Timestamp   int64   `json:"timestamp"`
 	Description string  `json:"description"`
+	Nonce       uint64  `json:"nonce,omitempty"`     // 签名交易的发送方序号，须大于链上已确认的交易数
+	PublicKey   string  `json:"publicKey,omitempty"` // 签名交易的发送方公钥(十六进制)，Sender由其派生
+	Signature   string  `json:"signature,omitempty"` // 对SigningBytes的ed25519签名(十六进制)
 }
 
 var (
@@ -0,0 +1,274 @@
+package block_chain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrDuplicateTx    = errors.New("transaction already known")
+	ErrDuplicateBlock = errors.New("block already known")
+)
+
+// ValidateTransaction 检查交易字段是否合法
+func (bc *Blockchain) ValidateTransaction(tx Transaction) error {
+	if tx.ID == "" {
+		return errors.New("transaction id is required")
+	}
+	if tx.Sender == "" || tx.Recipient == "" {
+		return errors.New("sender and recipient are required")
+	}
+	if tx.Sender == tx.Recipient {
+		return errors.New("sender and recipient must differ")
+	}
+	if tx.Sender == "System" {
+		return errors.New("system transactions are only valid inside blocks")
+	}
+	if tx.Amount <= 0 {
+		return fmt.Errorf("invalid amount %.2f", tx.Amount)
+	}
+	if tx.IsSigned() {
+		if err := VerifySignature(tx); err != nil {
+			return err
+		}
+	}
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.checkPending(tx)
+}
+
+// checkPending 在链尾状态上依次执行交易池中同一发送方的交易后检查tx，
+// 即签名交易的nonce必须紧接已确认和待确认的交易，余额扣除待确认转出后仍然足够；
+// 调用方需持有bc.mu
+func (bc *Blockchain) checkPending(tx Transaction) error {
+	if tx.Sender == "System" || (!tx.IsSigned() && !isAccountAddress(tx.Sender)) {
+		return nil
+	}
+	account := NewState()
+	account.Balances[tx.Sender] = bc.state.Balances[tx.Sender]
+	account.Nonces[tx.Sender] = bc.state.Nonces[tx.Sender]
+	for _, p := range bc.PendingTx {
+		if p.Sender == tx.Sender {
+			account.applyTx(p)
+		}
+	}
+	return account.CheckTx(tx)
+}
+
+// ValidateBlock 检查区块能否接在当前链尾之后
+func (bc *Blockchain) ValidateBlock(block Block) error {
+	tip := bc.LastBlock()
+	if block.Index != tip.Index+1 {
+		return fmt.Errorf("unexpected block index %d, tip is %d", block.Index, tip.Index)
+	}
+	if block.PrevHash != tip.Hash {
+		return fmt.Errorf("block %d prev hash mismatch", block.Index)
+	}
+	return bc.ValidateBlockHash(block)
+}
+
+// ValidateBlockHash 检查区块哈希与内容一致且满足难度要求
+func (bc *Blockchain) ValidateBlockHash(block Block) error {
+	if bc.CalculateHash(block) != block.Hash {
+		return fmt.Errorf("block %d hash mismatch", block.Index)
+	}
+	if !strings.HasPrefix(block.Hash, strings.Repeat("0", bc.Difficulty)) {
+		return fmt.Errorf("block %d does not meet difficulty %d", block.Index, bc.Difficulty)
+	}
+	rewards := 0
+	for _, tx := range block.Transactions {
+		if tx.Sender == "System" {
+			rewards++
+		}
+		if tx.IsSigned() {
+			if err := VerifySignature(tx); err != nil {
+				return fmt.Errorf("block %d transaction %s: %w", block.Index, tx.ID, err)
+			}
+		}
+	}
+	if rewards > 1 {
+		return fmt.Errorf("block %d has %d reward transactions", block.Index, rewards)
+	}
+	return nil
+}
+
+// AcceptTransaction 校验并加入交易池，已存在的交易返回ErrDuplicateTx；
+// 账户检查与加入交易池在同一把锁内完成，并发提交同一nonce时只有一笔成功
+func (bc *Blockchain) AcceptTransaction(tx Transaction) error {
+	if bc.HasTransaction(tx.ID) {
+		return ErrDuplicateTx
+	}
+	if err := bc.ValidateTransaction(tx); err != nil {
+		return err
+	}
+
+	bc.mu.Lock()
+	for _, p := range bc.PendingTx {
+		if p.ID == tx.ID {
+			bc.mu.Unlock()
+			return ErrDuplicateTx
+		}
+	}
+	if err := bc.checkPending(tx); err != nil {
+		bc.mu.Unlock()
+		return err
+	}
+	bc.PendingTx = append(bc.PendingTx, tx)
+	bc.mu.Unlock()
+
+	if bc.OnNewTransaction != nil {
+		bc.OnNewTransaction(tx)
+	}
+	return nil
+}
+
+// AcceptBlock 校验并追加来自其他节点的区块，同时从交易池移除已打包的交易
+func (bc *Blockchain) AcceptBlock(block Block) error {
+	if bc.HasBlock(block.Hash) {
+		return ErrDuplicateBlock
+	}
+
+	bc.mu.Lock()
+	tip := bc.Chain[len(bc.Chain)-1]
+	if block.Index != tip.Index+1 || block.PrevHash != tip.Hash {
+		bc.mu.Unlock()
+		return fmt.Errorf("block %d does not extend tip %d", block.Index, tip.Index)
+	}
+	if err := bc.ValidateBlockHash(block); err != nil {
+		bc.mu.Unlock()
+		return err
+	}
+	next := bc.state.Clone()
+	if err := next.ApplyChecked(block); err != nil {
+		bc.mu.Unlock()
+		return fmt.Errorf("block %d: %w", block.Index, err)
+	}
+	if block.StateRoot != "" && block.StateRoot != next.Root() {
+		bc.mu.Unlock()
+		return fmt.Errorf("block %d state root mismatch", block.Index)
+	}
+
+	included := make(map[string]bool, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		included[tx.ID] = true
+	}
+	pending := bc.PendingTx[:0]
+	for _, tx := range bc.PendingTx {
+		if !included[tx.ID] {
+			pending = append(pending, tx)
+		}
+	}
+	bc.PendingTx = pending
+	bc.Chain = append(bc.Chain, block)
+	bc.Contributions[block.Miner]++
+	bc.state = next
+	bc.afterAppend(block)
+	bc.mu.Unlock()
+
+	if bc.OnNewBlock != nil {
+		bc.OnNewBlock(block)
+	}
+	return nil
+}
+
+// LastBlock 返回链尾区块
+func (bc *Blockchain) LastBlock() Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.Chain[len(bc.Chain)-1]
+}
+
+// Height 返回链高度，即链尾区块索引
+func (bc *Blockchain) Height() int {
+	return bc.LastBlock().Index
+}
+
+// HasTransaction 判断交易是否在交易池中
+func (bc *Blockchain) HasTransaction(id string) bool {
+	_, ok := bc.GetTransaction(id)
+	return ok
+}
+
+// GetTransaction 从交易池中按ID查找交易
+func (bc *Blockchain) GetTransaction(id string) (Transaction, bool) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	for _, tx := range bc.PendingTx {
+		if tx.ID == id {
+			return tx, true
+		}
+	}
+	return Transaction{}, false
+}
+
+// HasBlock 判断区块是否已在链上，区块体已被裁剪的区块同样视为已在链上
+func (bc *Blockchain) HasBlock(hash string) bool {
+	_, ok := bc.GetHeaderByHash(hash)
+	return ok
+}
+
+// GetBlockByHash 按哈希查找链上区块，区块体已被裁剪时返回false
+func (bc *Blockchain) GetBlockByHash(hash string) (Block, bool) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	for i := len(bc.Chain) - 1; i >= 0; i-- {
+		if bc.Chain[i].Hash == hash {
+			if bc.Chain[i].Index > 0 && bc.Chain[i].Index < bc.prunedBelow {
+				return Block{}, false
+			}
+			return bc.Chain[i], true
+		}
+	}
+	return Block{}, false
+}
+
+// GetHeaderByHash 按哈希查找链上区块头，包括区块体已被裁剪的区块
+func (bc *Blockchain) GetHeaderByHash(hash string) (BlockHeader, bool) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	for i := len(bc.Chain) - 1; i >= 0; i-- {
+		if bc.Chain[i].Hash == hash {
+			return bc.Chain[i].Header(), true
+		}
+	}
+	return BlockHeader{}, false
+}
+
+// GetBlockByIndex 按高度查找链上区块，区块体已被裁剪时返回false
+func (bc *Blockchain) GetBlockByIndex(index int) (Block, bool) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	if index < 0 || index >= len(bc.Chain) || (index > 0 && index < bc.prunedBelow) {
+		return Block{}, false
+	}
+	return bc.Chain[index], true
+}
+
+// GetHeaders 返回从from高度开始的至多count个区块头
+func (bc *Blockchain) GetHeaders(from, count int) []BlockHeader {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	var headers []BlockHeader
+	for i := from; i < len(bc.Chain) && len(headers) < count; i++ {
+		if i < 0 {
+			continue
+		}
+		headers = append(headers, bc.Chain[i].Header())
+	}
+	return headers
+}
+
+// ValidateHeader 检查区块头能否接在prev之后，区块体到达后再校验完整哈希
+func (bc *Blockchain) ValidateHeader(prev, header BlockHeader) error {
+	if header.Index != prev.Index+1 {
+		return fmt.Errorf("unexpected header index %d after %d", header.Index, prev.Index)
+	}
+	if header.PrevHash != prev.Hash {
+		return fmt.Errorf("header %d prev hash mismatch", header.Index)
+	}
+	if !strings.HasPrefix(header.Hash, strings.Repeat("0", bc.Difficulty)) {
+		return fmt.Errorf("header %d does not meet difficulty %d", header.Index, bc.Difficulty)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package compress
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"sync"
+)
+
+var (
+	ErrUnknownCodec = errors.New("unknown compression codec")
+	ErrTooLarge     = errors.New("decompressed data too large")
+)
+
+// 编解码器ID写入段头和帧头，已分配的ID不能变更
+const (
+	IDNone  byte = 0
+	IDFlate byte = 1
+	IDGzip  byte = 2
+)
+
+// Codec 压缩编解码器
+type Codec interface {
+	ID() byte
+	Name() string
+	Compress(src []byte) ([]byte, error)
+	Decompress(src []byte, limit int) ([]byte, error)
+}
+
+var (
+	mu     sync.RWMutex
+	byID   = make(map[byte]Codec)
+	byName = make(map[string]Codec)
+)
+
+func init() {
+	Register(noneCodec{})
+	Register(flateCodec{})
+	Register(gzipCodec{})
+}
+
+// Register 注册编解码器，同ID或同名的已有编解码器被替换
+func Register(c Codec) {
+	mu.Lock()
+	defer mu.Unlock()
+	byID[c.ID()] = c
+	byName[c.Name()] = c
+}
+
+// ByID 按ID查找编解码器
+func ByID(id byte) (Codec, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := byID[id]
+	if !ok {
+		return nil, ErrUnknownCodec
+	}
+	return c, nil
+}
+
+// ByName 按名称查找编解码器，空名称表示不压缩
+func ByName(name string) (Codec, error) {
+	if name == "" {
+		name = "none"
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := byName[name]
+	if !ok {
+		return nil, ErrUnknownCodec
+	}
+	return c, nil
+}
+
+// Negotiate 按本地偏好顺序选出对端也支持的第一个编解码器，没有共同的则不压缩
+func Negotiate(local, remote []string) Codec {
+	for _, name := range local {
+		for _, r := range remote {
+			if name != r {
+				continue
+			}
+			if c, err := ByName(name); err == nil {
+				return c
+			}
+		}
+	}
+	return noneCodec{}
+}
+
+// readLimited 读取解压数据，超过limit字节时报错，limit<=0表示不限
+func readLimited(r io.Reader, limit int) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	out, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > limit {
+		return nil, ErrTooLarge
+	}
+	return out, nil
+}
+
+type noneCodec struct{}
+
+func (noneCodec) ID() byte     { return IDNone }
+func (noneCodec) Name() string { return "none" }
+
+func (noneCodec) Compress(src []byte) ([]byte, error) {
+	return src, nil
+}
+
+func (noneCodec) Decompress(src []byte, limit int) ([]byte, error) {
+	if limit > 0 && len(src) > limit {
+		return nil, ErrTooLarge
+	}
+	return src, nil
+}
+
+type flateCodec struct{}
+
+func (flateCodec) ID() byte     { return IDFlate }
+func (flateCodec) Name() string { return "flate" }
+
+func (flateCodec) Compress(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (flateCodec) Decompress(src []byte, limit int) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(src))
+	defer r.Close()
+	return readLimited(r, limit)
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) ID() byte     { return IDGzip }
+func (gzipCodec) Name() string { return "gzip" }
+
+func (gzipCodec) Compress(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decompress(src []byte, limit int) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return readLimited(r, limit)
+}
@@ -0,0 +1,58 @@
+package compress
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// 段头: 2字节魔数 + 1字节版本 + 1字节编解码器ID + 4字节大端原始长度
+const (
+	segmentMagic0  = 'B'
+	segmentMagic1  = 'S'
+	segmentVersion = 1
+	SegmentHeader  = 8
+)
+
+var ErrBadSegment = errors.New("malformed segment")
+
+// EncodeSegment 用codec压缩数据并加上段头
+func EncodeSegment(c Codec, raw []byte) ([]byte, error) {
+	body, err := c.Compress(raw)
+	if err != nil {
+		return nil, err
+	}
+	seg := make([]byte, SegmentHeader+len(body))
+	seg[0], seg[1], seg[2], seg[3] = segmentMagic0, segmentMagic1, segmentVersion, c.ID()
+	binary.BigEndian.PutUint32(seg[4:], uint32(len(raw)))
+	copy(seg[SegmentHeader:], body)
+	return seg, nil
+}
+
+// DecodeSegment 按段头记录的编解码器解压；没有段头的旧数据原样返回
+func DecodeSegment(seg []byte) ([]byte, error) {
+	if !IsSegment(seg) {
+		return seg, nil
+	}
+	if seg[2] != segmentVersion {
+		return nil, fmt.Errorf("%w: version %d", ErrBadSegment, seg[2])
+	}
+	c, err := ByID(seg[3])
+	if err != nil {
+		return nil, err
+	}
+	size := int(binary.BigEndian.Uint32(seg[4:]))
+	raw, err := c.Decompress(seg[SegmentHeader:], size)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != size {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrBadSegment, size, len(raw))
+	}
+	return raw, nil
+}
+
+// IsSegment 判断数据是否带段头
+func IsSegment(data []byte) bool {
+	return len(data) >= SegmentHeader && data[0] == segmentMagic0 && data[1] == segmentMagic1
+}
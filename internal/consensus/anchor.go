@@ -0,0 +1,157 @@
+package consensus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"blockchain/global"
+	"blockchain/internal/network"
+	"blockchain/service"
+)
+
+// AnchorManager 选举锚节点并保证同一时间只有一个锚节点监听器在运行：
+// 重新选举时先停止旧监听器，新监听器从已分发到的高度继续，区块不会被重复分发
+type AnchorManager struct {
+	raft   *Raft
+	sender network.BlockAssignSender
+
+	mu        sync.Mutex
+	anchor    *network.Node
+	stop      chan struct{}
+	processed int // 已分发的最高区块高度
+}
+
+// NewAnchorManager 创建锚节点管理器，raft决定选举方式；
+// VRF选举时注册对端锚节点公告的验证函数，公告必须附带可验证的选举结果
+func NewAnchorManager(raft *Raft, sender network.BlockAssignSender) *AnchorManager {
+	m := &AnchorManager{raft: raft, sender: sender, processed: -1}
+	if raft.Mode == ElectionByVRF {
+		network.SetElectionVerifier(m.verifyAnnouncement)
+	}
+	return m
+}
+
+// Election 返回最近一次VRF选举结果，按得分选举或尚未选举时为nil
+func (m *AnchorManager) Election() *ElectionResult {
+	return m.raft.LastResult()
+}
+
+// verifyAnnouncement 验证对端锚节点公告中的选举结果: 纪元和获胜者与公告一致，
+// 候选节点都在本地节点表中(公钥和贡献值以本地记录为准)，再由VerifyElection重新计算种子、权重和抽签
+func (m *AnchorManager) verifyAnnouncement(a network.Anchor) error {
+	if len(a.Election) == 0 {
+		return errors.New("announcement carries no election result")
+	}
+	var result ElectionResult
+	if err := json.Unmarshal(a.Election, &result); err != nil {
+		return err
+	}
+	if result.Epoch != a.Epoch || result.Winner != a.ID {
+		return fmt.Errorf("election epoch %d winner %s does not match announcement", result.Epoch, result.Winner)
+	}
+	nodes := make([]*network.Node, 0, len(result.Candidates))
+	for _, c := range result.Candidates {
+		n := service.GetNodeByID(c.NodeID)
+		if n == nil {
+			return fmt.Errorf("candidate %s: unknown node", c.NodeID)
+		}
+		nodes = append(nodes, n)
+	}
+	return m.raft.VerifyElection(&result, nodes)
+}
+
+// Elect 在所有已登记、未排空、未因挑战失败被暂停且持有私钥的节点中选举锚节点，并切换监听器；
+// 远端节点的私钥不在本进程，无法签名分配信息，因此不参与选举
+func (m *AnchorManager) Elect() *network.Node {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var candidates []*network.Node
+	for _, n := range service.GetAllNodes() {
+		if !n.Draining && !n.Suspended() && n.CanSign() {
+			candidates = append(candidates, n)
+		}
+	}
+	anchor := m.raft.ElectAnchor(candidates)
+
+	// 已下线的旧锚节点不在节点表中，SetAnchor清除不到它的标记
+	if m.anchor != nil && m.anchor != anchor {
+		m.anchor.IsAnchor = false
+	}
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+	// 每次选举进入新纪元，旧锚节点在旧纪元签发的分配信息不再被接受
+	epoch := network.CurrentAnchor().Epoch + 1
+	var announcement network.Anchor
+	if anchor != nil {
+		var err error
+		if announcement, err = anchor.SignAnchor(epoch, m.electionJSON()); err != nil {
+			log.Printf("[锚节点] 节点 %s 签名锚节点公告失败: %v", anchor.ID, err)
+			anchor = nil
+		}
+	}
+	if anchor == nil {
+		network.SetAnchor(network.Anchor{Epoch: epoch})
+		service.SetAnchor("")
+		m.anchor = nil
+		global.AnchorNode = nil
+		log.Printf("[锚节点] 没有可选举的节点")
+		return nil
+	}
+	network.SetAnchor(announcement)
+	if global.Host != nil {
+		global.Host.AnnounceAnchor(announcement)
+	}
+	service.SetAnchor(anchor.ID)
+	m.anchor = anchor
+	global.AnchorNode = anchor
+
+	m.stop = make(chan struct{})
+	m.raft.StartAnchorListener(anchor.ID, m.sender, m.processed, m.markProcessed, m.stop)
+	log.Printf("[锚节点] 节点 %s 当选锚节点，从区块 %d 之后继续分发", anchor.ID, m.processed)
+	return anchor
+}
+
+// Anchor 返回当前锚节点
+func (m *AnchorManager) Anchor() *network.Node {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.anchor
+}
+
+// Stop 停止当前的锚节点监听器
+func (m *AnchorManager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// electionJSON 编码本次VRF选举结果，随锚节点公告发送，按得分选举时为空
+func (m *AnchorManager) electionJSON() json.RawMessage {
+	result := m.raft.LastResult()
+	if m.raft.Mode != ElectionByVRF || result == nil {
+		return nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("[锚节点] 编码选举结果失败: %v", err)
+		return nil
+	}
+	return data
+}
+
+func (m *AnchorManager) markProcessed(index int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if index > m.processed {
+		m.processed = index
+	}
+}
@@ -5,18 +5,48 @@ import (
 	"blockchain/internal/hash"
 	"blockchain/internal/network"
 	"blockchain/internal/storage"
+	"blockchain/internal/vrf"
+	"blockchain/pkg/config"
 	"blockchain/service"
 	"log"
 	"math/rand"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
-type Raft struct{}
+type Raft struct {
+	Mode          ElectionMode  // 选举方式，默认按得分
+	Backend       vrf.Backend   // VRF选举使用的后端
+	ScoreVerifier ScoreVerifier // VRF选举中计算候选权重
+	FixedScores   bool          // 为true时直接使用节点已有的评分，不重新采集性能指标，供模拟网络注入评分
+
+	mu           sync.Mutex
+	Epoch        uint64          // 当前选举纪元，由mu保护
+	LastElection *ElectionResult // 最近一次VRF选举结果，由mu保护，读取使用LastResult
+}
 
 // NewRaft New creates a new Raft instance
 func NewRaft() *Raft {
-	return &Raft{}
+	return &Raft{Mode: ElectionByScore}
+}
+
+// NewVRFRaft 创建使用VRF加权抽签选举锚节点的Raft实例
+func NewVRFRaft(backend vrf.Backend) *Raft {
+	return &Raft{
+		Mode:          ElectionByVRF,
+		Backend:       backend,
+		ScoreVerifier: MedianCappedContribution,
+	}
+}
+
+// NewConfiguredRaft 按config.AnchorElection选择锚节点选举方式
+func NewConfiguredRaft() *Raft {
+	if config.AnchorElection == "vrf" {
+		return NewVRFRaft(vrf.NewEd25519Backend())
+	}
+	return NewRaft()
 }
 
 func (r *Raft) ElectAnchor(nodes []*network.Node) *network.Node {
@@ -25,11 +55,20 @@ func (r *Raft) ElectAnchor(nodes []*network.Node) *network.Node {
 	}
 
 	for _, n := range nodes {
-		n.CalculateScore(n)
+		if !r.FixedScores {
+			n.CalculateScore(n)
+		}
 		n.IsAnchor = false // Reset anchor status
 	}
 
+	if r.Mode == ElectionByVRF {
+		return r.electByVRF(nodes)
+	}
+
 	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Score == nodes[j].Score {
+			return nodes[i].ID < nodes[j].ID // 同分时按ID，选举结果与输入顺序无关
+		}
 		return nodes[i].Score > nodes[j].Score // Sort by score in descending order
 	})
 
@@ -39,16 +78,28 @@ func (r *Raft) ElectAnchor(nodes []*network.Node) *network.Node {
 	return anchor
 }
 
-// StartAnchorListener 启动锚节点监听器，监听区块池并分发区块
-func (r *Raft) StartAnchorListener(nodeID string, assignChan chan network.BlockAssignInfo) {
+// StartAnchorListener 启动锚节点监听器，监听区块池并分发高度大于from的区块，
+// 每分发一个区块调用onProcessed，stop关闭后退出
+func (r *Raft) StartAnchorListener(nodeID string, sender network.BlockAssignSender, from int, onProcessed func(index int), stop <-chan struct{}) {
 	go func() {
 		log.Printf("[锚节点监听器] 节点 %s 开始监听区块池...", nodeID)
 
-		lastProcessedIndex := -1 // 记录最后处理的区块索引
+		lastProcessedIndex := from // 记录最后处理的区块索引
+		wait := func(d time.Duration) bool {
+			select {
+			case <-stop:
+				log.Printf("[锚节点监听器] 节点 %s 停止监听", nodeID)
+				return false
+			case <-time.After(d):
+				return true
+			}
+		}
 
 		for {
 			if !service.IsCurrentNodeAnchor(nodeID) {
-				time.Sleep(30 * time.Second) // 每30秒检查一次锚节点状态
+				if !wait(30 * time.Second) { // 每30秒检查一次锚节点状态
+					return
+				}
 				continue
 			}
 
@@ -56,24 +107,38 @@ func (r *Raft) StartAnchorListener(nodeID string, assignChan chan network.BlockA
 			availableNodes := r.getAvailableNodes()
 			if len(availableNodes) == 0 {
 				log.Printf("[锚节点] 没有可用的节点进行区块分发")
-				time.Sleep(5 * time.Second)
+				if !wait(5 * time.Second) {
+					return
+				}
 				continue
 			}
 
 			// 获取区块池中的新区块
 			newBlocks := r.getNewBlocks(lastProcessedIndex)
 			if len(newBlocks) == 0 {
-				time.Sleep(2 * time.Second)
+				if !wait(2 * time.Second) {
+					return
+				}
 				continue
 			}
 
 			// 分发新区块
 			for _, block := range newBlocks {
-				r.distributeBlock(block, availableNodes, nodeID, assignChan)
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				r.distributeBlock(block, availableNodes, nodeID, sender)
 				lastProcessedIndex = block.Index
+				if onProcessed != nil {
+					onProcessed(block.Index)
+				}
 			}
 
-			time.Sleep(1 * time.Second)
+			if !wait(1 * time.Second) {
+				return
+			}
 		}
 	}()
 }
@@ -83,7 +148,7 @@ func (r *Raft) getAvailableNodes() []*network.Node {
 	var availableNodes []*network.Node
 
 	for _, node := range service.GetAllNodes() {
-		if node != nil && node.Score > 0 {
+		if node != nil && node.Score > 0 && !node.Draining && !node.Suspended() {
 			availableNodes = append(availableNodes, node)
 		}
 	}
@@ -105,35 +170,75 @@ func (r *Raft) getNewBlocks(lastProcessedIndex int) []bc.Block {
 	return newBlocks
 }
 
-// distributeBlock 分发区块到不同节点，并通过通道广播分配信息
-func (r *Raft) distributeBlock(block bc.Block, availableNodes []*network.Node, anchorNodeID string, assignChan chan network.BlockAssignInfo) {
-	// 使用一致性哈希选择目标节点
-	targetNodeID, err := hash.GetNode(block.Hash)
-	if err != nil {
+// distributeBlock 将区块分发到一致性哈希环上的多个副本节点，并通过传输层下发分配信息
+func (r *Raft) distributeBlock(block bc.Block, availableNodes []*network.Node, anchorNodeID string, sender network.BlockAssignSender) {
+	if config.StorageMode == storage.ModeErasure {
+		r.distributeShards(block, anchorNodeID)
+		return
+	}
+
+	// 使用加权一致性哈希选择副本节点，副本尽量分布在不同故障域，并跳过负载超过上限的节点
+	replicas, err := hash.GetReplicasBounded(block.Hash, config.ReplicationFactor, service.NodeZone, storage.NodeLoad)
+	if err != nil || len(replicas) == 0 {
 		// 如果一致性哈希失败，使用负载均衡策略
-		targetNodeID = r.selectNodeByLoadBalance(availableNodes)
+		replicas = []string{r.selectNodeByLoadBalance(availableNodes)}
 	}
 
-	// 存储区块到目标节点
-	storage.StoreBlock(targetNodeID, &block)
+	// 环上其余节点按顺序作为候选，副本节点配额已满时由其接替
+	successors, _ := hash.GetReplicas(block.Hash, len(hash.Members()), service.NodeZone)
+	replicas = storage.PlaceReplicas(block, append(replicas, successors...), config.ReplicationFactor)
+	if len(replicas) == 0 {
+		log.Printf("[锚节点分发] 区块 %d 没有节点有足够的存储配额", block.Index)
+		return
+	}
 
+	if err := storage.RecordCommitment(block); err != nil {
+		log.Printf("[锚节点分发] 区块 %d 记录存储承诺失败: %v", block.Index, err)
+	}
 	anchorNode := service.GetNodeByID(anchorNodeID)
-	anchorNode.NodeBlockMap[targetNodeID] = append(anchorNode.NodeBlockMap[targetNodeID], block.Hash)
 
 	// 增加锚节点的贡献值
 	service.AddContribution(anchorNodeID, 10.0)
 
-	// 增加目标节点的贡献值
-	service.AddContribution(targetNodeID, 5.0)
+	for _, targetNodeID := range replicas {
+		anchorNode.RecordAssignment(targetNodeID, block.Hash)
+
+		// 增加副本节点的贡献值
+		service.AddContribution(targetNodeID, 5.0)
+
+		// 锚节点签名后通过传输层下发分配信息
+		info := network.BlockAssignInfo{
+			Block:        block,
+			TargetNodeID: targetNodeID,
+		}
+		if err := anchorNode.SignBlockAssign(&info); err != nil {
+			log.Printf("[锚节点分发] 区块 %d 分配信息签名失败: %v", block.Index, err)
+			return
+		}
+		if err := sender.SendBlockAssign(info); err != nil {
+			log.Printf("[锚节点分发] 区块 %d 分配信息发送至 %s 失败: %v", block.Index, targetNodeID, err)
+		}
+	}
 
 	log.Printf("[锚节点分发] 区块 %d (哈希: %s) 分发至节点 %s",
-		block.Index, block.Hash[:8], targetNodeID)
+		block.Index, block.Hash[:8], strings.Join(replicas, ","))
+}
+
+// distributeShards 纠删码模式下把区块切分为分片放到环上不同的后继节点
+func (r *Raft) distributeShards(block bc.Block, anchorNodeID string) {
+	owners, err := storage.StoreErasure(block)
+	if err != nil {
+		log.Printf("[锚节点分发] 区块 %d 纠删码存储失败: %v", block.Index, err)
+		return
+	}
 
-	// 通过通道广播分配信息
-	assignChan <- network.BlockAssignInfo{
-		Block:        block,
-		TargetNodeID: targetNodeID,
+	service.AddContribution(anchorNodeID, 10.0)
+	for _, id := range owners {
+		service.AddContribution(id, 5.0)
 	}
+
+	log.Printf("[锚节点分发] 区块 %d (哈希: %s) 以 %d+%d 分片分发至节点 %s",
+		block.Index, block.Hash[:8], config.ErasureDataShards, config.ErasureParityShards, strings.Join(owners, ","))
 }
 
 // selectNodeByLoadBalance 使用负载均衡策略选择节点
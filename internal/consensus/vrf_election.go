@@ -0,0 +1,247 @@
+package consensus
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	bc "blockchain/internal/blockchain"
+	"blockchain/internal/network"
+	"blockchain/internal/vrf"
+	"blockchain/pkg/config"
+)
+
+// ElectionMode 锚节点选举方式
+type ElectionMode int
+
+const (
+	ElectionByScore ElectionMode = iota // 按节点得分排序
+	ElectionByVRF                       // 按VRF输出加权抽签
+)
+
+// weightCapFactor 权重上限为候选节点权重中位数的倍数，限制单个节点积累的优势
+const weightCapFactor = 2.0
+
+// ScoreVerifier 计算候选节点用于抽签的权重；验证方用本地数据重新计算，
+// 因此只能使用各节点独立记账得到的值，不能使用对端自报的性能指标
+type ScoreVerifier func(node *network.Node, candidates []*network.Node) float64
+
+// Candidate 参与VRF选举的候选节点及其证明
+type Candidate struct {
+	NodeID    string  `json:"node_id"`
+	PublicKey []byte  `json:"public_key"`
+	Output    []byte  `json:"output"`
+	Proof     []byte  `json:"proof"`
+	Weight    float64 `json:"weight"`
+	Ticket    float64 `json:"ticket"`
+}
+
+// ElectionResult VRF选举结果，任何节点都可以据此验证获胜者
+type ElectionResult struct {
+	Epoch      uint64      `json:"epoch"`
+	Seed       []byte      `json:"seed"`
+	Backend    string      `json:"backend"`
+	Candidates []Candidate `json:"candidates"`
+	Winner     string      `json:"winner"`
+}
+
+// EpochSeed 由上一区块哈希和纪元编号派生选举种子
+func EpochSeed(prevHash string, epoch uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, epoch)
+	h := sha256.New()
+	h.Write([]byte(prevHash))
+	h.Write(buf)
+	return h.Sum(nil)
+}
+
+// observedWeight 本地记账的贡献值加1，新节点也有机会当选；
+// 连续挑战失败达到上限的节点权重为0
+func observedWeight(node *network.Node) float64 {
+	if node.ChallengeFailures >= config.MaxChallengeFailures {
+		return 0
+	}
+	return 1 + math.Max(node.Contribution, 0)
+}
+
+// MedianCappedContribution 默认权重: 本地记账的贡献值，不超过中位数的weightCapFactor倍。
+// 贡献值由保存副本和挑战结果在各节点本地累计，CPU、磁盘等自报指标不参与抽签
+func MedianCappedContribution(node *network.Node, candidates []*network.Node) float64 {
+	weights := make([]float64, 0, len(candidates))
+	for _, c := range candidates {
+		if w := observedWeight(c); w > 0 {
+			weights = append(weights, w)
+		}
+	}
+	weight := observedWeight(node)
+	if len(weights) == 0 || weight <= 0 {
+		return 0
+	}
+	sort.Float64s(weights)
+	median := weights[len(weights)/2]
+	return math.Min(weight, median*weightCapFactor)
+}
+
+// electByVRF 每个候选节点对纪元种子计算VRF输出，按权重抽签选出锚节点。
+// 纪元与随后签发的锚节点公告一致，验证方据此核对公告中的选举结果
+func (r *Raft) electByVRF(nodes []*network.Node) *network.Node {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Backend == nil {
+		r.Backend = vrf.NewEd25519Backend()
+	}
+	if r.ScoreVerifier == nil {
+		r.ScoreVerifier = MedianCappedContribution
+	}
+
+	r.Epoch = network.CurrentAnchor().Epoch + 1
+	seed := EpochSeed(latestBlockHash(), r.Epoch)
+
+	// 无法给出VRF证明的节点(例如远端节点)不参与抽签，权重只在给出证明的候选之间计算，
+	// 验证方据此核对候选集合
+	type proven struct {
+		node          *network.Node
+		output, proof []byte
+	}
+	var provers []proven
+	var candidates []*network.Node
+	for _, n := range nodes {
+		output, proof, err := n.ProveVRF(r.Backend, seed)
+		if err != nil {
+			continue
+		}
+		provers = append(provers, proven{node: n, output: output, proof: proof})
+		candidates = append(candidates, n)
+	}
+
+	result := &ElectionResult{
+		Epoch:   r.Epoch,
+		Seed:    seed,
+		Backend: r.Backend.Name(),
+	}
+
+	var anchor *network.Node
+	best := math.Inf(1)
+	for _, p := range provers {
+		weight := r.ScoreVerifier(p.node, candidates)
+		ticket := vrf.Ticket(p.output, weight)
+		result.Candidates = append(result.Candidates, Candidate{
+			NodeID:    p.node.ID,
+			PublicKey: p.node.PublicKey,
+			Output:    p.output,
+			Proof:     p.proof,
+			Weight:    weight,
+			Ticket:    ticket,
+		})
+		if ticket < best {
+			best = ticket
+			anchor = p.node
+		}
+	}
+
+	if anchor == nil {
+		return nil
+	}
+	result.Winner = anchor.ID
+	r.LastElection = result
+	anchor.IsAnchor = true
+	return anchor
+}
+
+// LastResult 返回最近一次VRF选举结果，按得分选举时为nil
+func (r *Raft) LastResult() *ElectionResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.LastElection
+}
+
+// VerifyElection 用本地数据验证选举结果: 种子由本地的上一区块哈希和纪元重新计算，
+// 候选集合必须与本地已知的候选节点完全一致，权重由weigh在本地重新计算，
+// 再检查每个候选的VRF证明并确认获胜者抽签值最小
+func VerifyElection(result *ElectionResult, backend vrf.Backend, prevHash string, nodes []*network.Node, weigh ScoreVerifier) error {
+	if result == nil || len(result.Candidates) == 0 {
+		return errors.New("empty election result")
+	}
+	if backend.Name() != result.Backend {
+		return fmt.Errorf("backend mismatch: want %s, got %s", result.Backend, backend.Name())
+	}
+	if seed := EpochSeed(prevHash, result.Epoch); !bytes.Equal(seed, result.Seed) {
+		return fmt.Errorf("seed mismatch for epoch %d", result.Epoch)
+	}
+
+	known := make(map[string]*network.Node, len(nodes))
+	for _, n := range nodes {
+		known[n.ID] = n
+	}
+	if len(result.Candidates) != len(known) {
+		return fmt.Errorf("candidate set mismatch: want %d, got %d", len(known), len(result.Candidates))
+	}
+
+	seen := make(map[string]bool, len(result.Candidates))
+	winner := ""
+	best := math.Inf(1)
+	for _, c := range result.Candidates {
+		node, ok := known[c.NodeID]
+		if !ok {
+			return fmt.Errorf("candidate %s: unknown node", c.NodeID)
+		}
+		if seen[c.NodeID] {
+			return fmt.Errorf("candidate %s: duplicated", c.NodeID)
+		}
+		seen[c.NodeID] = true
+		if !bytes.Equal(c.PublicKey, node.PublicKey) {
+			return fmt.Errorf("candidate %s: public key mismatch", c.NodeID)
+		}
+		if err := network.VerifyNodeID(c.NodeID, c.PublicKey); err != nil {
+			return err
+		}
+		if err := backend.Verify(c.PublicKey, result.Seed, c.Output, c.Proof); err != nil {
+			return fmt.Errorf("candidate %s: %w", c.NodeID, err)
+		}
+		weight := weigh(node, nodes)
+		if weight != c.Weight {
+			return fmt.Errorf("candidate %s: weight mismatch: want %v, got %v", c.NodeID, weight, c.Weight)
+		}
+		ticket := vrf.Ticket(c.Output, weight)
+		if ticket != c.Ticket {
+			return fmt.Errorf("candidate %s: ticket mismatch", c.NodeID)
+		}
+		if ticket < best {
+			best = ticket
+			winner = c.NodeID
+		}
+	}
+
+	if winner != result.Winner {
+		return fmt.Errorf("winner mismatch: want %s, got %s", winner, result.Winner)
+	}
+	return nil
+}
+
+// VerifyElection 以本地最新区块哈希和本实例的后端、权重函数验证选举结果，
+// nodes为本地已知的候选节点
+func (r *Raft) VerifyElection(result *ElectionResult, nodes []*network.Node) error {
+	r.mu.Lock()
+	weigh, backend := r.ScoreVerifier, r.Backend
+	r.mu.Unlock()
+	if weigh == nil {
+		weigh = MedianCappedContribution
+	}
+	if backend == nil {
+		backend = vrf.NewEd25519Backend()
+	}
+	return VerifyElection(result, backend, latestBlockHash(), nodes, weigh)
+}
+
+// latestBlockHash 返回区块池中最新区块的哈希，没有区块时使用创世标记
+func latestBlockHash() string {
+	blocks := bc.GetAllBlocks()
+	if len(blocks) == 0 {
+		return "genesis"
+	}
+	return blocks[len(blocks)-1].Hash
+}
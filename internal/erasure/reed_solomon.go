@@ -0,0 +1,159 @@
+package erasure
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidShardCount = errors.New("invalid shard count")
+	ErrTooFewShards      = errors.New("too few shards to reconstruct")
+	ErrShardSize         = errors.New("shards have different sizes")
+)
+
+// Coder 系统化Reed–Solomon编码器: k个数据分片原样保留，另生成m个校验分片，
+// 任意k个分片即可恢复全部数据；编码矩阵为单位阵加Cauchy矩阵，保证任意k行可逆
+type Coder struct {
+	dataShards   int
+	parityShards int
+	encode       matrix // (k+m) x k
+}
+
+// New 创建k个数据分片、m个校验分片的编码器
+func New(dataShards, parityShards int) (*Coder, error) {
+	if dataShards < 1 || parityShards < 0 || dataShards+parityShards > 256 {
+		return nil, ErrInvalidShardCount
+	}
+	total := dataShards + parityShards
+	m := newMatrix(total, dataShards)
+	for i := 0; i < dataShards; i++ {
+		m[i][i] = 1
+	}
+	// Cauchy矩阵 C[i][j] = 1 / (x_i + y_j)，x_i = k+i，y_j = j，两组元素互不相同
+	for i := 0; i < parityShards; i++ {
+		for j := 0; j < dataShards; j++ {
+			m[dataShards+i][j] = gfInv(byte(dataShards+i) ^ byte(j))
+		}
+	}
+	return &Coder{dataShards: dataShards, parityShards: parityShards, encode: m}, nil
+}
+
+// DataShards 返回数据分片数k
+func (c *Coder) DataShards() int {
+	return c.dataShards
+}
+
+// ParityShards 返回校验分片数m
+func (c *Coder) ParityShards() int {
+	return c.parityShards
+}
+
+// Split 把数据补零后切成k个等长数据分片，并计算m个校验分片
+func (c *Coder) Split(data []byte) [][]byte {
+	size := (len(data) + c.dataShards - 1) / c.dataShards
+	if size == 0 {
+		size = 1
+	}
+	padded := make([]byte, size*c.dataShards)
+	copy(padded, data)
+
+	shards := make([][]byte, c.dataShards+c.parityShards)
+	for i := 0; i < c.dataShards; i++ {
+		shards[i] = padded[i*size : (i+1)*size]
+	}
+	for i := c.dataShards; i < len(shards); i++ {
+		shards[i] = make([]byte, size)
+	}
+	c.encodeParity(shards)
+	return shards
+}
+
+func (c *Coder) encodeParity(shards [][]byte) {
+	for i := 0; i < c.parityShards; i++ {
+		row := c.encode[c.dataShards+i]
+		out := shards[c.dataShards+i]
+		for b := range out {
+			out[b] = 0
+		}
+		for j := 0; j < c.dataShards; j++ {
+			coef := row[j]
+			for b, v := range shards[j] {
+				out[b] ^= gfMul(coef, v)
+			}
+		}
+	}
+}
+
+// Reconstruct 用现存分片恢复缺失分片(nil表示缺失)，至少需要k个分片
+func (c *Coder) Reconstruct(shards [][]byte) error {
+	if len(shards) != c.dataShards+c.parityShards {
+		return ErrInvalidShardCount
+	}
+	size := -1
+	var present []int
+	for i, s := range shards {
+		if s == nil {
+			continue
+		}
+		if size >= 0 && len(s) != size {
+			return ErrShardSize
+		}
+		size = len(s)
+		present = append(present, i)
+	}
+	if len(present) < c.dataShards {
+		return fmt.Errorf("%w: have %d, need %d", ErrTooFewShards, len(present), c.dataShards)
+	}
+	if len(present) == len(shards) {
+		return nil
+	}
+
+	// 取任意k个现存分片对应的编码矩阵行，求逆后得到原始数据分片
+	rows := present[:c.dataShards]
+	sub := newMatrix(c.dataShards, c.dataShards)
+	for i, r := range rows {
+		copy(sub[i], c.encode[r])
+	}
+	inv, ok := sub.invert()
+	if !ok {
+		return errors.New("erasure: singular decode matrix")
+	}
+	for i := 0; i < c.dataShards; i++ {
+		if shards[i] != nil {
+			continue
+		}
+		out := make([]byte, size)
+		for j, r := range rows {
+			coef := inv[i][j]
+			for b, v := range shards[r] {
+				out[b] ^= gfMul(coef, v)
+			}
+		}
+		shards[i] = out
+	}
+	for i := c.dataShards; i < len(shards); i++ {
+		if shards[i] == nil {
+			shards[i] = make([]byte, size)
+		}
+	}
+	c.encodeParity(shards)
+	return nil
+}
+
+// Join 拼接数据分片并截断为原始长度
+func (c *Coder) Join(shards [][]byte, size int) ([]byte, error) {
+	if len(shards) < c.dataShards {
+		return nil, ErrTooFewShards
+	}
+	out := make([]byte, 0, size)
+	for i := 0; i < c.dataShards; i++ {
+		if shards[i] == nil {
+			return nil, ErrTooFewShards
+		}
+		out = append(out, shards[i]...)
+	}
+	if len(out) < size {
+		return nil, ErrShardSize
+	}
+	return out[:size], nil
+}
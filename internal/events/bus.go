@@ -0,0 +1,170 @@
+// Package events 进程内事件总线: 链、交易池和节点状态的变化发布到总线，
+// 由WebSocket/SSE订阅接口推送给客户端
+package events
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Topic 事件主题
+type Topic string
+
+const (
+	TopicNewHeads     Topic = "newHeads"     // 新区块头
+	TopicPendingTx    Topic = "pendingTx"    // 进入交易池的交易
+	TopicTransactions Topic = "transactions" // 随区块上链的交易
+	TopicNodes        Topic = "nodes"        // 节点得分或健康状态变化
+	TopicAnchor       Topic = "anchor"       // 锚节点变化
+	TopicAssignments  Topic = "assignments"  // 锚节点的区块分配
+)
+
+// Topics 全部主题
+var Topics = []Topic{TopicNewHeads, TopicPendingTx, TopicTransactions, TopicNodes, TopicAnchor, TopicAssignments}
+
+// ErrLagged 订阅者消费过慢、缓冲区满时订阅被终止，客户端应按最后收到的高度重新订阅
+var ErrLagged = errors.New("subscriber lagged behind and was dropped")
+
+// Event 总线上的事件
+type Event struct {
+	Seq    uint64      `json:"seq"`
+	Topic  Topic       `json:"topic"`
+	Height int         `json:"height,omitempty"` // 与链相关的事件所在的区块高度
+	Time   int64       `json:"time"`
+	Data   interface{} `json:"data"`
+
+	Accounts []string `json:"-"` // 事件涉及的账户，用于按地址过滤
+	Node     string   `json:"-"` // 事件涉及的节点，用于按节点过滤
+}
+
+// Filter 订阅过滤条件，空字段表示不限
+type Filter struct {
+	Topics  []Topic
+	Address string // 只接收涉及该账户的交易事件
+	NodeID  string // 只接收涉及该节点的节点和分配事件
+}
+
+// Match 判断事件是否满足过滤条件
+func (f Filter) Match(ev Event) bool {
+	if len(f.Topics) > 0 {
+		found := false
+		for _, t := range f.Topics {
+			if t == ev.Topic {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Address != "" && (ev.Topic == TopicPendingTx || ev.Topic == TopicTransactions) {
+		found := false
+		for _, a := range ev.Accounts {
+			if a == f.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.NodeID != "" && (ev.Topic == TopicNodes || ev.Topic == TopicAssignments) && ev.Node != f.NodeID {
+		return false
+	}
+	return true
+}
+
+// Bus 事件总线，发布不会因订阅者消费慢而阻塞
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[uint64]*Subscription
+	nextID uint64
+	seq    uint64
+}
+
+// NewBus 创建事件总线
+func NewBus() *Bus {
+	return &Bus{subs: make(map[uint64]*Subscription)}
+}
+
+// Publish 发布事件，缓冲区已满的订阅者被终止并收到ErrLagged
+func (b *Bus) Publish(ev Event) {
+	b.mu.Lock()
+	b.seq++
+	ev.Seq = b.seq
+	if ev.Time == 0 {
+		ev.Time = time.Now().Unix()
+	}
+	var lagged []*Subscription
+	for _, s := range b.subs {
+		if !s.filter.Match(ev) {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			lagged = append(lagged, s)
+		}
+	}
+	for _, s := range lagged {
+		b.removeLocked(s, ErrLagged)
+	}
+	b.mu.Unlock()
+}
+
+// Subscribe 按过滤条件订阅，buffer为未消费事件的上限
+func (b *Bus) Subscribe(f Filter, buffer int) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	s := &Subscription{id: b.nextID, bus: b, filter: f, ch: make(chan Event, buffer)}
+	b.subs[s.id] = s
+	return s
+}
+
+// Subscribers 返回当前订阅数
+func (b *Bus) Subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs)
+}
+
+func (b *Bus) removeLocked(s *Subscription, err error) {
+	if _, ok := b.subs[s.id]; !ok {
+		return
+	}
+	delete(b.subs, s.id)
+	s.err = err
+	close(s.ch)
+}
+
+// Subscription 一个订阅，事件通道关闭后通过Err查看原因
+type Subscription struct {
+	id     uint64
+	bus    *Bus
+	filter Filter
+	ch     chan Event
+	err    error
+}
+
+// Events 返回事件通道，订阅结束时关闭
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Err 订阅被终止的原因，主动关闭时为nil
+func (s *Subscription) Err() error {
+	s.bus.mu.RLock()
+	defer s.bus.mu.RUnlock()
+	return s.err
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.removeLocked(s, nil)
+}
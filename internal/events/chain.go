@@ -0,0 +1,75 @@
+package events
+
+import (
+	bc "blockchain/internal/blockchain"
+	"blockchain/internal/network"
+)
+
+// AttachChain 在链已有的新区块/新交易回调之后发布事件，需在广播器Attach之后、挖矿开始之前调用
+func (b *Bus) AttachChain(chain *bc.Blockchain) {
+	onBlock := chain.OnNewBlock
+	chain.OnNewBlock = func(block bc.Block) {
+		if onBlock != nil {
+			onBlock(block)
+		}
+		b.PublishBlock(block)
+	}
+	onTx := chain.OnNewTransaction
+	chain.OnNewTransaction = func(tx bc.Transaction) {
+		if onTx != nil {
+			onTx(tx)
+		}
+		b.PublishPendingTx(tx)
+	}
+}
+
+// PublishBlock 发布新区块头以及区块中的每笔交易
+func (b *Bus) PublishBlock(block bc.Block) {
+	b.Publish(Event{Topic: TopicNewHeads, Height: block.Index, Data: block.Header()})
+	for _, ev := range BlockTxEvents(block) {
+		b.Publish(ev)
+	}
+}
+
+// PublishPendingTx 发布进入交易池的交易
+func (b *Bus) PublishPendingTx(tx bc.Transaction) {
+	b.Publish(Event{Topic: TopicPendingTx, Data: tx, Accounts: []string{tx.Sender, tx.Recipient}})
+}
+
+// BlockTxEvents 把区块中的交易转为事件，断线重连补发历史时也使用
+func BlockTxEvents(block bc.Block) []Event {
+	evs := make([]Event, 0, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		evs = append(evs, Event{
+			Topic:    TopicTransactions,
+			Height:   block.Index,
+			Time:     block.Timestamp,
+			Data:     bc.LocatedTx{Transaction: tx, Height: block.Index, BlockHash: block.Hash},
+			Accounts: []string{tx.Sender, tx.Recipient},
+		})
+	}
+	return evs
+}
+
+// Assignment 锚节点把区块分配给节点
+type Assignment struct {
+	BlockHash    string `json:"blockHash"`
+	Height       int    `json:"height"`
+	TargetNodeID string `json:"targetNodeId"`
+	AnchorID     string `json:"anchorId"`
+}
+
+// PublishAssignment 发布区块分配事件
+func (b *Bus) PublishAssignment(info network.BlockAssignInfo) {
+	b.Publish(Event{
+		Topic:  TopicAssignments,
+		Height: info.Block.Index,
+		Data: Assignment{
+			BlockHash:    info.Block.Hash,
+			Height:       info.Block.Index,
+			TargetNodeID: info.TargetNodeID,
+			AnchorID:     info.AnchorID,
+		},
+		Node: info.TargetNodeID,
+	})
+}
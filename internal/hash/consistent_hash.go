@@ -1,40 +1,337 @@
 package hash
 
 import (
-	"github.com/stathat/consistent"
+	"errors"
+	"hash/crc32"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
 	"sync"
+
+	"blockchain/pkg/config"
 )
 
 var (
-	ring = consistent.New()
-	mu   sync.Mutex
+	ErrEmptyRing   = errors.New("empty circle")
+	ErrUnknownHash = errors.New("unknown hash function")
 )
 
-func AddNode(nodeID string) {
-	mu.Lock()
-	defer mu.Unlock()
+// HashFunc 把键映射到环上的点
+type HashFunc func(key []byte) uint32
+
+var (
+	hashMu    sync.RWMutex
+	hashFuncs = map[string]HashFunc{
+		"crc32": crc32Mix,
+		"fnv1a": fnv1a,
+	}
+)
+
+// RegisterHashFunc 注册具名哈希函数，快照中按名称恢复环
+func RegisterHashFunc(name string, fn HashFunc) {
+	hashMu.Lock()
+	defer hashMu.Unlock()
+	hashFuncs[name] = fn
+}
+
+func lookupHashFunc(name string) (HashFunc, bool) {
+	hashMu.RLock()
+	defer hashMu.RUnlock()
+	fn, ok := hashFuncs[name]
+	return fn, ok
+}
+
+// crc32Mix 对crc32结果做murmur3终结混合，避免相近的虚拟节点名在环上聚集
+func crc32Mix(key []byte) uint32 {
+	h := crc32.ChecksumIEEE(key)
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+func fnv1a(key []byte) uint32 {
+	h := fnv.New32a()
+	h.Write(key)
+	return h.Sum32()
+}
+
+// HashRing 带权重的一致性哈希环，每个节点的虚拟节点数与其容量权重成正比
+type HashRing struct {
+	mu       sync.RWMutex
+	hashName string
+	hashFn   HashFunc
+	weights  map[string]int    // 节点 -> 虚拟节点数
+	owners   map[uint32]string // 环上的点 -> 节点
+	points   []uint32          // 排好序的环上的点
+	exclude  func(nodeID string) bool
+}
+
+// NewHashRing 使用已注册的哈希函数创建空环，hashName为空时使用 config.RingHashFunc
+func NewHashRing(hashName string) (*HashRing, error) {
+	if hashName == "" {
+		hashName = config.RingHashFunc
+	}
+	fn, ok := lookupHashFunc(hashName)
+	if !ok {
+		return nil, ErrUnknownHash
+	}
+	return &HashRing{
+		hashName: hashName,
+		hashFn:   fn,
+		weights:  make(map[string]int),
+		owners:   make(map[uint32]string),
+	}, nil
+}
+
+// HashName 返回环使用的哈希函数名
+func (r *HashRing) HashName() string {
+	return r.hashName
+}
 
-	// Add the node to the consistent hash ring
-	ring.Add(nodeID)
+// KeyHash 返回key在环上的位置
+func (r *HashRing) KeyHash(key string) uint32 {
+	return r.hashFn([]byte(key))
 }
 
-func RemoveNode(nodeID string) {
-	mu.Lock()
-	defer mu.Unlock()
+// Add 使用默认虚拟节点数把节点加入环
+func (r *HashRing) Add(nodeID string) {
+	r.AddWithWeight(nodeID, config.DefaultVirtualNodes)
+}
 
-	// Remove the node from the consistent hash ring
-	ring.Remove(nodeID)
+// AddWithWeight 以指定虚拟节点数把节点加入环，已存在时更新权重
+func (r *HashRing) AddWithWeight(nodeID string, weight int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if weight < 1 {
+		weight = 1
+	}
+	r.weights[nodeID] = weight
+	r.rebuild()
 }
 
-func GetNode(key string) (string, error) {
-	mu.Lock()
-	defer mu.Unlock()
+// SetWeight 更新已在环上节点的虚拟节点数，节点不存在时忽略
+func (r *HashRing) SetWeight(nodeID string, weight int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if weight < 1 {
+		weight = 1
+	}
+	if cur, ok := r.weights[nodeID]; ok && cur != weight {
+		r.weights[nodeID] = weight
+		r.rebuild()
+	}
+}
 
-	// Get the node responsible for the given key
-	nodeID, err := ring.Get(key)
-	if err != nil {
-		return "", err // Handle error appropriately
+// SetExclude 设置放置时排除的节点，例如未通过存储证明挑战的节点；
+// 它们仍在环上，只在其他节点都不可用时才被选为副本
+func (r *HashRing) SetExclude(exclude func(nodeID string) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exclude = exclude
+}
+
+// Weight 返回节点的虚拟节点数
+func (r *HashRing) Weight(nodeID string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.weights[nodeID]
+}
+
+// Remove 把节点从环上移除
+func (r *HashRing) Remove(nodeID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.weights, nodeID)
+	r.rebuild()
+}
+
+// Members 返回环上的所有节点，按ID排序
+func (r *HashRing) Members() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	members := make([]string, 0, len(r.weights))
+	for id := range r.weights {
+		members = append(members, id)
 	}
+	sort.Strings(members)
+	return members
+}
 
-	return nodeID, nil
+// Get 返回负责key的节点
+func (r *HashRing) Get(key string) (string, error) {
+	successors := r.successors(key)
+	if len(successors) == 0 {
+		return "", ErrEmptyRing
+	}
+	return successors[0], nil
+}
+
+// GetN 返回环上key之后的n个不同节点，第一个为主副本
+func (r *HashRing) GetN(key string, n int) ([]string, error) {
+	successors := r.successors(key)
+	if len(successors) == 0 {
+		return nil, ErrEmptyRing
+	}
+	if n < len(successors) {
+		successors = successors[:n]
+	}
+	return successors, nil
+}
+
+// GetReplicas 返回key的n个副本节点，沿环顺时针优先选择不同故障域(zone)的节点，
+// 不同故障域不足n个时再按环上顺序补足；zoneOf为nil或返回空串时视为无标签
+func (r *HashRing) GetReplicas(key string, n int, zoneOf func(nodeID string) string) ([]string, error) {
+	return r.GetReplicasBounded(key, n, zoneOf, nil)
+}
+
+// GetReplicasBounded 在GetReplicas基础上应用有界负载: load返回节点当前承载的键数，
+// 负载已达到按权重分摊的上限 (1+ε)·平均值 的节点被跳过，可用节点不足时才使用超载节点；
+// SetExclude排除的节点最后才使用
+func (r *HashRing) GetReplicasBounded(key string, n int, zoneOf func(nodeID string) string, load func(nodeID string) int) ([]string, error) {
+	successors := r.successors(key)
+	if len(successors) == 0 {
+		return nil, ErrEmptyRing
+	}
+	if n > len(successors) {
+		n = len(successors)
+	}
+
+	r.mu.RLock()
+	exclude := r.exclude
+	r.mu.RUnlock()
+	excluded := make(map[string]bool)
+	if exclude != nil {
+		for _, id := range successors {
+			excluded[id] = exclude(id)
+		}
+	}
+
+	overloaded := make(map[string]bool)
+	if load != nil {
+		r.mu.RLock()
+		weights := make(map[string]int, len(r.weights))
+		for id, w := range r.weights {
+			weights[id] = w
+		}
+		r.mu.RUnlock()
+
+		totalLoad, totalWeight := 0, 0
+		for id, w := range weights {
+			totalLoad += load(id)
+			totalWeight += w
+		}
+		// 加上即将放置的n个副本后计算每个节点的容量上限
+		for id, w := range weights {
+			capacity := math.Ceil((1 + config.LoadBoundEpsilon) * float64(totalLoad+n) * float64(w) / float64(totalWeight))
+			if float64(load(id)) >= capacity {
+				overloaded[id] = true
+			}
+		}
+	}
+
+	replicas := make([]string, 0, n)
+	chosen := make(map[string]bool, n)
+	usedZones := make(map[string]bool, n)
+	pick := func(requireZone, allowOverloaded, allowExcluded bool) {
+		for _, id := range successors {
+			if len(replicas) == n {
+				return
+			}
+			if chosen[id] || (overloaded[id] && !allowOverloaded) || (excluded[id] && !allowExcluded) {
+				continue
+			}
+			zone := ""
+			if zoneOf != nil {
+				zone = zoneOf(id)
+			}
+			if requireZone && zone != "" && usedZones[zone] {
+				continue
+			}
+			if zone != "" {
+				usedZones[zone] = true
+			}
+			replicas = append(replicas, id)
+			chosen[id] = true
+		}
+	}
+	pick(true, false, false)
+	pick(false, false, false)
+	pick(false, true, false)
+	pick(false, true, true)
+	return replicas, nil
+}
+
+// Clone 返回环的独立副本
+func (r *HashRing) Clone() *HashRing {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c := &HashRing{
+		hashName: r.hashName,
+		hashFn:   r.hashFn,
+		weights:  make(map[string]int, len(r.weights)),
+		owners:   make(map[uint32]string, len(r.owners)),
+		points:   append([]uint32(nil), r.points...),
+		exclude:  r.exclude,
+	}
+	for id, w := range r.weights {
+		c.weights[id] = w
+	}
+	for p, id := range r.owners {
+		c.owners[p] = id
+	}
+	return c
+}
+
+// rebuild 按权重重新生成虚拟节点，调用方需持有r.mu
+func (r *HashRing) rebuild() {
+	r.owners = make(map[uint32]string)
+	r.points = r.points[:0]
+	for id, weight := range r.weights {
+		for i := 0; i < weight; i++ {
+			p := r.hashFn([]byte(id + "#" + strconv.Itoa(i)))
+			cur, ok := r.owners[p]
+			// 哈希碰撞时按节点ID决定归属，保证结果与遍历顺序无关
+			if ok && cur < id {
+				continue
+			}
+			if !ok {
+				r.points = append(r.points, p)
+			}
+			r.owners[p] = id
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+}
+
+// successors 返回从key开始顺时针遇到的所有不同节点
+func (r *HashRing) successors(key string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.points) == 0 {
+		return nil
+	}
+	return r.successorsAt(r.hashFn([]byte(key)), len(r.weights))
+}
+
+// successorsAt 返回从环上位置h开始顺时针遇到的前n个不同节点，调用方需持有r.mu
+func (r *HashRing) successorsAt(h uint32, n int) []string {
+	if len(r.points) == 0 {
+		return nil
+	}
+	start := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+
+	seen := make(map[string]bool, len(r.weights))
+	result := make([]string, 0, n)
+	for i := 0; i < len(r.points) && len(result) < n; i++ {
+		id := r.owners[r.points[(start+i)%len(r.points)]]
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
 }
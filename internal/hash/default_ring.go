@@ -0,0 +1,79 @@
+package hash
+
+import "blockchain/pkg/config"
+
+// ring 进程内共享的默认环，包级函数都作用于它
+var ring = mustNewHashRing(config.RingHashFunc)
+
+func mustNewHashRing(hashName string) *HashRing {
+	r, err := NewHashRing(hashName)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Default 返回默认环
+func Default() *HashRing {
+	return ring
+}
+
+// AddNode 使用默认虚拟节点数把节点加入环
+func AddNode(nodeID string) {
+	ring.Add(nodeID)
+}
+
+// AddNodeWithWeight 以指定虚拟节点数把节点加入环，已存在时更新权重
+func AddNodeWithWeight(nodeID string, weight int) {
+	ring.AddWithWeight(nodeID, weight)
+}
+
+// SetWeight 更新已在环上节点的虚拟节点数，节点不存在时忽略
+func SetWeight(nodeID string, weight int) {
+	ring.SetWeight(nodeID, weight)
+}
+
+// SetExclude 设置默认环放置时排除的节点
+func SetExclude(exclude func(nodeID string) bool) {
+	ring.SetExclude(exclude)
+}
+
+// Weight 返回节点的虚拟节点数
+func Weight(nodeID string) int {
+	return ring.Weight(nodeID)
+}
+
+func RemoveNode(nodeID string) {
+	// Remove the node from the consistent hash ring
+	ring.Remove(nodeID)
+}
+
+func GetNode(key string) (string, error) {
+	// Get the node responsible for the given key
+	return ring.Get(key)
+}
+
+// GetN 返回环上key之后的n个不同节点，第一个为主副本
+func GetN(key string, n int) ([]string, error) {
+	return ring.GetN(key, n)
+}
+
+// GetReplicas 返回key的n个副本节点，副本尽量分布在不同故障域
+func GetReplicas(key string, n int, zoneOf func(nodeID string) string) ([]string, error) {
+	return ring.GetReplicas(key, n, zoneOf)
+}
+
+// GetReplicasBounded 返回key的n个副本节点，跳过负载超过上限的节点
+func GetReplicasBounded(key string, n int, zoneOf func(nodeID string) string, load func(nodeID string) int) ([]string, error) {
+	return ring.GetReplicasBounded(key, n, zoneOf, load)
+}
+
+// Members 返回默认环上的所有节点，按ID排序
+func Members() []string {
+	return ring.Members()
+}
+
+// TakeSnapshot 返回默认环当前布局的快照
+func TakeSnapshot() Snapshot {
+	return ring.Snapshot()
+}
@@ -0,0 +1,141 @@
+package hash
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrHashMismatch 比较的两个环使用了不同的哈希函数，键在两个环上的位置不可比
+var ErrHashMismatch = errors.New("hash function mismatch")
+
+// Snapshot 环布局的可序列化表示，相同的哈希函数和权重总能还原出相同的环
+type Snapshot struct {
+	Hash    string         `json:"hash"`
+	Weights map[string]int `json:"weights"`
+}
+
+// Snapshot 返回环当前布局的快照
+func (r *HashRing) Snapshot() Snapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s := Snapshot{Hash: r.hashName, Weights: make(map[string]int, len(r.weights))}
+	for id, w := range r.weights {
+		s.Weights[id] = w
+	}
+	return s
+}
+
+// Marshal 把快照序列化为JSON
+func (s Snapshot) Marshal() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// UnmarshalSnapshot 从JSON解析快照
+func UnmarshalSnapshot(data []byte) (Snapshot, error) {
+	var s Snapshot
+	err := json.Unmarshal(data, &s)
+	return s, err
+}
+
+// Ring 按快照还原环
+func (s Snapshot) Ring() (*HashRing, error) {
+	r, err := NewHashRing(s.Hash)
+	if err != nil {
+		return nil, err
+	}
+	for id, w := range s.Weights {
+		if w < 1 {
+			w = 1
+		}
+		r.weights[id] = w
+	}
+	r.rebuild()
+	return r, nil
+}
+
+// RangeMove 一段键范围的副本集合变化，范围为(Start, End]，Start > End 表示跨过环的零点；
+// From和To按环上顺序列出新旧副本节点，第一个为主副本
+type RangeMove struct {
+	Start uint32   `json:"start"`
+	End   uint32   `json:"end"`
+	From  []string `json:"from"`
+	To    []string `json:"to"`
+}
+
+// Contains 判断环上位置h是否落在该范围内
+func (m RangeMove) Contains(h uint32) bool {
+	if m.Start < m.End {
+		return h > m.Start && h <= m.End
+	}
+	return h > m.Start || h <= m.End
+}
+
+// Diff 比较两个环的布局，返回n个副本的集合(含顺序)发生变化的键范围，不考虑故障域和有界负载；
+// 两个环的哈希函数不同时返回ErrHashMismatch。From为空表示旧环为空，To为空表示新环为空
+func Diff(old, new *HashRing, n int) ([]RangeMove, error) {
+	if old.hashName != new.hashName {
+		return nil, fmt.Errorf("%w: %s vs %s", ErrHashMismatch, old.hashName, new.hashName)
+	}
+	old.mu.RLock()
+	defer old.mu.RUnlock()
+	if new != old {
+		new.mu.RLock()
+		defer new.mu.RUnlock()
+	}
+
+	// 两个环上所有的点把环切分成若干段，每段内新旧副本集合都不变
+	set := make(map[uint32]bool, len(old.points)+len(new.points))
+	for _, p := range old.points {
+		set[p] = true
+	}
+	for _, p := range new.points {
+		set[p] = true
+	}
+	if len(set) == 0 {
+		return nil, nil
+	}
+	bounds := make([]uint32, 0, len(set))
+	for p := range set {
+		bounds = append(bounds, p)
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	var moves []RangeMove
+	for i, end := range bounds {
+		start := bounds[(i+len(bounds)-1)%len(bounds)]
+		from, to := old.successorsAt(end, n), new.successorsAt(end, n)
+		if sameReplicas(from, to) {
+			continue
+		}
+		// 与上一段首尾相接且迁移方向相同则合并
+		if k := len(moves); k > 0 && moves[k-1].End == start && sameMove(moves[k-1], from, to) {
+			moves[k-1].End = end
+			continue
+		}
+		moves = append(moves, RangeMove{Start: start, End: end, From: from, To: to})
+	}
+	// 首段跨过零点时可能与末段相接
+	if k := len(moves); k > 1 && moves[k-1].End == moves[0].Start && sameMove(moves[k-1], moves[0].From, moves[0].To) {
+		moves[0].Start = moves[k-1].Start
+		moves = moves[:k-1]
+	}
+	return moves, nil
+}
+
+func sameMove(m RangeMove, from, to []string) bool {
+	return sameReplicas(m.From, from) && sameReplicas(m.To, to)
+}
+
+func sameReplicas(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+)
+
+var ErrInvalidProof = errors.New("invalid merkle proof")
+
+// 叶子与内部节点使用不同前缀，防止第二原像攻击
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// Tree 二叉Merkle树，层数不足时最后一个节点与自身配对
+type Tree struct {
+	levels [][][]byte // levels[0]为叶子哈希，最后一层为根
+}
+
+// ProofStep 证明中的一步: 兄弟节点哈希及其是否在左侧
+type ProofStep struct {
+	Hash []byte `json:"hash"`
+	Left bool   `json:"left"`
+}
+
+// HashLeaf 计算叶子哈希
+func HashLeaf(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{leafPrefix})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func hashNode(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{nodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// New 按给定顺序用叶子数据构建Merkle树
+func New(leaves [][]byte) *Tree {
+	level := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		level[i] = HashLeaf(leaf)
+	}
+	t := &Tree{levels: [][][]byte{level}}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			right := level[i]
+			if i+1 < len(level) {
+				right = level[i+1]
+			}
+			next = append(next, hashNode(level[i], right))
+		}
+		t.levels = append(t.levels, next)
+		level = next
+	}
+	return t
+}
+
+// Root 返回根哈希，空树的根为空
+func (t *Tree) Root() []byte {
+	top := t.levels[len(t.levels)-1]
+	if len(top) == 0 {
+		return nil
+	}
+	return top[0]
+}
+
+// Len 返回叶子数
+func (t *Tree) Len() int {
+	return len(t.levels[0])
+}
+
+// Proof 返回第i个叶子到根的证明路径
+func (t *Tree) Proof(i int) ([]ProofStep, error) {
+	if i < 0 || i >= t.Len() {
+		return nil, errors.New("leaf index out of range")
+	}
+	var proof []ProofStep
+	for _, level := range t.levels[:len(t.levels)-1] {
+		sibling := i ^ 1
+		if sibling >= len(level) {
+			sibling = i
+		}
+		proof = append(proof, ProofStep{Hash: level[sibling], Left: sibling < i})
+		i /= 2
+	}
+	return proof, nil
+}
+
+// Verify 校验叶子数据通过proof可得到root
+func Verify(root, leaf []byte, proof []ProofStep) error {
+	h := HashLeaf(leaf)
+	for _, step := range proof {
+		if step.Left {
+			h = hashNode(step.Hash, h)
+		} else {
+			h = hashNode(h, step.Hash)
+		}
+	}
+	if !bytes.Equal(h, root) {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+// Root 计算叶子数据的Merkle根
+func Root(leaves [][]byte) []byte {
+	return New(leaves).Root()
+}
@@ -0,0 +1,242 @@
+package network
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"blockchain/pkg/config"
+)
+
+// 地址来源
+const (
+	AddrSourceSeed   = "seed"
+	AddrSourcePeer   = "peer"
+	AddrSourceManual = "manual"
+)
+
+// PeerAddr 节点地址，用于地址交换
+type PeerAddr struct {
+	ID       string    `json:"id,omitempty"`
+	Addr     string    `json:"addr"`
+	LastSeen time.Time `json:"last_seen,omitempty"`
+}
+
+// AddrEntry 地址簿中的一条记录
+type AddrEntry struct {
+	PeerAddr
+	Source      string    `json:"source"`
+	LastAttempt time.Time `json:"last_attempt,omitempty"`
+	Failures    int       `json:"failures"`
+}
+
+// Score 地址评分，最近见过的加分，连续失败的扣分，种子节点略微优先
+func (e *AddrEntry) Score(now time.Time) float64 {
+	score := 0.0
+	if !e.LastSeen.IsZero() {
+		score += 10 / (1 + now.Sub(e.LastSeen).Hours())
+	}
+	score -= 2 * float64(e.Failures)
+	if e.Source == AddrSourceSeed {
+		score += 1
+	}
+	return score
+}
+
+// backoff 按失败次数指数退避的重试间隔
+func (e *AddrEntry) backoff() time.Duration {
+	if e.Failures == 0 {
+		return 0
+	}
+	d := time.Duration(float64(config.BaseDialBackoff) * math.Pow(2, float64(e.Failures-1)))
+	if d > config.MaxDialBackoff || d <= 0 {
+		d = config.MaxDialBackoff
+	}
+	return d
+}
+
+// AddrBook 持久化的节点地址簿
+type AddrBook struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]*AddrEntry
+}
+
+// NewAddrBook 创建地址簿，path为空时只保存在内存中
+func NewAddrBook(path string) *AddrBook {
+	return &AddrBook{
+		path:    path,
+		entries: make(map[string]*AddrEntry),
+	}
+}
+
+// Load 从文件加载地址簿，文件不存在时视为空地址簿
+func (b *AddrBook) Load() error {
+	if b.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(b.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var entries []*AddrEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, e := range entries {
+		b.entries[e.Addr] = e
+	}
+	return nil
+}
+
+// Save 将地址簿写入文件，先写临时文件再重命名避免写坏
+func (b *AddrBook) Save() error {
+	if b.path == "" {
+		return nil
+	}
+
+	b.mu.Lock()
+	entries := make([]*AddrEntry, 0, len(b.entries))
+	for _, e := range b.entries {
+		cp := *e
+		entries = append(entries, &cp)
+	}
+	b.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Addr < entries[j].Addr })
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0o755); err != nil {
+		return err
+	}
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, b.path)
+}
+
+// Add 加入新地址，已存在时只补充节点ID和最近见到时间
+func (b *AddrBook) Add(addr PeerAddr, source string) {
+	if addr.Addr == "" {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e, ok := b.entries[addr.Addr]; ok {
+		if addr.ID != "" {
+			e.ID = addr.ID
+		}
+		if addr.LastSeen.After(e.LastSeen) {
+			e.LastSeen = addr.LastSeen
+		}
+		if source == AddrSourceSeed {
+			e.Source = source
+		}
+		return
+	}
+	b.entries[addr.Addr] = &AddrEntry{PeerAddr: addr, Source: source}
+}
+
+// MarkAttempt 记录一次拨号尝试
+func (b *AddrBook) MarkAttempt(addr string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if e, ok := b.entries[addr]; ok {
+		e.LastAttempt = time.Now()
+	}
+}
+
+// MarkGood 连接成功，清零失败计数
+func (b *AddrBook) MarkGood(addr string, id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e, ok := b.entries[addr]
+	if !ok {
+		e = &AddrEntry{PeerAddr: PeerAddr{Addr: addr}, Source: AddrSourcePeer}
+		b.entries[addr] = e
+	}
+	e.ID = id
+	e.LastSeen = time.Now()
+	e.Failures = 0
+}
+
+// MarkFailed 拨号失败，失败过多的非种子地址会被移除
+func (b *AddrBook) MarkFailed(addr string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e, ok := b.entries[addr]
+	if !ok {
+		return
+	}
+	e.Failures++
+	if e.Failures > config.MaxAddrFailures && e.Source != AddrSourceSeed {
+		delete(b.entries, addr)
+	}
+}
+
+// Select 按评分选出至多n个可以拨号的地址，跳过exclude和仍在退避期内的地址
+func (b *AddrBook) Select(n int, exclude map[string]bool) []AddrEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	var candidates []AddrEntry
+	for addr, e := range b.entries {
+		if exclude[addr] || (e.ID != "" && exclude[e.ID]) {
+			continue
+		}
+		if now.Sub(e.LastAttempt) < e.backoff() {
+			continue
+		}
+		candidates = append(candidates, *e)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Score(now) > candidates[j].Score(now)
+	})
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	return candidates
+}
+
+// Known 返回最近见过的地址，用于回复getaddr
+func (b *AddrBook) Known(n int) []PeerAddr {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var addrs []PeerAddr
+	for _, e := range b.entries {
+		if e.LastSeen.IsZero() {
+			continue
+		}
+		addrs = append(addrs, e.PeerAddr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].LastSeen.After(addrs[j].LastSeen) })
+	if len(addrs) > n {
+		addrs = addrs[:n]
+	}
+	return addrs
+}
+
+// Len 返回地址簿条目数
+func (b *AddrBook) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.entries)
+}
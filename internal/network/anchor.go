@@ -0,0 +1,172 @@
+package network
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+var (
+	ErrUnknownAnchor = errors.New("no known anchor")
+	ErrNotAnchor     = errors.New("not signed by the current anchor")
+	ErrStaleEpoch    = errors.New("anchor epoch mismatch")
+	ErrBadElection   = errors.New("anchor election cannot be verified")
+)
+
+// Anchor 锚节点公告，由锚节点对自己的ID、公钥、选举纪元和选举结果签名；
+// 纪元每次选举加一，节点只接受比已知纪元更新的公告。
+// VRF选举时Election携带完整的选举结果，接收方用ElectionVerifier验证
+type Anchor struct {
+	ID        string          `json:"id"`
+	PublicKey []byte          `json:"public_key"`
+	Epoch     uint64          `json:"epoch"`
+	Election  json.RawMessage `json:"election,omitempty"`
+	Signature []byte          `json:"signature"`
+}
+
+func (a *Anchor) signingBytes() []byte {
+	var election []byte
+	if len(a.Election) > 0 {
+		sum := sha256.Sum256(a.Election)
+		election = sum[:]
+	}
+	data, _ := json.Marshal(struct {
+		ID       string
+		Epoch    uint64
+		Election []byte `json:",omitempty"`
+	}{a.ID, a.Epoch, election})
+	return data
+}
+
+// Verify 检查公告由其声明的锚节点签名，ID为空表示当前没有锚节点，不需要签名
+func (a *Anchor) Verify() error {
+	if a.ID == "" {
+		return nil
+	}
+	return VerifySignature(a.ID, a.PublicKey, a.signingBytes(), a.Signature)
+}
+
+// SignAnchor 以锚节点身份签名第epoch次选举的公告，election为选举结果，按得分选举时为空
+func (n *Node) SignAnchor(epoch uint64, election json.RawMessage) (Anchor, error) {
+	a := Anchor{ID: n.ID, PublicKey: n.PublicKey, Epoch: epoch, Election: election}
+	sig, err := n.Sign(a.signingBytes())
+	if err != nil {
+		return Anchor{}, err
+	}
+	a.Signature = sig
+	return a, nil
+}
+
+var (
+	anchorMu    sync.RWMutex
+	knownAnchor Anchor
+	verifier    func(a Anchor) error
+)
+
+// SetElectionVerifier 设置对端锚节点公告中选举结果的验证函数，由共识层按选举方式注册；
+// 未设置时只验证公告签名
+func SetElectionVerifier(v func(a Anchor) error) {
+	anchorMu.Lock()
+	defer anchorMu.Unlock()
+	verifier = v
+}
+
+// CurrentAnchor 返回本进程已知的锚节点，分配信息只接受它的签名
+func CurrentAnchor() Anchor {
+	anchorMu.RLock()
+	defer anchorMu.RUnlock()
+	return knownAnchor
+}
+
+// SetAnchor 记录本地选举出的锚节点
+func SetAnchor(a Anchor) {
+	anchorMu.Lock()
+	defer anchorMu.Unlock()
+	knownAnchor = a
+}
+
+// LearnAnchor 记录对端转发的锚节点公告，签名无效、选举结果验证失败或纪元不比已知的新时拒绝
+func LearnAnchor(a Anchor) error {
+	if a.ID == "" {
+		return fmt.Errorf("%w: empty announcement", ErrUnknownAnchor)
+	}
+	if err := a.Verify(); err != nil {
+		return err
+	}
+	anchorMu.RLock()
+	v := verifier
+	anchorMu.RUnlock()
+	if v != nil {
+		if err := v(a); err != nil {
+			return fmt.Errorf("%w: %v", ErrBadElection, err)
+		}
+	}
+	anchorMu.Lock()
+	defer anchorMu.Unlock()
+	if a.Epoch <= knownAnchor.Epoch {
+		return fmt.Errorf("%w: have %d, got %d", ErrStaleEpoch, knownAnchor.Epoch, a.Epoch)
+	}
+	knownAnchor = a
+	return nil
+}
+
+// AnnounceAnchor 向所有对端广播锚节点公告
+func (h *Host) AnnounceAnchor(a Anchor) {
+	msg, err := NewMessage(MsgAnchor, a)
+	if err != nil {
+		log.Printf("[P2P] 编码锚节点公告失败: %v", err)
+		return
+	}
+	h.Broadcast(msg, "")
+}
+
+// sendAnchor 新连接建立后告知对端本地已知的锚节点
+func (h *Host) sendAnchor(p *Peer) {
+	a := CurrentAnchor()
+	if a.ID == "" {
+		return
+	}
+	msg, err := NewMessage(MsgAnchor, a)
+	if err != nil {
+		return
+	}
+	if err := p.Send(msg); err != nil {
+		log.Printf("[P2P] 向 %s 发送锚节点公告失败: %v", p.ID, err)
+	}
+}
+
+// handleAnchor 接受更新纪元的锚节点公告并继续转发，旧公告直接忽略
+func (h *Host) handleAnchor(p *Peer, msg *Message) error {
+	var a Anchor
+	if err := msg.Decode(&a); err != nil {
+		return err
+	}
+	if err := LearnAnchor(a); err != nil {
+		if errors.Is(err, ErrStaleEpoch) {
+			return nil
+		}
+		return err
+	}
+	log.Printf("[P2P] 从 %s 获知锚节点 %s (纪元 %d)", p.ID, a.ID, a.Epoch)
+	h.Broadcast(msg, p.ID)
+	return nil
+}
+
+// verifyAssignAnchor 检查分配信息的签名者是本地已知的锚节点且纪元一致
+func verifyAssignAnchor(info *BlockAssignInfo) (Anchor, error) {
+	a := CurrentAnchor()
+	if a.ID == "" {
+		return a, ErrUnknownAnchor
+	}
+	if info.AnchorID != a.ID || !bytes.Equal(info.AnchorKey, a.PublicKey) {
+		return a, fmt.Errorf("%w: signer %s, anchor %s", ErrNotAnchor, info.AnchorID, a.ID)
+	}
+	if info.Epoch != a.Epoch {
+		return a, fmt.Errorf("%w: assignment %d, anchor %d", ErrStaleEpoch, info.Epoch, a.Epoch)
+	}
+	return a, nil
+}
@@ -0,0 +1,40 @@
+package network
+
+// BlockAssignSender 锚节点下发区块分配信息的接口
+type BlockAssignSender interface {
+	SendBlockAssign(info BlockAssignInfo) error
+}
+
+// ChanAssignSender 进程内实现，直接写入共享的分配通道
+type ChanAssignSender chan BlockAssignInfo
+
+func (c ChanAssignSender) SendBlockAssign(info BlockAssignInfo) error {
+	c <- info
+	return nil
+}
+
+// RoutedAssignSender 按目标节点选择传输: 目标是已连接的对端(或本进程网络层的节点)时
+// 经Host以MsgBlockAssign发送，其余目标即进程内的模拟节点交给Local；
+// Host在网络层启动前为nil，此时全部交给Local。Sent在经Host发送成功后回调，可为nil
+type RoutedAssignSender struct {
+	Host  func() *Host
+	Local BlockAssignSender
+	Sent  func(info BlockAssignInfo)
+}
+
+func (r RoutedAssignSender) SendBlockAssign(info BlockAssignInfo) error {
+	var h *Host
+	if r.Host != nil {
+		h = r.Host()
+	}
+	if h == nil || !h.Reaches(info.TargetNodeID) {
+		return r.Local.SendBlockAssign(info)
+	}
+	if err := h.SendBlockAssign(info); err != nil {
+		return err
+	}
+	if r.Sent != nil {
+		r.Sent(info)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package network
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRoutedAssignSender 已连接对端的分配经网络层以MsgBlockAssign送达，进程内节点的分配写入本地通道
+func TestRoutedAssignSender(t *testing.T) {
+	transport := NewChanTransport()
+	chain := newTestChain()
+	anchorHost := newTestHost(t, transport, "anchor", chain)
+	remoteHost := newTestHost(t, transport, "remote", newTestChain())
+	remote := remoteHost.Node()
+	remote.Disk = 1
+	remote.NodeBlockMap = make(map[string][]string)
+	if _, err := anchorHost.Connect(remote.Address); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := anchorHost.Node().SignAnchor(1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetAnchor(a)
+	t.Cleanup(func() { SetAnchor(Anchor{}) })
+
+	local := make(chan BlockAssignInfo, 1)
+	var sent []string
+	sender := RoutedAssignSender{
+		Host:  func() *Host { return anchorHost },
+		Local: ChanAssignSender(local),
+		Sent:  func(info BlockAssignInfo) { sent = append(sent, info.TargetNodeID) },
+	}
+	assign := func(target string) {
+		t.Helper()
+		info := BlockAssignInfo{Block: chain.LastBlock(), TargetNodeID: target}
+		if err := anchorHost.Node().SignBlockAssign(&info); err != nil {
+			t.Fatal(err)
+		}
+		if err := sender.SendBlockAssign(info); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assign(remote.ID)
+	hash := chain.LastBlock().Hash
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		remote.storeMu.Lock()
+		_, held := remote.held[hash]
+		remote.storeMu.Unlock()
+		if held {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("remote peer did not receive the assignment")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(local) != 0 || len(sent) != 1 || sent[0] != remote.ID {
+		t.Fatalf("remote assignment routed locally: local=%d sent=%v", len(local), sent)
+	}
+
+	assign("in-process-node")
+	select {
+	case info := <-local:
+		if info.TargetNodeID != "in-process-node" {
+			t.Fatalf("local assignment target = %s", info.TargetNodeID)
+		}
+	default:
+		t.Fatal("in-process assignment was not written to the local channel")
+	}
+	if len(sent) != 1 {
+		t.Fatalf("in-process assignment sent over the host: %v", sent)
+	}
+}
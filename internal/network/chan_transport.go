@@ -0,0 +1,162 @@
+package network
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// chanBuffer 进程内连接每个方向的缓冲消息数
+const chanBuffer = 100
+
+// ChanTransport 进程内传输层，使用Go通道在同一进程的节点间传递消息
+// 多个节点共享同一个ChanTransport实例，地址只是注册表中的名字
+type ChanTransport struct {
+	mu        sync.Mutex
+	listeners map[string]*chanListener
+	dialSeq   int
+}
+
+// NewChanTransport 创建进程内传输层
+func NewChanTransport() *ChanTransport {
+	return &ChanTransport{listeners: make(map[string]*chanListener)}
+}
+
+func (t *ChanTransport) Listen(addr string) (Listener, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.listeners[addr]; exists {
+		return nil, fmt.Errorf("address %s already in use", addr)
+	}
+	l := &chanListener{
+		transport: t,
+		addr:      addr,
+		accept:    make(chan Conn, chanBuffer),
+		done:      make(chan struct{}),
+	}
+	t.listeners[addr] = l
+	return l, nil
+}
+
+func (t *ChanTransport) Dial(addr string) (Conn, error) {
+	t.mu.Lock()
+	l, exists := t.listeners[addr]
+	t.dialSeq++
+	local := fmt.Sprintf("chan-dialer-%d", t.dialSeq)
+	t.mu.Unlock()
+
+	if !exists {
+		return nil, fmt.Errorf("no listener at %s", addr)
+	}
+
+	a, b := newChanPipe(local, addr)
+	select {
+	case l.accept <- b:
+		return a, nil
+	case <-l.done:
+		return nil, fmt.Errorf("listener %s closed", addr)
+	}
+}
+
+func (t *ChanTransport) remove(addr string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.listeners, addr)
+}
+
+type chanListener struct {
+	transport *ChanTransport
+	addr      string
+	accept    chan Conn
+	done      chan struct{}
+	once      sync.Once
+}
+
+func (l *chanListener) Accept() (Conn, error) {
+	select {
+	case c := <-l.accept:
+		return c, nil
+	case <-l.done:
+		return nil, io.EOF
+	}
+}
+
+func (l *chanListener) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+		l.transport.remove(l.addr)
+	})
+	return nil
+}
+
+func (l *chanListener) Addr() string {
+	return l.addr
+}
+
+// chanPipe 一对进程内连接共享的关闭信号
+type chanPipe struct {
+	done chan struct{}
+	once sync.Once
+}
+
+type chanConn struct {
+	pipe   *chanPipe
+	in     chan *Message
+	out    chan *Message
+	local  string
+	remote string
+}
+
+// newChanPipe 创建一对互相连接的进程内连接
+func newChanPipe(a, b string) (*chanConn, *chanConn) {
+	pipe := &chanPipe{done: make(chan struct{})}
+	ab := make(chan *Message, chanBuffer)
+	ba := make(chan *Message, chanBuffer)
+	return &chanConn{pipe: pipe, in: ba, out: ab, local: a, remote: b},
+		&chanConn{pipe: pipe, in: ab, out: ba, local: b, remote: a}
+}
+
+func (c *chanConn) Send(msg *Message) error {
+	// 复制负载，避免收发双方共享同一底层数组
+	cp := &Message{Type: msg.Type, Payload: append([]byte(nil), msg.Payload...)}
+	select {
+	case <-c.pipe.done:
+		return ErrConnClosed
+	default:
+	}
+	select {
+	case c.out <- cp:
+		return nil
+	case <-c.pipe.done:
+		return ErrConnClosed
+	}
+}
+
+func (c *chanConn) Receive() (*Message, error) {
+	// 优先读取已缓冲的消息，保证关闭前发送的消息(如disconnect)不会丢失
+	select {
+	case msg := <-c.in:
+		return msg, nil
+	default:
+	}
+	select {
+	case msg := <-c.in:
+		return msg, nil
+	case <-c.pipe.done:
+		return nil, io.EOF
+	}
+}
+
+func (c *chanConn) Close() error {
+	c.pipe.once.Do(func() { close(c.pipe.done) })
+	return nil
+}
+
+func (c *chanConn) LocalAddr() string {
+	return c.local
+}
+
+func (c *chanConn) RemoteAddr() string {
+	return c.remote
+}
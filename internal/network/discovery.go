@@ -0,0 +1,148 @@
+package network
+
+import (
+	"log"
+
+	"blockchain/pkg/config"
+)
+
+// Discovery 节点发现: 从种子节点和地址交换中收集地址，周期性拨号维持目标连接数
+type Discovery struct {
+	host   *Host
+	book   *AddrBook
+	seeds  []string
+	target int
+}
+
+// NewDiscovery 创建节点发现服务并注册getaddr/addr处理函数
+func NewDiscovery(host *Host, book *AddrBook, seeds []string) *Discovery {
+	d := &Discovery{
+		host:   host,
+		book:   book,
+		seeds:  seeds,
+		target: config.TargetPeers,
+	}
+	host.Handle(MsgGetAddr, d.handleGetAddr)
+	host.Handle(MsgAddr, d.handleAddr)
+	host.OnPeer(d.onConnected, nil)
+	return d
+}
+
+// Book 返回地址簿
+func (d *Discovery) Book() *AddrBook {
+	return d.book
+}
+
+// Start 加载种子地址并开始周期性维护连接
+func (d *Discovery) Start(stop <-chan struct{}) {
+	for _, seed := range d.seeds {
+		d.book.Add(PeerAddr{Addr: seed}, AddrSourceSeed)
+	}
+
+	go func() {
+		for {
+			d.maintain()
+			select {
+			case <-stop:
+				if err := d.book.Save(); err != nil {
+					log.Printf("[节点发现] 保存地址簿失败: %v", err)
+				}
+				return
+			case <-d.host.Clock().After(config.DiscoveryInterval):
+			}
+		}
+	}()
+}
+
+// AddAddress 手动加入地址并立即尝试连接
+func (d *Discovery) AddAddress(addr string) error {
+	d.book.Add(PeerAddr{Addr: addr}, AddrSourceManual)
+	return d.dial(addr)
+}
+
+// maintain 连接数不足目标时按评分拨号地址簿中的地址，并向已连接对端索要更多地址
+func (d *Discovery) maintain() {
+	peers := d.host.Peers()
+	need := d.target - len(peers)
+	if need <= 0 {
+		return
+	}
+
+	exclude := map[string]bool{d.host.Node().Address: true, d.host.Node().ID: true}
+	for _, p := range peers {
+		exclude[p.Addr] = true
+		exclude[p.ID] = true
+	}
+
+	for _, e := range d.book.Select(need, exclude) {
+		if err := d.dial(e.Addr); err != nil {
+			log.Printf("[节点发现] 连接 %s 失败: %v", e.Addr, err)
+		}
+	}
+
+	if len(d.host.Peers()) < d.target {
+		if msg, err := NewMessage(MsgGetAddr, nil); err == nil {
+			d.host.Broadcast(msg, "")
+		}
+	}
+
+	if err := d.book.Save(); err != nil {
+		log.Printf("[节点发现] 保存地址簿失败: %v", err)
+	}
+}
+
+func (d *Discovery) dial(addr string) error {
+	d.book.MarkAttempt(addr)
+	if _, err := d.host.Connect(addr); err != nil {
+		d.book.MarkFailed(addr)
+		return err
+	}
+	return nil
+}
+
+// onConnected 连接建立后记录地址并请求对端的地址列表
+func (d *Discovery) onConnected(p *Peer) {
+	if p.Addr != "" {
+		d.book.MarkGood(p.Addr, p.ID)
+	}
+	if msg, err := NewMessage(MsgGetAddr, nil); err == nil {
+		_ = p.Send(msg)
+	}
+}
+
+func (d *Discovery) handleGetAddr(p *Peer, msg *Message) error {
+	addrs := d.book.Known(config.MaxAddrPerMessage)
+	for _, peer := range d.host.Peers() {
+		if len(addrs) >= config.MaxAddrPerMessage {
+			break
+		}
+		if peer.ID != p.ID && peer.Addr != "" {
+			addrs = append(addrs, PeerAddr{ID: peer.ID, Addr: peer.Addr, LastSeen: peer.LastSeen()})
+		}
+	}
+
+	reply, err := NewMessage(MsgAddr, AddrPayload{Addrs: addrs})
+	if err != nil {
+		return err
+	}
+	return p.Send(reply)
+}
+
+func (d *Discovery) handleAddr(p *Peer, msg *Message) error {
+	var payload AddrPayload
+	if err := msg.Decode(&payload); err != nil {
+		return err
+	}
+	if len(payload.Addrs) > config.MaxAddrPerMessage {
+		payload.Addrs = payload.Addrs[:config.MaxAddrPerMessage]
+	}
+
+	self := d.host.Node()
+	for _, a := range payload.Addrs {
+		if a.Addr == self.Address || a.ID == self.ID {
+			continue
+		}
+		d.book.Add(a, AddrSourcePeer)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package network
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"blockchain/internal/compress"
+	"blockchain/pkg/config"
+)
+
+// frameHeaderSize 帧头为4字节大端长度，最高字节为消息体的压缩编解码器ID
+// (config.MaxFrameSize 小于16MB，不压缩时最高字节为0，与旧格式兼容)
+const frameHeaderSize = 4
+
+// WriteFrame 以长度前缀帧格式写出不压缩的消息
+func WriteFrame(w io.Writer, msg *Message) error {
+	return WriteFrameWith(w, msg, nil)
+}
+
+// WriteFrameWith 以长度前缀帧格式写出消息，消息体超过 config.CompressMinSize 时用codec压缩
+func WriteFrameWith(w io.Writer, msg *Message, codec compress.Codec) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	id := compress.IDNone
+	if codec != nil && codec.ID() != compress.IDNone && len(body) > config.CompressMinSize {
+		compressed, err := codec.Compress(body)
+		if err != nil {
+			return err
+		}
+		if len(compressed) < len(body) {
+			body, id = compressed, codec.ID()
+		}
+	}
+	if len(body) > config.MaxFrameSize {
+		return fmt.Errorf("frame too large: %d bytes", len(body))
+	}
+
+	buf := make([]byte, frameHeaderSize+len(body))
+	binary.BigEndian.PutUint32(buf, uint32(len(body)))
+	buf[0] = id
+	copy(buf[frameHeaderSize:], body)
+	_, err = w.Write(buf)
+	return err
+}
+
+// ReadFrame 读取一帧，按帧头记录的编解码器解压后解码为消息
+func ReadFrame(r io.Reader) (*Message, error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	id := header[0]
+	header[0] = 0
+	size := binary.BigEndian.Uint32(header[:])
+	if size > config.MaxFrameSize {
+		return nil, fmt.Errorf("frame too large: %d bytes", size)
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	if id != compress.IDNone {
+		codec, err := compress.ByID(id)
+		if err != nil {
+			return nil, fmt.Errorf("decode frame: %w", err)
+		}
+		if body, err = codec.Decompress(body, config.MaxFrameSize); err != nil {
+			return nil, fmt.Errorf("decompress frame: %w", err)
+		}
+	}
+
+	msg := &Message{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		return nil, fmt.Errorf("decode frame: %w", err)
+	}
+	return msg, nil
+}
@@ -0,0 +1,256 @@
+package network
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"blockchain/internal/blockchain"
+	"blockchain/pkg/config"
+)
+
+// GossipStore 广播层访问本地交易池和区块链的接口，由*block_chain.Blockchain实现
+type GossipStore interface {
+	HasTransaction(id string) bool
+	GetTransaction(id string) (block_chain.Transaction, bool)
+	AcceptTransaction(tx block_chain.Transaction) error
+	HasBlock(hash string) bool
+	GetBlockByHash(hash string) (block_chain.Block, bool)
+	AcceptBlock(block block_chain.Block) error
+}
+
+// Gossiper 在对端之间以流行病方式传播交易和区块
+// 新条目只宣告哈希(inv)，对端按需拉取(getdata)，校验通过后才继续转发
+type Gossiper struct {
+	host  *Host
+	store GossipStore
+	seen  *SeenCache
+
+	fanout     int
+	retryDelay time.Duration
+
+	mu        sync.Mutex
+	requested map[string]time.Time
+}
+
+// NewGossiper 创建广播器并在host上注册inv/getdata/tx/block处理函数
+func NewGossiper(host *Host, store GossipStore) *Gossiper {
+	g := &Gossiper{
+		host:       host,
+		store:      store,
+		seen:       NewSeenCache(config.SeenCacheSize, config.SeenCacheTTL, host.Clock()),
+		fanout:     config.GossipFanout,
+		retryDelay: config.GetDataRetryDelay,
+		requested:  make(map[string]time.Time),
+	}
+	host.Handle(MsgInv, g.handleInv)
+	host.Handle(MsgGetData, g.handleGetData)
+	host.Handle(MsgTx, g.handleTx)
+	host.Handle(MsgBlock, g.handleBlock)
+	return g
+}
+
+// Attach 将区块链的新交易/新区块回调接到广播器上
+func (g *Gossiper) Attach(chain *block_chain.Blockchain) {
+	chain.OnNewTransaction = g.AnnounceTx
+	chain.OnNewBlock = g.AnnounceBlock
+}
+
+// AnnounceTx 宣告本地产生的新交易
+func (g *Gossiper) AnnounceTx(tx block_chain.Transaction) {
+	if g.seen.Add(seenKey(InvTx, tx.ID)) {
+		g.relay(InvItem{Type: InvTx, Hash: tx.ID}, "")
+	}
+}
+
+// AnnounceBlock 宣告本地产生的新区块
+func (g *Gossiper) AnnounceBlock(block block_chain.Block) {
+	if g.seen.Add(seenKey(InvBlock, block.Hash)) {
+		g.relay(InvItem{Type: InvBlock, Hash: block.Hash}, "")
+	}
+}
+
+// relay 随机选择不超过fanout个对端宣告条目，不回发给来源节点
+func (g *Gossiper) relay(item InvItem, from string) {
+	msg, err := NewMessage(MsgInv, InvPayload{Items: []InvItem{item}})
+	if err != nil {
+		return
+	}
+
+	peers := g.host.Peers()
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+
+	sent := 0
+	for _, p := range peers {
+		if sent >= g.fanout {
+			break
+		}
+		if p.ID == from {
+			continue
+		}
+		if err := p.Send(msg); err != nil {
+			log.Printf("[Gossip] 向 %s 宣告 %s 失败: %v", p.ID, item.Hash, err)
+			continue
+		}
+		sent++
+	}
+}
+
+func (g *Gossiper) handleInv(p *Peer, msg *Message) error {
+	var inv InvPayload
+	if err := msg.Decode(&inv); err != nil {
+		return err
+	}
+
+	var want []InvItem
+	for _, item := range inv.Items {
+		if g.known(item) || !g.markRequested(item) {
+			continue
+		}
+		want = append(want, item)
+	}
+	if len(want) == 0 {
+		return nil
+	}
+
+	req, err := NewMessage(MsgGetData, InvPayload{Items: want})
+	if err != nil {
+		return err
+	}
+	return p.Send(req)
+}
+
+func (g *Gossiper) handleGetData(p *Peer, msg *Message) error {
+	var req InvPayload
+	if err := msg.Decode(&req); err != nil {
+		return err
+	}
+
+	for _, item := range req.Items {
+		var reply *Message
+		var err error
+		switch item.Type {
+		case InvTx:
+			if tx, ok := g.store.GetTransaction(item.Hash); ok {
+				reply, err = NewMessage(MsgTx, TxPayload{Tx: tx})
+			}
+		case InvBlock:
+			if block, ok := g.store.GetBlockByHash(item.Hash); ok {
+				reply, err = NewMessage(MsgBlock, BlockPayload{Block: block})
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if reply == nil {
+			continue
+		}
+		if err := p.Send(reply); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Gossiper) handleTx(p *Peer, msg *Message) error {
+	var payload TxPayload
+	if err := msg.Decode(&payload); err != nil {
+		return err
+	}
+	tx := payload.Tx
+	g.clearRequested(seenKey(InvTx, tx.ID))
+
+	key := seenKey(InvTx, tx.ID)
+	if g.seen.Has(key) {
+		return nil
+	}
+	// 校验通过后才记入已见缓存，否则伪造的同ID交易会让真正的交易被当作已见而丢弃
+	if err := g.store.AcceptTransaction(tx); err != nil {
+		if errors.Is(err, block_chain.ErrDuplicateTx) {
+			g.seen.Add(key)
+			return nil
+		}
+		return err // 校验失败的交易不转发
+	}
+
+	if g.seen.Add(key) {
+		g.relay(InvItem{Type: InvTx, Hash: tx.ID}, p.ID)
+	}
+	return nil
+}
+
+func (g *Gossiper) handleBlock(p *Peer, msg *Message) error {
+	var payload BlockPayload
+	if err := msg.Decode(&payload); err != nil {
+		return err
+	}
+	block := payload.Block
+	g.clearRequested(seenKey(InvBlock, block.Hash))
+
+	key := seenKey(InvBlock, block.Hash)
+	if g.seen.Has(key) {
+		return nil
+	}
+	// 同交易，校验失败的区块不记入已见缓存
+	if err := g.store.AcceptBlock(block); err != nil {
+		if errors.Is(err, block_chain.ErrDuplicateBlock) {
+			g.seen.Add(key)
+			return nil
+		}
+		return err // 校验失败的区块不转发
+	}
+
+	p.SetHeight(block.Index)
+	if g.seen.Add(key) {
+		g.relay(InvItem{Type: InvBlock, Hash: block.Hash}, p.ID)
+	}
+	return nil
+}
+
+// known 判断条目是否已见过或本地已持有
+func (g *Gossiper) known(item InvItem) bool {
+	if g.seen.Has(seenKey(item.Type, item.Hash)) {
+		return true
+	}
+	switch item.Type {
+	case InvTx:
+		return g.store.HasTransaction(item.Hash)
+	case InvBlock:
+		return g.store.HasBlock(item.Hash)
+	}
+	return true
+}
+
+// markRequested 记录已发出的getdata请求，避免向多个对端重复拉取同一条目
+func (g *Gossiper) markRequested(item InvItem) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.host.Clock().Now()
+	if len(g.requested) > config.SeenCacheSize {
+		for k, at := range g.requested {
+			if now.Sub(at) >= g.retryDelay {
+				delete(g.requested, k)
+			}
+		}
+	}
+
+	key := seenKey(item.Type, item.Hash)
+	if at, ok := g.requested[key]; ok && now.Sub(at) < g.retryDelay {
+		return false
+	}
+	g.requested[key] = now
+	return true
+}
+
+func (g *Gossiper) clearRequested(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.requested, key)
+}
+
+func seenKey(t InvType, hash string) string {
+	return string(t) + ":" + hash
+}
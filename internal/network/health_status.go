@@ -2,12 +2,12 @@ package network
 
 // HealthStatus 定义节点健康状态
 type HealthStatus struct {
-	Status      string  // 状态描述 (健康/警告/危险)
-	Score       float64 // 健康评分 (0-100)
-	CPUUsage    float64 // CPU使用率
-	MemoryUsage float64 // 内存使用率
-	DiskUsage   float64 // 磁盘使用率
-	NetActivity float64 // 网络活动度
+	Status      string  `json:"status"`      // 状态描述 (健康/警告/危险)
+	Score       float64 `json:"score"`       // 健康评分 (0-100)
+	CPUUsage    float64 `json:"cpuUsage"`    // CPU使用率
+	MemoryUsage float64 `json:"memoryUsage"` // 内存使用率
+	DiskUsage   float64 `json:"diskUsage"`   // 磁盘使用率
+	NetActivity float64 `json:"netActivity"` // 网络活动度
 }
 
 // CheckHealth 检查节点健康状态
@@ -24,9 +24,11 @@ func (n *Node) CheckHealth() HealthStatus {
 	healthScore := 0.3*(100-cpuUsagePct) + 0.2*(100-memUsagePct) +
 		0.3*(100-diskUsagePct) + 0.2*(bandwidth/1000) // 假设1Gbps为满分
 
-	// 确定状态级别
+	// 确定状态级别，被暂停的节点无论性能如何都视为危险
 	status := "健康"
-	if healthScore < 60 {
+	if n.Suspended() {
+		status, healthScore = "危险", 0
+	} else if healthScore < 60 {
 		status = "危险"
 	} else if healthScore < 80 {
 		status = "警告"
@@ -0,0 +1,594 @@
+package network
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"blockchain/internal/compress"
+	"blockchain/pkg/config"
+)
+
+var (
+	ErrHostClosed    = errors.New("host closed")
+	ErrPeerNotFound  = errors.New("peer not found")
+	ErrTooManyPeers  = errors.New("too many peers")
+	ErrDuplicatePeer = errors.New("duplicate peer")
+)
+
+// HandlerFunc 处理某一类型的入站消息
+type HandlerFunc func(p *Peer, msg *Message) error
+
+// HostConfig 节点网络层配置
+type HostConfig struct {
+	ChainID          string
+	Version          int
+	MaxPeers         int
+	PingInterval     time.Duration
+	PeerTimeout      time.Duration
+	HandshakeTimeout time.Duration
+	Height           func() int // 返回本地链高度，握手时告知对端
+	Clock            Clock      // 心跳和超时使用的时钟，为nil时使用系统时钟
+	Codecs           []string   // 支持的连接压缩方式，按偏好排序，为空时不压缩
+}
+
+// DefaultHostConfig 使用pkg/config中的默认值
+func DefaultHostConfig() HostConfig {
+	return HostConfig{
+		ChainID:          config.ChainID,
+		Version:          config.ProtocolVersion,
+		MaxPeers:         config.MaxPeers,
+		PingInterval:     config.PingInterval,
+		PeerTimeout:      config.PeerTimeout,
+		HandshakeTimeout: config.HandshakeTimeout,
+		Clock:            RealClock,
+		Codecs:           config.WireCodecs,
+	}
+}
+
+// Host 节点的网络层，负责监听、握手、心跳和对端连接管理
+type Host struct {
+	node      *Node
+	transport Transport
+	cfg       HostConfig
+
+	listener Listener
+	mu       sync.RWMutex
+	peers    map[string]*Peer
+	handlers map[MessageType]HandlerFunc
+	closed   bool
+
+	connectHooks    []func(p *Peer) // 握手完成后回调
+	disconnectHooks []func(p *Peer) // 连接断开后回调
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewHost 为节点创建网络层
+func NewHost(node *Node, transport Transport, cfg HostConfig) *Host {
+	h := &Host{
+		node:      node,
+		transport: transport,
+		cfg:       cfg,
+		peers:     make(map[string]*Peer),
+		handlers:  make(map[MessageType]HandlerFunc),
+		quit:      make(chan struct{}),
+	}
+	if h.cfg.Clock == nil {
+		h.cfg.Clock = RealClock
+	}
+	h.Handle(MsgBlockAssign, h.handleBlockAssign)
+	h.Handle(MsgAnchor, h.handleAnchor)
+	h.OnPeer(h.sendAnchor, nil)
+	return h
+}
+
+// Node 返回本地节点
+func (h *Host) Node() *Node {
+	return h.node
+}
+
+// Clock 返回网络层使用的时钟
+func (h *Host) Clock() Clock {
+	return h.cfg.Clock
+}
+
+// Handle 注册消息处理函数，同一类型重复注册会覆盖
+func (h *Host) Handle(t MessageType, fn HandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers[t] = fn
+}
+
+// OnPeer 注册对端连接建立和断开时的回调，任一参数可为nil
+func (h *Host) OnPeer(connected, disconnected func(p *Peer)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if connected != nil {
+		h.connectHooks = append(h.connectHooks, connected)
+	}
+	if disconnected != nil {
+		h.disconnectHooks = append(h.disconnectHooks, disconnected)
+	}
+}
+
+// Start 开始监听入站连接，监听地址写入Node.Address
+func (h *Host) Start(addr string) error {
+	l, err := h.transport.Listen(addr)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", addr, err)
+	}
+	h.listener = l
+	h.node.Address = l.Addr()
+
+	h.wg.Add(2)
+	go h.acceptLoop()
+	go h.pingLoop()
+
+	log.Printf("[P2P] 节点 %s 开始监听 %s", h.node.ID, h.node.Address)
+	return nil
+}
+
+// Connect 主动连接对端并完成握手
+func (h *Host) Connect(addr string) (*Peer, error) {
+	if h.isClosed() {
+		return nil, ErrHostClosed
+	}
+	conn, err := h.transport.Dial(addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", addr, err)
+	}
+	return h.setupConn(conn, false, addr)
+}
+
+// Peers 返回当前所有对端
+func (h *Host) Peers() []*Peer {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	peers := make([]*Peer, 0, len(h.peers))
+	for _, p := range h.peers {
+		peers = append(peers, p)
+	}
+	return peers
+}
+
+// Peer 按节点ID查找对端
+func (h *Host) Peer(id string) (*Peer, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	p, ok := h.peers[id]
+	return p, ok
+}
+
+// SendTo 向指定节点发送消息
+func (h *Host) SendTo(id string, msg *Message) error {
+	p, ok := h.Peer(id)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPeerNotFound, id)
+	}
+	return p.Send(msg)
+}
+
+// Broadcast 向除except外的所有对端发送消息
+func (h *Host) Broadcast(msg *Message, except string) {
+	for _, p := range h.Peers() {
+		if p.ID == except {
+			continue
+		}
+		if err := p.Send(msg); err != nil {
+			log.Printf("[P2P] 向节点 %s 广播失败: %v", p.ID, err)
+		}
+	}
+}
+
+// Disconnect 通知对端原因后断开连接
+func (h *Host) Disconnect(id string, reason string) {
+	p, ok := h.Peer(id)
+	if !ok {
+		return
+	}
+	if msg, err := NewMessage(MsgDisconnect, DisconnectPayload{Reason: reason}); err == nil {
+		_ = p.Send(msg)
+	}
+	h.removePeer(p)
+}
+
+// Close 优雅关闭: 停止监听，通知所有对端后断开并等待后台协程退出
+func (h *Host) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	close(h.quit)
+	h.mu.Unlock()
+
+	if h.listener != nil {
+		h.listener.Close()
+	}
+	for _, p := range h.Peers() {
+		h.Disconnect(p.ID, "shutdown")
+	}
+	h.wg.Wait()
+	log.Printf("[P2P] 节点 %s 网络层已关闭", h.node.ID)
+	return nil
+}
+
+// Reaches 判断节点是本节点或已连接的对端
+func (h *Host) Reaches(nodeID string) bool {
+	if nodeID == h.node.ID {
+		return true
+	}
+	_, ok := h.Peer(nodeID)
+	return ok
+}
+
+// SendBlockAssign 实现BlockAssignSender，目标为本节点时直接处理
+func (h *Host) SendBlockAssign(info BlockAssignInfo) error {
+	if info.TargetNodeID == h.node.ID {
+		h.node.HandleBlockAssign(info)
+		return nil
+	}
+	msg, err := NewMessage(MsgBlockAssign, info)
+	if err != nil {
+		return err
+	}
+	return h.SendTo(info.TargetNodeID, msg)
+}
+
+func (h *Host) handleBlockAssign(p *Peer, msg *Message) error {
+	var info BlockAssignInfo
+	if err := msg.Decode(&info); err != nil {
+		return err
+	}
+	if info.TargetNodeID != h.node.ID {
+		return nil
+	}
+	// 签名者必须是本地已知的锚节点，对端只是转发者
+	if err := info.Verify(); err != nil {
+		return fmt.Errorf("reject block assignment from %s: %w", p.ID, err)
+	}
+	h.node.HandleBlockAssign(info)
+	return nil
+}
+
+func (h *Host) isClosed() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.closed
+}
+
+func (h *Host) height() int {
+	if h.cfg.Height == nil {
+		return 0
+	}
+	return h.cfg.Height()
+}
+
+func (h *Host) acceptLoop() {
+	defer h.wg.Done()
+	for {
+		conn, err := h.listener.Accept()
+		if err != nil {
+			if !h.isClosed() {
+				log.Printf("[P2P] 接受连接失败: %v", err)
+			}
+			return
+		}
+		go func() {
+			if _, err := h.setupConn(conn, true, ""); err != nil {
+				log.Printf("[P2P] 入站连接 %s 握手失败: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// setupConn 与对端交换握手信息并互相证明持有身份私钥，校验通过后登记为Peer并启动读循环
+func (h *Host) setupConn(conn Conn, inbound bool, dialAddr string) (*Peer, error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	hs, err := NewMessage(MsgHandshake, HandshakePayload{
+		ChainID:    h.cfg.ChainID,
+		Version:    h.cfg.Version,
+		NodeID:     h.node.ID,
+		PublicKey:  h.node.PublicKey,
+		Nonce:      nonce,
+		Height:     h.height(),
+		ListenAddr: h.node.Address,
+		Stats:      h.node.Stats(),
+		Zone:       h.node.Zone,
+		Labels:     h.node.Labels,
+		Codecs:     h.cfg.Codecs,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.Send(hs); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	remote, err := h.receiveHandshake(conn)
+	if err != nil {
+		rejectConn(conn, err)
+		return nil, err
+	}
+
+	// 使用TLS时，证书绑定的节点ID必须与握手声明的身份一致
+	if cc, ok := conn.(CertifiedConn); ok {
+		certID, err := cc.PeerNodeID()
+		if err == nil && certID != remote.NodeID {
+			err = fmt.Errorf("certificate bound to %s, handshake claims %s", certID, remote.NodeID)
+		}
+		if err != nil {
+			rejectConn(conn, err)
+			return nil, err
+		}
+	}
+
+	// 对对端的随机数签名，证明持有节点ID对应的私钥
+	sig, err := h.node.Sign(handshakeChallenge(h.cfg.ChainID, h.node.ID, remote.Nonce))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	ack, err := NewMessage(MsgHandshakeAck, HandshakeAckPayload{Signature: sig})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.Send(ack); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := h.receiveHandshakeAck(conn, remote, nonce); err != nil {
+		rejectConn(conn, err)
+		return nil, err
+	}
+
+	// 双方都确认身份后再启用压缩；对端按帧头识别压缩方式，两端偏好不同也能互通
+	codec := compress.Negotiate(h.cfg.Codecs, remote.Codecs)
+	if cc, ok := conn.(CompressibleConn); ok {
+		cc.SetCodec(codec)
+	}
+
+	addr := remote.ListenAddr
+	if addr == "" {
+		addr = dialAddr
+	}
+	p := &Peer{
+		ID:          remote.NodeID,
+		PublicKey:   remote.PublicKey,
+		Addr:        addr,
+		Version:     remote.Version,
+		Height:      remote.Height,
+		Inbound:     inbound,
+		Stats:       remote.Stats,
+		Zone:        remote.Zone,
+		Labels:      remote.Labels,
+		Codec:       codec.Name(),
+		ConnectedAt: h.cfg.Clock.Now(),
+		conn:        conn,
+		clock:       h.cfg.Clock,
+	}
+	p.touch()
+
+	if err := h.addPeer(p); err != nil {
+		rejectConn(conn, err)
+		return nil, err
+	}
+
+	h.wg.Add(1)
+	go h.readLoop(p)
+
+	log.Printf("[P2P] 节点 %s 与 %s 建立连接 (入站=%v, 高度=%d)", h.node.ID, p.ID, inbound, p.Height)
+	h.mu.RLock()
+	hooks := h.connectHooks
+	h.mu.RUnlock()
+	for _, fn := range hooks {
+		fn(p)
+	}
+	return p, nil
+}
+
+// rejectConn 告知对端拒绝原因后关闭连接
+func rejectConn(conn Conn, reason error) {
+	if msg, err := NewMessage(MsgDisconnect, DisconnectPayload{Reason: reason.Error()}); err == nil {
+		_ = conn.Send(msg)
+	}
+	conn.Close()
+}
+
+// receiveTimeout 在握手超时时间内读取一条指定类型的消息
+func (h *Host) receiveTimeout(conn Conn, want MessageType) (*Message, error) {
+	type result struct {
+		msg *Message
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		msg, err := conn.Receive()
+		ch <- result{msg, err}
+	}()
+
+	var res result
+	select {
+	case res = <-ch:
+	case <-h.cfg.Clock.After(h.cfg.HandshakeTimeout):
+		return nil, errors.New("handshake timeout")
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+	if res.msg.Type == MsgDisconnect {
+		var d DisconnectPayload
+		_ = res.msg.Decode(&d)
+		return nil, fmt.Errorf("rejected by peer: %s", d.Reason)
+	}
+	if res.msg.Type != want {
+		return nil, fmt.Errorf("expected %s, got %s", want, res.msg.Type)
+	}
+	return res.msg, nil
+}
+
+func (h *Host) receiveHandshake(conn Conn) (*HandshakePayload, error) {
+	msg, err := h.receiveTimeout(conn, MsgHandshake)
+	if err != nil {
+		return nil, err
+	}
+
+	var remote HandshakePayload
+	if err := msg.Decode(&remote); err != nil {
+		return nil, err
+	}
+	if remote.ChainID != h.cfg.ChainID {
+		return nil, fmt.Errorf("chain id mismatch: %s", remote.ChainID)
+	}
+	if remote.Version != h.cfg.Version {
+		return nil, fmt.Errorf("protocol version mismatch: %d", remote.Version)
+	}
+	if remote.NodeID == "" || remote.NodeID == h.node.ID {
+		return nil, fmt.Errorf("invalid remote node id %q", remote.NodeID)
+	}
+	if err := VerifyNodeID(remote.NodeID, remote.PublicKey); err != nil {
+		return nil, err
+	}
+	if len(remote.Nonce) == 0 {
+		return nil, errors.New("handshake nonce is required")
+	}
+	return &remote, nil
+}
+
+// receiveHandshakeAck 验证对端对本端随机数的签名
+func (h *Host) receiveHandshakeAck(conn Conn, remote *HandshakePayload, nonce []byte) error {
+	msg, err := h.receiveTimeout(conn, MsgHandshakeAck)
+	if err != nil {
+		return err
+	}
+	var ack HandshakeAckPayload
+	if err := msg.Decode(&ack); err != nil {
+		return err
+	}
+	challenge := handshakeChallenge(h.cfg.ChainID, remote.NodeID, nonce)
+	return VerifySignature(remote.NodeID, remote.PublicKey, challenge, ack.Signature)
+}
+
+// handshakeChallenge 握手签名内容，绑定链标识和签名方ID防止跨链或跨节点重放
+func handshakeChallenge(chainID, signerID string, nonce []byte) []byte {
+	msg := make([]byte, 0, len(chainID)+len(signerID)+len(nonce)+16)
+	msg = append(msg, "handshake:"...)
+	msg = append(msg, chainID...)
+	msg = append(msg, ':')
+	msg = append(msg, signerID...)
+	msg = append(msg, ':')
+	return append(msg, nonce...)
+}
+
+func (h *Host) addPeer(p *Peer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return ErrHostClosed
+	}
+	if _, exists := h.peers[p.ID]; exists {
+		return ErrDuplicatePeer
+	}
+	if h.cfg.MaxPeers > 0 && len(h.peers) >= h.cfg.MaxPeers {
+		return ErrTooManyPeers
+	}
+	h.peers[p.ID] = p
+	return nil
+}
+
+func (h *Host) removePeer(p *Peer) {
+	h.mu.Lock()
+	removed := false
+	if cur, ok := h.peers[p.ID]; ok && cur == p {
+		delete(h.peers, p.ID)
+		removed = true
+	}
+	h.mu.Unlock()
+
+	p.conn.Close()
+	if removed {
+		log.Printf("[P2P] 节点 %s 与 %s 断开连接", h.node.ID, p.ID)
+		h.mu.RLock()
+		hooks := h.disconnectHooks
+		h.mu.RUnlock()
+		for _, fn := range hooks {
+			fn(p)
+		}
+	}
+}
+
+func (h *Host) readLoop(p *Peer) {
+	defer h.wg.Done()
+	defer h.removePeer(p)
+
+	for {
+		msg, err := p.conn.Receive()
+		if err != nil {
+			return
+		}
+		p.touch()
+
+		switch msg.Type {
+		case MsgPing:
+			pong := &Message{Type: MsgPong, Payload: msg.Payload}
+			if err := p.Send(pong); err != nil {
+				return
+			}
+		case MsgPong:
+		case MsgDisconnect:
+			var d DisconnectPayload
+			_ = msg.Decode(&d)
+			log.Printf("[P2P] 节点 %s 主动断开: %s", p.ID, d.Reason)
+			return
+		default:
+			h.mu.RLock()
+			fn := h.handlers[msg.Type]
+			h.mu.RUnlock()
+			if fn == nil {
+				log.Printf("[P2P] 忽略来自 %s 的未知消息 %s", p.ID, msg.Type)
+				continue
+			}
+			if err := fn(p, msg); err != nil {
+				log.Printf("[P2P] 处理来自 %s 的 %s 消息失败: %v", p.ID, msg.Type, err)
+			}
+		}
+	}
+}
+
+func (h *Host) pingLoop() {
+	defer h.wg.Done()
+	for {
+		select {
+		case <-h.quit:
+			return
+		case <-h.cfg.Clock.After(h.cfg.PingInterval):
+			for _, p := range h.Peers() {
+				if h.cfg.Clock.Now().Sub(p.LastSeen()) > h.cfg.PeerTimeout {
+					h.Disconnect(p.ID, "timeout")
+					continue
+				}
+				var buf [8]byte
+				_, _ = rand.Read(buf[:])
+				ping, _ := NewMessage(MsgPing, PingPayload{Nonce: binary.BigEndian.Uint64(buf[:])})
+				if err := p.Send(ping); err != nil {
+					h.removePeer(p)
+				}
+			}
+		}
+	}
+}
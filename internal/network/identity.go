@@ -0,0 +1,114 @@
+package network
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var (
+	ErrIdentityMismatch = errors.New("node id does not match public key")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// nodeIDPrefix 节点ID前缀，后接公钥哈希的前20个十六进制字符
+const nodeIDPrefix = "node-"
+
+// Identity 节点身份密钥对，节点ID由公钥派生
+type Identity struct {
+	PublicKey  ed25519.PublicKey
+	PrivateKey ed25519.PrivateKey
+}
+
+// identityFile 身份文件的持久化格式
+type identityFile struct {
+	PrivateKey string `json:"private_key"`
+}
+
+// GenerateIdentity 生成新的节点身份
+func GenerateIdentity() (*Identity, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{PublicKey: pub, PrivateKey: priv}, nil
+}
+
+// IdentityFromSeed 由32字节种子确定性地生成身份，用于测试
+func IdentityFromSeed(seed []byte) *Identity {
+	h := sha256.Sum256(seed)
+	priv := ed25519.NewKeyFromSeed(h[:])
+	return &Identity{PublicKey: priv.Public().(ed25519.PublicKey), PrivateKey: priv}
+}
+
+// LoadOrCreateIdentity 从文件加载身份，文件不存在时生成新身份并保存
+func LoadOrCreateIdentity(path string) (*Identity, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var f identityFile
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("parse identity %s: %w", path, err)
+		}
+		key, err := hex.DecodeString(f.PrivateKey)
+		if err != nil || len(key) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("invalid private key in %s", path)
+		}
+		priv := ed25519.PrivateKey(key)
+		return &Identity{PublicKey: priv.Public().(ed25519.PublicKey), PrivateKey: priv}, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	id, err := GenerateIdentity()
+	if err != nil {
+		return nil, err
+	}
+	data, err = json.Marshal(identityFile{PrivateKey: hex.EncodeToString(id.PrivateKey)})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// NodeID 返回由公钥派生的节点ID
+func (id *Identity) NodeID() string {
+	return NodeIDFromPublicKey(id.PublicKey)
+}
+
+// NodeIDFromPublicKey 由公钥派生节点ID
+func NodeIDFromPublicKey(pub ed25519.PublicKey) string {
+	h := sha256.Sum256(pub)
+	return nodeIDPrefix + hex.EncodeToString(h[:10])
+}
+
+// VerifyNodeID 检查节点ID是否由该公钥派生
+func VerifyNodeID(id string, pub []byte) error {
+	if len(pub) != ed25519.PublicKeySize || NodeIDFromPublicKey(pub) != id {
+		return fmt.Errorf("%w: %s", ErrIdentityMismatch, id)
+	}
+	return nil
+}
+
+// VerifySignature 检查节点ID与公钥绑定，并用公钥验证签名
+func VerifySignature(id string, pub, msg, sig []byte) error {
+	if err := VerifyNodeID(id, pub); err != nil {
+		return err
+	}
+	if !ed25519.Verify(ed25519.PublicKey(pub), msg, sig) {
+		return fmt.Errorf("%w from %s", ErrInvalidSignature, id)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"blockchain/internal/blockchain"
+)
+
+// MessageType 节点间消息类型
+type MessageType string
+
+const (
+	MsgHandshake    MessageType = "handshake"     // 握手，交换链标识、版本和高度
+	MsgHandshakeAck MessageType = "handshake_ack" // 握手确认，对对端随机数签名证明身份
+	MsgPing         MessageType = "ping"          // 心跳请求
+	MsgPong         MessageType = "pong"          // 心跳响应
+	MsgInv          MessageType = "inv"           // 宣告持有的区块或交易哈希
+	MsgGetData      MessageType = "getdata"       // 按哈希请求区块或交易
+	MsgBlock        MessageType = "block"         // 区块数据
+	MsgTx           MessageType = "tx"            // 交易数据
+	MsgBlockAssign  MessageType = "block_assign"  // 锚节点分配区块
+	MsgAnchor       MessageType = "anchor"        // 锚节点公告，携带选举纪元
+	MsgDisconnect   MessageType = "disconnect"    // 主动断开连接
+	MsgGetHeaders   MessageType = "getheaders"    // 按高度请求区块头
+	MsgHeaders      MessageType = "headers"       // 区块头列表
+	MsgGetBlocks    MessageType = "getblocks"     // 按哈希批量请求区块体
+	MsgBlocks       MessageType = "blocks"        // 区块体列表
+	MsgGetAddr      MessageType = "getaddr"       // 请求对端已知的节点地址
+	MsgAddr         MessageType = "addr"          // 节点地址列表
+	MsgGetSnapshot  MessageType = "getsnapshot"   // 请求最近的状态快照
+	MsgSnapshot     MessageType = "snapshot"      // 状态快照
+)
+
+// InvType 清单条目类型
+type InvType string
+
+const (
+	InvTx    InvType = "tx"
+	InvBlock InvType = "block"
+)
+
+// Message 节点间传输的消息，Payload为具体类型的JSON编码
+type Message struct {
+	Type    MessageType     `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// HandshakePayload 握手消息
+type HandshakePayload struct {
+	ChainID    string            `json:"chain_id"`
+	Version    int               `json:"version"`
+	NodeID     string            `json:"node_id"`
+	PublicKey  []byte            `json:"public_key"`
+	Nonce      []byte            `json:"nonce"`
+	Height     int               `json:"height"`
+	ListenAddr string            `json:"listen_addr"`
+	Stats      NodeStats         `json:"stats"`
+	Zone       string            `json:"zone,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Codecs     []string          `json:"codecs,omitempty"` // 支持的压缩方式，按偏好排序
+}
+
+// HandshakeAckPayload 握手确认
+type HandshakeAckPayload struct {
+	Signature []byte `json:"signature"`
+}
+
+// PingPayload 心跳消息，Pong原样返回Nonce
+type PingPayload struct {
+	Nonce uint64 `json:"nonce"`
+}
+
+// InvItem 清单条目
+type InvItem struct {
+	Type InvType `json:"type"`
+	Hash string  `json:"hash"`
+}
+
+// InvPayload 用于inv和getdata消息
+type InvPayload struct {
+	Items []InvItem `json:"items"`
+}
+
+// DisconnectPayload 断开原因
+type DisconnectPayload struct {
+	Reason string `json:"reason"`
+}
+
+// BlockPayload 区块消息
+type BlockPayload struct {
+	Block block_chain.Block `json:"block"`
+}
+
+// TxPayload 交易消息
+type TxPayload struct {
+	Tx block_chain.Transaction `json:"tx"`
+}
+
+// GetHeadersPayload 请求从From高度开始的至多Count个区块头
+type GetHeadersPayload struct {
+	From  int `json:"from"`
+	Count int `json:"count"`
+}
+
+// HeadersPayload 区块头列表
+type HeadersPayload struct {
+	Headers []block_chain.BlockHeader `json:"headers"`
+}
+
+// GetBlocksPayload 按哈希请求区块体
+type GetBlocksPayload struct {
+	Hashes []string `json:"hashes"`
+}
+
+// BlocksPayload 区块体列表
+type BlocksPayload struct {
+	Blocks []block_chain.Block `json:"blocks"`
+}
+
+// SnapshotPayload 状态快照，对端没有快照时Snapshot为空
+type SnapshotPayload struct {
+	Snapshot *block_chain.StateSnapshot `json:"snapshot,omitempty"`
+}
+
+// AddrPayload 节点地址交换
+type AddrPayload struct {
+	Addrs []PeerAddr `json:"addrs"`
+}
+
+// NewMessage 构造消息并编码负载
+func NewMessage(t MessageType, payload interface{}) (*Message, error) {
+	msg := &Message{Type: t}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("encode %s payload: %w", t, err)
+		}
+		msg.Payload = data
+	}
+	return msg, nil
+}
+
+// Decode 将负载解码到v
+func (m *Message) Decode(v interface{}) error {
+	if len(m.Payload) == 0 {
+		return fmt.Errorf("empty %s payload", m.Type)
+	}
+	if err := json.Unmarshal(m.Payload, v); err != nil {
+		return fmt.Errorf("decode %s payload: %w", m.Type, err)
+	}
+	return nil
+}
@@ -1,9 +1,16 @@
 package network
 
 import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 
 	"blockchain/internal/blockchain"
+	"blockchain/internal/vrf"
+	"blockchain/pkg/config"
 
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/shirou/gopsutil/disk"
@@ -11,6 +18,8 @@ import (
 	"github.com/shirou/gopsutil/net"
 )
 
+var ErrQuotaExceeded = errors.New("node storage quota exceeded")
+
 type Node struct {
 	ID           string
 	CPU          float64
@@ -22,21 +31,78 @@ type Node struct {
 	IsAnchor     bool
 	Address      string
 	NodeBlockMap map[string][]string
-	LastHealth   HealthStatus // 新增健康状态记录
+	LastHealth   HealthStatus      // 新增健康状态记录
+	Zone         string            // 故障域，副本尽量分布在不同zone
+	Labels       map[string]string // 放置标签，如机架、机房
+	PublicKey    ed25519.PublicKey
+	// 连续未通过存储证明挑战的次数
+	ChallengeFailures int
+	// 存储配额(字节)，0表示不限；UsedBytes为已保存区块编码后的总大小
+	QuotaBytes int64
+	UsedBytes  int64
+	// 排空中的节点已移出哈希环，不再接收新区块，已有副本迁移到其他节点
+	Draining bool
+	// 手动设置的虚拟节点数，0表示按磁盘和评分自动计算
+	Weight int
+
+	privateKey ed25519.PrivateKey
+
+	storeMu sync.Mutex
+	held    map[string]int64 // 已保存的区块/分片 -> 占用字节
 }
 
-// BlockAssignInfo 用于锚节点分配区块时在通道中传递的信息
+// NodeStats 节点性能指标，握手时发送给对端
+type NodeStats struct {
+	CPU       float64 `json:"cpu"`
+	Memory    float64 `json:"memory"`
+	Disk      float64 `json:"disk"`
+	Bandwidth float64 `json:"bandwidth"`
+}
+
+// BlockAssignInfo 用于锚节点分配区块时在通道中传递的信息，由锚节点签名
 type BlockAssignInfo struct {
 	Block        block_chain.Block
 	TargetNodeID string
+	AnchorID     string
+	AnchorKey    []byte
+	Epoch        uint64 // 签名时锚节点所在的选举纪元
+	Signature    []byte
+}
+
+// signingBytes 分配信息中被签名的内容，包含区块完整内容的摘要，区块被替换后签名失效
+func (info *BlockAssignInfo) signingBytes() []byte {
+	block, _ := json.Marshal(info.Block)
+	digest := sha256.Sum256(block)
+	data, _ := json.Marshal(struct {
+		Index        int
+		Hash         string
+		Digest       []byte
+		TargetNodeID string
+		AnchorID     string
+		Epoch        uint64
+	}{info.Block.Index, info.Block.Hash, digest[:], info.TargetNodeID, info.AnchorID, info.Epoch})
+	return data
 }
 
-// 更新NewNode函数
-func NewNode(id string) *Node {
+// Verify 检查分配信息由本地已知的当前锚节点在当前纪元签名，
+// 只用已知锚节点的公钥验签，不信任分配信息中携带的公钥
+func (info *BlockAssignInfo) Verify() error {
+	if info.AnchorID == "" || len(info.Signature) == 0 {
+		return errors.New("block assignment is not signed")
+	}
+	anchor, err := verifyAssignAnchor(info)
+	if err != nil {
+		return err
+	}
+	return VerifySignature(anchor.ID, anchor.PublicKey, info.signingBytes(), info.Signature)
+}
+
+// NewNode 使用身份密钥创建本地节点，节点ID由公钥派生
+func NewNode(identity *Identity) *Node {
 	cpuUsage, memFree, diskFree, bandwidth := getPerformance()
 
 	node := &Node{
-		ID:           id,
+		ID:           identity.NodeID(),
 		CPU:          cpuUsage,
 		Memory:       memFree,
 		Disk:         diskFree,
@@ -46,6 +112,11 @@ func NewNode(id string) *Node {
 		IsAnchor:     false,
 		Address:      "",
 		NodeBlockMap: make(map[string][]string),
+		PublicKey:    identity.PublicKey,
+		privateKey:   identity.PrivateKey,
+		Zone:         config.LocalZone,
+		Labels:       make(map[string]string),
+		QuotaBytes:   config.NodeQuotaBytes,
 	}
 
 	// 初始化健康状态
@@ -54,11 +125,86 @@ func NewNode(id string) *Node {
 	return node
 }
 
+// NewRemoteNode 根据对端握手信息创建远端节点记录，只持有公钥
+func NewRemoteNode(id string, publicKey ed25519.PublicKey, address string, stats NodeStats) *Node {
+	return &Node{
+		ID:           id,
+		PublicKey:    publicKey,
+		CPU:          stats.CPU,
+		Memory:       stats.Memory,
+		Disk:         stats.Disk,
+		Bandwidth:    stats.Bandwidth,
+		Address:      address,
+		NodeBlockMap: make(map[string][]string),
+		Labels:       make(map[string]string),
+		QuotaBytes:   config.NodeQuotaBytes,
+	}
+}
+
+// Stats 返回节点性能指标
+func (n *Node) Stats() NodeStats {
+	return NodeStats{
+		CPU:       n.CPU,
+		Memory:    n.Memory,
+		Disk:      n.Disk,
+		Bandwidth: n.Bandwidth,
+	}
+}
+
+// Sign 使用节点私钥签名，远端节点没有私钥时返回错误
+func (n *Node) Sign(msg []byte) ([]byte, error) {
+	if n.privateKey == nil {
+		return nil, fmt.Errorf("node %s has no private key", n.ID)
+	}
+	return ed25519.Sign(n.privateKey, msg), nil
+}
+
+// Suspended 连续未通过存储证明挑战达到 config.MaxChallengeFailures 次的节点被暂停:
+// 健康度和评分为0，不再被选为新区块的副本，也不参与锚节点选举，再次通过挑战后恢复
+func (n *Node) Suspended() bool {
+	return n.ChallengeFailures >= config.MaxChallengeFailures
+}
+
+// CanSign 判断本进程是否持有节点私钥，远端节点返回false
+func (n *Node) CanSign() bool {
+	return n.privateKey != nil
+}
+
+// SignBlockAssign 以锚节点身份签名区块分配信息，节点不是当前锚节点时返回错误
+func (n *Node) SignBlockAssign(info *BlockAssignInfo) error {
+	anchor := CurrentAnchor()
+	if anchor.ID != n.ID {
+		return fmt.Errorf("%w: node %s, anchor %s", ErrNotAnchor, n.ID, anchor.ID)
+	}
+	info.AnchorID = n.ID
+	info.AnchorKey = n.PublicKey
+	info.Epoch = anchor.Epoch
+	sig, err := n.Sign(info.signingBytes())
+	if err != nil {
+		return err
+	}
+	info.Signature = sig
+	return nil
+}
+
+// ProveVRF 使用节点私钥对选举种子计算VRF输出与证明
+func (n *Node) ProveVRF(backend vrf.Backend, seed []byte) ([]byte, []byte, error) {
+	if n.privateKey == nil {
+		return nil, nil, vrf.ErrInvalidKey
+	}
+	return backend.Prove(n.privateKey, seed)
+}
+
 // CalculateScore 更新CalculateScore时考虑健康状态
 func (n *Node) CalculateScore(node *Node) {
 	alpha, beta, gamma, delta := 0.3, 0.3, 0.2, 0.2
 	theta, epsilon := 0.6, 0.4
 
+	if node.Suspended() {
+		n.Score = 0
+		return
+	}
+
 	// 获取当前健康评分(0-100)并归一化到0-1
 	healthScore := node.CheckHealth().Score / 100
 
@@ -66,15 +212,31 @@ func (n *Node) CalculateScore(node *Node) {
 		epsilon*(node.Contribution*healthScore)
 }
 
-// StoreBlock 模拟存储区块并扣减磁盘空间
-func (n *Node) StoreBlock(blockID string, blockSizeGB float64) error {
+// StoreBlock 记录节点保存了size字节的区块(或分片)，超出配额或磁盘空间时拒绝；
+// 同一blockID重复保存不会重复计量
+func (n *Node) StoreBlock(blockID string, size int64) error {
+	n.storeMu.Lock()
+	defer n.storeMu.Unlock()
+
+	if _, exists := n.held[blockID]; exists {
+		return nil
+	}
+	if n.QuotaBytes > 0 && n.UsedBytes+size > n.QuotaBytes {
+		return fmt.Errorf("%w: 需要 %d 字节，已用 %d/%d 字节", ErrQuotaExceeded, size, n.UsedBytes, n.QuotaBytes)
+	}
 	// 检查磁盘空间是否足够
-	if blockSizeGB > n.Disk {
-		return fmt.Errorf("磁盘空间不足，需要 %.2fGB，可用 %.2fGB", blockSizeGB, n.Disk)
+	sizeGB := float64(size) / 1e9
+	if sizeGB > n.Disk {
+		return fmt.Errorf("%w: 需要 %.6fGB，可用 %.6fGB", ErrQuotaExceeded, sizeGB, n.Disk)
 	}
 
+	if n.held == nil {
+		n.held = make(map[string]int64)
+	}
+	n.held[blockID] = size
+	n.UsedBytes += size
 	// 扣减磁盘空间
-	n.Disk -= blockSizeGB
+	n.Disk -= sizeGB
 
 	// 更新区块映射
 	if _, exists := n.NodeBlockMap[blockID]; !exists {
@@ -84,31 +246,70 @@ func (n *Node) StoreBlock(blockID string, blockSizeGB float64) error {
 	return nil
 }
 
-// RemoveBlock 模拟删除区块并释放磁盘空间
-func (n *Node) RemoveBlock(blockID string, blockSizeGB float64) {
-	if _, exists := n.NodeBlockMap[blockID]; exists {
-		delete(n.NodeBlockMap, blockID)
-		n.Disk += blockSizeGB
+// RemoveBlock 删除区块并释放其占用的配额和磁盘空间
+func (n *Node) RemoveBlock(blockID string) {
+	n.storeMu.Lock()
+	defer n.storeMu.Unlock()
+
+	size, exists := n.held[blockID]
+	if !exists {
+		return
+	}
+	delete(n.held, blockID)
+	delete(n.NodeBlockMap, blockID)
+	n.UsedBytes -= size
+	n.Disk += float64(size) / 1e9
+}
+
+// FreeBytes 返回剩余配额，不限配额时返回-1
+func (n *Node) FreeBytes() int64 {
+	n.storeMu.Lock()
+	defer n.storeMu.Unlock()
+	if n.QuotaBytes <= 0 {
+		return -1
 	}
+	return n.QuotaBytes - n.UsedBytes
+}
+
+// IsArchival 归档节点保存全部历史区块，不做冷数据淘汰
+func (n *Node) IsArchival() bool {
+	return n.Labels[config.ArchivalLabel] == "true"
+}
+
+// EncodedSize 返回区块编码后的字节数，即实际占用的存储
+func EncodedSize(block block_chain.Block) int64 {
+	encoded, err := block_chain.EncodeBlock(block)
+	if err != nil {
+		return 0
+	}
+	return int64(len(encoded))
 }
 
 // ListenBlockAssign 监听锚节点分配的区块信息
 func (n *Node) ListenBlockAssign(assignChan chan BlockAssignInfo) {
 	for info := range assignChan {
 		if info.TargetNodeID == n.ID {
-			fmt.Printf("[节点 %s] 收到锚节点分配区块: 区块索引=%d, 哈希=%s\n", n.ID, info.Block.Index, info.Block.Hash)
-			// 假设每笔交易0.01GB，计算区块大小
-			blockSizeGB := float64(len(info.Block.Transactions)) * 0.01
-			err := n.StoreBlock(info.Block.Hash, blockSizeGB)
-			if err != nil {
-				fmt.Printf("[节点 %s] 存储区块失败: %v\n", n.ID, err)
-			} else {
-				fmt.Printf("[节点 %s] 成功存储区块，扣减磁盘 %.2fGB，剩余磁盘 %.2fGB\n", n.ID, blockSizeGB, n.Disk)
-			}
+			n.HandleBlockAssign(info)
 		}
 	}
 }
 
+// HandleBlockAssign 处理一条分配给本节点的区块，签名无效的分配信息直接丢弃
+func (n *Node) HandleBlockAssign(info BlockAssignInfo) {
+	if err := info.Verify(); err != nil {
+		fmt.Printf("[节点 %s] 拒绝区块分配: %v\n", n.ID, err)
+		return
+	}
+	fmt.Printf("[节点 %s] 收到锚节点分配区块: 区块索引=%d, 哈希=%s\n", n.ID, info.Block.Index, info.Block.Hash)
+	size := EncodedSize(info.Block)
+	err := n.StoreBlock(info.Block.Hash, size)
+	if err != nil {
+		fmt.Printf("[节点 %s] 存储区块失败: %v\n", n.ID, err)
+	} else {
+		fmt.Printf("[节点 %s] 成功存储区块，占用 %d 字节，已用 %d 字节\n", n.ID, size, n.UsedBytes)
+	}
+}
+
 func getPerformance() (float64, float64, float64, float64) {
 	cpuPercent, _ := cpu.Percent(0, false)
 	memStat, _ := mem.VirtualMemory()
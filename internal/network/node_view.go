@@ -0,0 +1,68 @@
+package network
+
+// NodeView 节点对外展示的只读副本，接口返回它而不是*Node，
+// 避免编码时与并发写入的NodeBlockMap等字段竞争，也不暴露内部字段
+type NodeView struct {
+	ID                string            `json:"id"`
+	Address           string            `json:"address,omitempty"`
+	Zone              string            `json:"zone,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	CPU               float64           `json:"cpu"`
+	Memory            float64           `json:"memory"`
+	Disk              float64           `json:"disk"`
+	Bandwidth         float64           `json:"bandwidth"`
+	Contribution      float64           `json:"contribution"`
+	Score             float64           `json:"score"`
+	Health            HealthStatus      `json:"health"`
+	IsAnchor          bool              `json:"isAnchor"`
+	Draining          bool              `json:"draining"`
+	Weight            int               `json:"weight,omitempty"`
+	ChallengeFailures int               `json:"challengeFailures"`
+	QuotaBytes        int64             `json:"quotaBytes,omitempty"`
+	UsedBytes         int64             `json:"usedBytes"`
+	HeldBlocks        int               `json:"heldBlocks"`
+}
+
+// View 复制节点当前状态；存储相关字段在storeMu下读取，
+// 其余字段由调用方在修改它们的同一把锁(service层的节点表锁)下调用
+func (n *Node) View() NodeView {
+	v := NodeView{
+		ID:                n.ID,
+		Address:           n.Address,
+		Zone:              n.Zone,
+		CPU:               n.CPU,
+		Memory:            n.Memory,
+		Bandwidth:         n.Bandwidth,
+		Contribution:      n.Contribution,
+		Score:             n.Score,
+		Health:            n.LastHealth,
+		IsAnchor:          n.IsAnchor,
+		Draining:          n.Draining,
+		Weight:            n.Weight,
+		ChallengeFailures: n.ChallengeFailures,
+		QuotaBytes:        n.QuotaBytes,
+	}
+	if len(n.Labels) > 0 {
+		v.Labels = make(map[string]string, len(n.Labels))
+		for k, val := range n.Labels {
+			v.Labels[k] = val
+		}
+	}
+
+	n.storeMu.Lock()
+	v.Disk = n.Disk
+	v.UsedBytes = n.UsedBytes
+	v.HeldBlocks = len(n.held)
+	n.storeMu.Unlock()
+	return v
+}
+
+// RecordAssignment 锚节点记录把区块分配给了哪个节点
+func (n *Node) RecordAssignment(targetNodeID, blockHash string) {
+	n.storeMu.Lock()
+	defer n.storeMu.Unlock()
+	if n.NodeBlockMap == nil {
+		n.NodeBlockMap = make(map[string][]string)
+	}
+	n.NodeBlockMap[targetNodeID] = append(n.NodeBlockMap[targetNodeID], blockHash)
+}
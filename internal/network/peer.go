@@ -0,0 +1,60 @@
+package network
+
+import (
+	"sync"
+	"time"
+)
+
+// Peer 已完成握手的远端节点连接
+type Peer struct {
+	ID          string
+	PublicKey   []byte
+	Addr        string // 对端监听地址，可用于重连
+	Version     int
+	Height      int
+	Inbound     bool
+	Stats       NodeStats // 对端握手时自报的性能指标
+	Zone        string
+	Labels      map[string]string
+	Codec       string // 协商的连接压缩方式
+	ConnectedAt time.Time
+
+	conn     Conn
+	clock    Clock
+	mu       sync.Mutex
+	lastSeen time.Time
+}
+
+// Send 向对端发送消息
+func (p *Peer) Send(msg *Message) error {
+	return p.conn.Send(msg)
+}
+
+// LastSeen 最近一次收到对端消息的时间
+func (p *Peer) LastSeen() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastSeen
+}
+
+// SetHeight 更新对端已知的链高度
+func (p *Peer) SetHeight(height int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if height > p.Height {
+		p.Height = height
+	}
+}
+
+// BestHeight 返回对端已知的链高度
+func (p *Peer) BestHeight() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.Height
+}
+
+func (p *Peer) touch() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastSeen = p.clock.Now()
+}
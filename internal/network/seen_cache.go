@@ -0,0 +1,66 @@
+package network
+
+import (
+	"sync"
+	"time"
+)
+
+// SeenCache 记录最近见过的消息键，容量满或过期时淘汰最早的条目
+type SeenCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	clock Clock
+	items map[string]time.Time
+	order []string
+}
+
+// NewSeenCache 创建已见消息缓存，过期时间按clock计算，clock为nil时使用系统时钟
+func NewSeenCache(size int, ttl time.Duration, clock Clock) *SeenCache {
+	if clock == nil {
+		clock = RealClock
+	}
+	return &SeenCache{
+		size:  size,
+		ttl:   ttl,
+		clock: clock,
+		items: make(map[string]time.Time),
+	}
+}
+
+// Add 记录键，返回true表示此前未见过
+func (c *SeenCache) Add(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	c.evict(now)
+	if _, ok := c.items[key]; ok {
+		return false
+	}
+	c.items[key] = now
+	c.order = append(c.order, key)
+	return true
+}
+
+// Has 判断键是否在缓存中
+func (c *SeenCache) Has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evict(c.clock.Now())
+	_, ok := c.items[key]
+	return ok
+}
+
+func (c *SeenCache) evict(now time.Time) {
+	drop := 0
+	for _, key := range c.order {
+		expired := c.ttl > 0 && now.Sub(c.items[key]) > c.ttl
+		if !expired && len(c.order)-drop <= c.size {
+			break
+		}
+		delete(c.items, key)
+		drop++
+	}
+	c.order = c.order[drop:]
+}
@@ -0,0 +1,686 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"blockchain/internal/blockchain"
+	"blockchain/pkg/config"
+)
+
+var (
+	ErrSyncBusy    = errors.New("sync request already in flight")
+	ErrForkTooDeep = errors.New("fork deeper than reorg limit")
+)
+
+// SyncStore 同步过程访问本地链的接口，由*block_chain.Blockchain实现
+type SyncStore interface {
+	LastBlock() block_chain.Block
+	GetHeaders(from, count int) []block_chain.BlockHeader
+	GetBlockByHash(hash string) (block_chain.Block, bool)
+	ValidateHeader(prev, header block_chain.BlockHeader) error
+	AcceptBlock(block block_chain.Block) error
+	LatestSnapshot() (block_chain.StateSnapshot, bool)
+	ImportSnapshot(snap block_chain.StateSnapshot, headers []block_chain.BlockHeader) error
+	Rollback(height int) error
+}
+
+// SyncStatus 同步状态，Diverged表示对端链与本地链分叉，ForkHeight为共同祖先高度
+type SyncStatus struct {
+	Syncing       bool    `json:"syncing"`
+	CurrentHeight int     `json:"currentHeight"`
+	TargetHeight  int     `json:"targetHeight"`
+	Progress      float64 `json:"progress"`
+	Peer          string  `json:"peer,omitempty"`
+	LastError     string  `json:"lastError,omitempty"`
+	Diverged      bool    `json:"diverged,omitempty"`
+	ForkHeight    int     `json:"forkHeight,omitempty"`
+}
+
+// Syncer 初始区块下载与链同步
+// 先从最高的对端下载区块头并校验链接关系，再按窗口从多个对端并行拉取区块体，
+// 区块体到达后逐个校验上链；中断时已下载的区块头保留，下次同步从断点继续。
+// 对端链与本地链分叉时向下查找共同祖先，下载对端区块后回滚本地分叉部分再切换到更长的链。
+// 开启裁剪的新节点远远落后时先下载状态快照，按区块头校验后直接从快照高度继续同步
+type Syncer struct {
+	host  *Host
+	store SyncStore
+
+	running int32
+
+	mu      sync.Mutex
+	status  SyncStatus
+	headers []block_chain.BlockHeader // 已校验但区块体尚未上链的区块头
+
+	waitMu     sync.Mutex
+	headerWait map[string]chan []block_chain.BlockHeader
+	blockWait  map[string]chan []block_chain.Block
+	snapWait   map[string]chan *block_chain.StateSnapshot
+}
+
+// NewSyncer 创建同步器并注册区块头/区块体请求的处理函数
+func NewSyncer(host *Host, store SyncStore) *Syncer {
+	s := &Syncer{
+		host:       host,
+		store:      store,
+		headerWait: make(map[string]chan []block_chain.BlockHeader),
+		blockWait:  make(map[string]chan []block_chain.Block),
+		snapWait:   make(map[string]chan *block_chain.StateSnapshot),
+	}
+	host.Handle(MsgGetHeaders, s.handleGetHeaders)
+	host.Handle(MsgHeaders, s.handleHeaders)
+	host.Handle(MsgGetBlocks, s.handleGetBlocks)
+	host.Handle(MsgBlocks, s.handleBlocks)
+	host.Handle(MsgGetSnapshot, s.handleGetSnapshot)
+	host.Handle(MsgSnapshot, s.handleSnapshot)
+	return s
+}
+
+// Status 返回当前同步状态
+func (s *Syncer) Status() SyncStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	if !status.Syncing {
+		status.CurrentHeight = s.store.LastBlock().Index
+	}
+	return status
+}
+
+// Start 周期性检查对端高度，落后时自动同步
+func (s *Syncer) Start(stop <-chan struct{}) {
+	go func() {
+		for {
+			if err := s.Sync(); err != nil {
+				log.Printf("[同步] 同步中断: %v", err)
+			}
+			select {
+			case <-stop:
+				return
+			case <-s.host.Clock().After(config.SyncInterval):
+			}
+		}
+	}()
+}
+
+// CatchUp 启动时等待至多wait时间直到有对端连接，然后同步到对端的最高高度；
+// 挖矿应在追赶完成后开始，否则各节点会在各自的链上出块而无法合并
+func (s *Syncer) CatchUp(wait time.Duration) error {
+	deadline := s.host.Clock().Now().Add(wait)
+	for len(s.host.Peers()) == 0 && s.host.Clock().Now().Before(deadline) {
+		<-s.host.Clock().After(100 * time.Millisecond)
+	}
+	return s.Sync()
+}
+
+// Sync 执行一轮同步，本地已是最高或已有同步在进行时直接返回
+func (s *Syncer) Sync() error {
+	if !atomic.CompareAndSwapInt32(&s.running, 0, 1) {
+		return nil
+	}
+	defer atomic.StoreInt32(&s.running, 0)
+
+	local := s.store.LastBlock()
+	peer := s.bestPeer(local.Index)
+	if peer == nil {
+		s.finish(local.Index, nil)
+		return nil
+	}
+
+	target := peer.BestHeight()
+	s.mu.Lock()
+	s.status = SyncStatus{
+		Syncing:       true,
+		CurrentHeight: local.Index,
+		TargetHeight:  target,
+		Peer:          peer.ID,
+	}
+	s.trimApplied()
+	if len(s.headers) > 0 && s.headers[0].Index <= local.Index {
+		s.status.Diverged = true
+		s.status.ForkHeight = s.headers[0].Index - 1
+	}
+	s.mu.Unlock()
+	s.updateProgress(local.Index)
+
+	log.Printf("[同步] 从节点 %s 同步区块，本地高度 %d，目标高度 %d", peer.ID, local.Index, target)
+
+	err := s.downloadHeaders(peer, local.Header())
+	if err == nil && config.PruningEnabled && local.Index == 0 && target > config.PruneKeepBlocks {
+		if serr := s.syncSnapshot(peer); serr != nil {
+			log.Printf("[同步] 快照同步失败，改为完整下载区块: %v", serr)
+		}
+	}
+	if err == nil {
+		err = s.downloadBodies()
+	}
+	s.finish(s.store.LastBlock().Index, err)
+	return err
+}
+
+// bestPeer 选择链高度最高且高于本地的对端
+func (s *Syncer) bestPeer(localHeight int) *Peer {
+	var best *Peer
+	for _, p := range s.host.Peers() {
+		if p.BestHeight() <= localHeight {
+			continue
+		}
+		if best == nil || p.BestHeight() > best.BestHeight() {
+			best = p
+		}
+	}
+	return best
+}
+
+// downloadHeaders 从对端下载区块头直到其链尾，逐个校验与前一个区块头的链接关系；
+// 第一个区块头接不上时说明对端在分叉上，找到共同祖先后从祖先之后重新下载
+func (s *Syncer) downloadHeaders(peer *Peer, tip block_chain.BlockHeader) error {
+	s.mu.Lock()
+	prev := tip
+	if len(s.headers) > 0 {
+		prev = s.headers[len(s.headers)-1]
+	}
+	s.mu.Unlock()
+
+	forked := false
+	for prev.Index < peer.BestHeight() {
+		headers, err := s.requestHeaders(peer, prev.Index+1, config.HeaderBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(headers) == 0 {
+			break
+		}
+		if !forked && headers[0].Index == prev.Index+1 && headers[0].PrevHash != prev.Hash {
+			forked = true
+			ancestor, err := s.findAncestor(peer, prev.Index)
+			s.mu.Lock()
+			s.status.Diverged = true
+			if err == nil {
+				s.headers = nil
+				s.status.ForkHeight = ancestor.Index
+			}
+			s.mu.Unlock()
+			if err != nil {
+				return err
+			}
+			log.Printf("[同步] 节点 %s 的链在高度 %d 之后与本地链分叉", peer.ID, ancestor.Index)
+			prev = ancestor
+			continue
+		}
+		for _, h := range headers {
+			if err := s.store.ValidateHeader(prev, h); err != nil {
+				return fmt.Errorf("peer %s sent invalid header: %w", peer.ID, err)
+			}
+			prev = h
+		}
+		peer.SetHeight(prev.Index)
+
+		s.mu.Lock()
+		s.headers = append(s.headers, headers...)
+		if prev.Index > s.status.TargetHeight {
+			s.status.TargetHeight = prev.Index
+		}
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// findAncestor 从height起向下逐批比较对端与本地链的区块头，返回最高的共同区块头；
+// 共同祖先比本地链尾低MaxReorgDepth以上时返回ErrForkTooDeep
+func (s *Syncer) findAncestor(peer *Peer, height int) (block_chain.BlockHeader, error) {
+	tip := s.store.LastBlock().Index
+	if height > tip {
+		height = tip
+	}
+	floor := tip - config.MaxReorgDepth
+	if floor < 0 {
+		floor = 0
+	}
+	for hi := height; hi >= floor; {
+		lo := hi - config.HeaderBatchSize + 1
+		if lo < floor {
+			lo = floor
+		}
+		remote, err := s.requestHeaders(peer, lo, hi-lo+1)
+		if err != nil {
+			return block_chain.BlockHeader{}, err
+		}
+		local := s.store.GetHeaders(lo, hi-lo+1)
+		for i := len(remote) - 1; i >= 0; i-- {
+			j := remote[i].Index - lo
+			if j >= 0 && j < len(local) && local[j].Hash == remote[i].Hash {
+				return local[j], nil
+			}
+		}
+		hi = lo - 1
+	}
+	return block_chain.BlockHeader{}, fmt.Errorf("%w: no common block with peer %s above height %d", ErrForkTooDeep, peer.ID, floor)
+}
+
+// syncSnapshot 向对端请求最近的状态快照，用已下载并校验过的区块头验证后导入，
+// 快照高度及以下的区块头不再下载区块体
+func (s *Syncer) syncSnapshot(peer *Peer) error {
+	snap, err := s.requestSnapshot(peer)
+	if err != nil {
+		return err
+	}
+	if snap == nil {
+		return fmt.Errorf("peer %s has no snapshot", peer.ID)
+	}
+
+	s.mu.Lock()
+	var headers []block_chain.BlockHeader
+	for _, h := range s.headers {
+		if h.Index > snap.Height {
+			break
+		}
+		headers = append(headers, h)
+	}
+	s.mu.Unlock()
+
+	if err := s.store.ImportSnapshot(*snap, headers); err != nil {
+		return err
+	}
+	log.Printf("[同步] 已从节点 %s 导入高度 %d 的状态快照", peer.ID, snap.Height)
+
+	s.mu.Lock()
+	s.trimHeaders(snap.Height)
+	s.mu.Unlock()
+	s.updateProgress(snap.Height)
+	return nil
+}
+
+// downloadBodies 按窗口并行拉取区块体，并按高度顺序校验上链
+func (s *Syncer) downloadBodies() error {
+	for {
+		s.mu.Lock()
+		n := len(s.headers)
+		if n > config.BodyWindowSize {
+			n = config.BodyWindowSize
+		}
+		window := append([]block_chain.BlockHeader(nil), s.headers[:n]...)
+		s.mu.Unlock()
+
+		if len(window) == 0 {
+			return nil
+		}
+
+		blocks := s.fetchWindow(window)
+		for _, h := range window {
+			block, ok := blocks[h.Hash]
+			if !ok {
+				return fmt.Errorf("missing body for block %d", h.Index)
+			}
+			if block.Header() != h {
+				s.resetHeaders()
+				return fmt.Errorf("body for block %d does not match header", h.Index)
+			}
+			if tip := s.store.LastBlock().Index; h.Index <= tip {
+				if err := s.switchFork(h.Index-1, tip); err != nil {
+					s.resetHeaders()
+					return err
+				}
+			}
+			if err := s.store.AcceptBlock(block); err != nil && !errors.Is(err, block_chain.ErrDuplicateBlock) {
+				s.resetHeaders()
+				return fmt.Errorf("accept block %d: %w", h.Index, err)
+			}
+
+			s.mu.Lock()
+			s.headers = s.headers[1:]
+			s.mu.Unlock()
+			s.updateProgress(h.Index)
+		}
+	}
+}
+
+// switchFork 分叉链的区块体已到达后回滚本地链到共同祖先ancestor，
+// 只在已下载的分叉链比本地链更长时切换
+func (s *Syncer) switchFork(ancestor, tip int) error {
+	s.mu.Lock()
+	end := 0
+	if len(s.headers) > 0 {
+		end = s.headers[len(s.headers)-1].Index
+	}
+	s.mu.Unlock()
+	if end <= tip {
+		return fmt.Errorf("fork at height %d is not longer than local chain (%d <= %d)", ancestor, end, tip)
+	}
+	if err := s.store.Rollback(ancestor); err != nil {
+		return fmt.Errorf("roll back to fork height %d: %w", ancestor, err)
+	}
+	log.Printf("[同步] 回滚本地高度 %d 之后的 %d 个区块，切换到高度 %d 的分叉链", ancestor, tip-ancestor, end)
+	return nil
+}
+
+// fetchWindow 将窗口内的区块头分批分配给各对端，同一对端的批次串行请求，失败的批次换对端重试一次
+func (s *Syncer) fetchWindow(window []block_chain.BlockHeader) map[string]block_chain.Block {
+	var batches [][]block_chain.BlockHeader
+	for i := 0; i < len(window); i += config.BodyBatchSize {
+		end := i + config.BodyBatchSize
+		if end > len(window) {
+			end = len(window)
+		}
+		batches = append(batches, window[i:end])
+	}
+
+	result := make(map[string]block_chain.Block)
+	var mu sync.Mutex
+	run := func(assign map[*Peer][][]block_chain.BlockHeader) [][]block_chain.BlockHeader {
+		var failed [][]block_chain.BlockHeader
+		var wg sync.WaitGroup
+		for p, list := range assign {
+			wg.Add(1)
+			go func(p *Peer, list [][]block_chain.BlockHeader) {
+				defer wg.Done()
+				for _, batch := range list {
+					blocks, err := s.requestBlocks(p, batch)
+					mu.Lock()
+					if err != nil {
+						failed = append(failed, batch)
+					}
+					for _, b := range blocks {
+						result[b.Hash] = b
+					}
+					mu.Unlock()
+				}
+			}(p, list)
+		}
+		wg.Wait()
+		return failed
+	}
+
+	failed := run(s.assignBatches(batches, 0))
+	if len(failed) > 0 {
+		run(s.assignBatches(failed, 1))
+	}
+	return result
+}
+
+// assignBatches 按轮询把批次分给拥有对应高度的对端，offset用于重试时换一个对端
+func (s *Syncer) assignBatches(batches [][]block_chain.BlockHeader, offset int) map[*Peer][][]block_chain.BlockHeader {
+	assign := make(map[*Peer][][]block_chain.BlockHeader)
+	peers := s.host.Peers()
+	for i, batch := range batches {
+		need := batch[len(batch)-1].Index
+		var candidates []*Peer
+		for _, p := range peers {
+			if p.BestHeight() >= need {
+				candidates = append(candidates, p)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		p := candidates[(i+offset)%len(candidates)]
+		assign[p] = append(assign[p], batch)
+	}
+	return assign
+}
+
+func (s *Syncer) requestHeaders(p *Peer, from, count int) ([]block_chain.BlockHeader, error) {
+	ch := make(chan []block_chain.BlockHeader, 1)
+	s.waitMu.Lock()
+	if _, busy := s.headerWait[p.ID]; busy {
+		s.waitMu.Unlock()
+		return nil, ErrSyncBusy
+	}
+	s.headerWait[p.ID] = ch
+	s.waitMu.Unlock()
+	defer func() {
+		s.waitMu.Lock()
+		delete(s.headerWait, p.ID)
+		s.waitMu.Unlock()
+	}()
+
+	msg, err := NewMessage(MsgGetHeaders, GetHeadersPayload{From: from, Count: count})
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Send(msg); err != nil {
+		return nil, err
+	}
+
+	select {
+	case headers := <-ch:
+		return headers, nil
+	case <-s.host.Clock().After(config.SyncRequestTimeout):
+		return nil, fmt.Errorf("getheaders from %s timed out", p.ID)
+	}
+}
+
+func (s *Syncer) requestBlocks(p *Peer, batch []block_chain.BlockHeader) ([]block_chain.Block, error) {
+	ch := make(chan []block_chain.Block, 1)
+	s.waitMu.Lock()
+	if _, busy := s.blockWait[p.ID]; busy {
+		s.waitMu.Unlock()
+		return nil, ErrSyncBusy
+	}
+	s.blockWait[p.ID] = ch
+	s.waitMu.Unlock()
+	defer func() {
+		s.waitMu.Lock()
+		delete(s.blockWait, p.ID)
+		s.waitMu.Unlock()
+	}()
+
+	hashes := make([]string, len(batch))
+	for i, h := range batch {
+		hashes[i] = h.Hash
+	}
+	msg, err := NewMessage(MsgGetBlocks, GetBlocksPayload{Hashes: hashes})
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Send(msg); err != nil {
+		return nil, err
+	}
+
+	select {
+	case blocks := <-ch:
+		if len(blocks) < len(batch) {
+			return blocks, fmt.Errorf("peer %s returned %d of %d blocks", p.ID, len(blocks), len(batch))
+		}
+		return blocks, nil
+	case <-s.host.Clock().After(config.SyncRequestTimeout):
+		return nil, fmt.Errorf("getblocks from %s timed out", p.ID)
+	}
+}
+
+func (s *Syncer) requestSnapshot(p *Peer) (*block_chain.StateSnapshot, error) {
+	ch := make(chan *block_chain.StateSnapshot, 1)
+	s.waitMu.Lock()
+	if _, busy := s.snapWait[p.ID]; busy {
+		s.waitMu.Unlock()
+		return nil, ErrSyncBusy
+	}
+	s.snapWait[p.ID] = ch
+	s.waitMu.Unlock()
+	defer func() {
+		s.waitMu.Lock()
+		delete(s.snapWait, p.ID)
+		s.waitMu.Unlock()
+	}()
+
+	msg, err := NewMessage(MsgGetSnapshot, struct{}{})
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Send(msg); err != nil {
+		return nil, err
+	}
+
+	select {
+	case snap := <-ch:
+		return snap, nil
+	case <-s.host.Clock().After(config.SyncRequestTimeout):
+		return nil, fmt.Errorf("getsnapshot from %s timed out", p.ID)
+	}
+}
+
+func (s *Syncer) handleGetHeaders(p *Peer, msg *Message) error {
+	var req GetHeadersPayload
+	if err := msg.Decode(&req); err != nil {
+		return err
+	}
+	count := req.Count
+	if count <= 0 || count > config.HeaderBatchSize {
+		count = config.HeaderBatchSize
+	}
+	reply, err := NewMessage(MsgHeaders, HeadersPayload{Headers: s.store.GetHeaders(req.From, count)})
+	if err != nil {
+		return err
+	}
+	return p.Send(reply)
+}
+
+func (s *Syncer) handleHeaders(p *Peer, msg *Message) error {
+	var payload HeadersPayload
+	if err := msg.Decode(&payload); err != nil {
+		return err
+	}
+	s.waitMu.Lock()
+	ch, ok := s.headerWait[p.ID]
+	s.waitMu.Unlock()
+	if ok {
+		select {
+		case ch <- payload.Headers:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *Syncer) handleGetBlocks(p *Peer, msg *Message) error {
+	var req GetBlocksPayload
+	if err := msg.Decode(&req); err != nil {
+		return err
+	}
+	if len(req.Hashes) > config.BodyWindowSize {
+		req.Hashes = req.Hashes[:config.BodyWindowSize]
+	}
+	var blocks []block_chain.Block
+	for _, hash := range req.Hashes {
+		if b, ok := s.store.GetBlockByHash(hash); ok {
+			blocks = append(blocks, b)
+		}
+	}
+	reply, err := NewMessage(MsgBlocks, BlocksPayload{Blocks: blocks})
+	if err != nil {
+		return err
+	}
+	return p.Send(reply)
+}
+
+func (s *Syncer) handleBlocks(p *Peer, msg *Message) error {
+	var payload BlocksPayload
+	if err := msg.Decode(&payload); err != nil {
+		return err
+	}
+	s.waitMu.Lock()
+	ch, ok := s.blockWait[p.ID]
+	s.waitMu.Unlock()
+	if ok {
+		select {
+		case ch <- payload.Blocks:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *Syncer) handleGetSnapshot(p *Peer, msg *Message) error {
+	var payload SnapshotPayload
+	if snap, ok := s.store.LatestSnapshot(); ok {
+		payload.Snapshot = &snap
+	}
+	reply, err := NewMessage(MsgSnapshot, payload)
+	if err != nil {
+		return err
+	}
+	return p.Send(reply)
+}
+
+func (s *Syncer) handleSnapshot(p *Peer, msg *Message) error {
+	var payload SnapshotPayload
+	if err := msg.Decode(&payload); err != nil {
+		return err
+	}
+	s.waitMu.Lock()
+	ch, ok := s.snapWait[p.ID]
+	s.waitMu.Unlock()
+	if ok {
+		select {
+		case ch <- payload.Snapshot:
+		default:
+		}
+	}
+	return nil
+}
+
+// trimHeaders 丢弃已上链的区块头，调用方需持有s.mu
+func (s *Syncer) trimHeaders(height int) {
+	i := 0
+	for i < len(s.headers) && s.headers[i].Index <= height {
+		i++
+	}
+	s.headers = s.headers[i:]
+}
+
+// trimApplied 丢弃已在本地链上的区块头；剩余区块头接不上本地链时(对端已换到其他分叉)全部丢弃，
+// 调用方需持有s.mu
+func (s *Syncer) trimApplied() {
+	for len(s.headers) > 0 {
+		local := s.store.GetHeaders(s.headers[0].Index, 1)
+		if len(local) == 0 || local[0].Hash != s.headers[0].Hash {
+			break
+		}
+		s.headers = s.headers[1:]
+	}
+	if len(s.headers) > 0 {
+		parent := s.store.GetHeaders(s.headers[0].Index-1, 1)
+		if len(parent) == 0 || parent[0].Hash != s.headers[0].PrevHash {
+			s.headers = nil
+		}
+	}
+}
+
+// resetHeaders 区块体与区块头不符时丢弃已下载的区块头，下次重新下载
+func (s *Syncer) resetHeaders() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.headers = nil
+}
+
+func (s *Syncer) updateProgress(height int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.CurrentHeight = height
+	if s.status.TargetHeight > 0 {
+		s.status.Progress = float64(height) / float64(s.status.TargetHeight)
+	}
+}
+
+func (s *Syncer) finish(height int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Syncing = false
+	s.status.CurrentHeight = height
+	if height > s.status.TargetHeight {
+		s.status.TargetHeight = height
+	}
+	if s.status.TargetHeight > 0 {
+		s.status.Progress = float64(height) / float64(s.status.TargetHeight)
+	} else {
+		s.status.Progress = 1
+	}
+	s.status.LastError = ""
+	if err != nil {
+		s.status.LastError = err.Error()
+	}
+}
@@ -0,0 +1,190 @@
+package network
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"blockchain/internal/blockchain"
+)
+
+// newTestHost 在进程内传输层上启动一个持有chain的节点
+func newTestHost(t *testing.T, transport Transport, addr string, chain *block_chain.Blockchain) *Host {
+	t.Helper()
+	identity, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultHostConfig()
+	cfg.Height = chain.Height
+	h := NewHost(&Node{ID: identity.NodeID(), PublicKey: identity.PublicKey, privateKey: identity.PrivateKey}, transport, cfg)
+	if err := h.Start(addr); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+func newTestChain() *block_chain.Blockchain {
+	chain := block_chain.NewBlockchain()
+	chain.Difficulty = 1
+	return chain
+}
+
+// mineTo 在chain上打包随机交易直到链高度达到height
+func mineTo(chain *block_chain.Blockchain, height int) {
+	for chain.Height() < height {
+		for i := 0; i < 3; i++ {
+			chain.AddTransaction(chain.GenerateRandomTransaction())
+		}
+		chain.MineBlock()
+	}
+}
+
+// assertSameChain 检查got与want的区块和状态根一致
+func assertSameChain(t *testing.T, got, want *block_chain.Blockchain) {
+	t.Helper()
+	if got.Height() != want.Height() {
+		t.Fatalf("height = %d, want %d", got.Height(), want.Height())
+	}
+	for i := 0; i <= want.Height(); i++ {
+		w, _ := want.GetBlockByIndex(i)
+		g, _ := got.GetBlockByIndex(i)
+		if g.Hash != w.Hash {
+			t.Fatalf("block %d hash = %s, want %s", i, g.Hash, w.Hash)
+		}
+	}
+	if got.State().Root() != want.State().Root() {
+		t.Fatal("state root differs after sync")
+	}
+}
+
+// cappedStore 只提供高度不超过limit的区块体，用于模拟同步中途对端不再响应
+type cappedStore struct {
+	*block_chain.Blockchain
+	limit atomic.Int64
+}
+
+func (c *cappedStore) GetBlockByHash(hash string) (block_chain.Block, bool) {
+	b, ok := c.Blockchain.GetBlockByHash(hash)
+	if !ok || int64(b.Index) > c.limit.Load() {
+		return block_chain.Block{}, false
+	}
+	return b, true
+}
+
+// TestSyncCatchUp 新节点连接到领先的节点后下载全部区块，状态给出目标高度和进度
+func TestSyncCatchUp(t *testing.T) {
+	const blocks = 5
+	transport := NewChanTransport()
+
+	ahead := newTestChain()
+	mineTo(ahead, blocks)
+	behind := newTestChain()
+
+	leader := newTestHost(t, transport, "leader", ahead)
+	NewSyncer(leader, ahead)
+	follower := newTestHost(t, transport, "follower", behind)
+	syncer := NewSyncer(follower, behind)
+
+	if status := syncer.Status(); status.CurrentHeight != 0 || status.Syncing {
+		t.Fatalf("status before sync = %+v", status)
+	}
+	if _, err := follower.Connect(leader.Node().Address); err != nil {
+		t.Fatal(err)
+	}
+	if err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertSameChain(t, behind, ahead)
+
+	status := syncer.Status()
+	if status.Syncing || status.TargetHeight != blocks || status.CurrentHeight != blocks || status.Progress != 1 || status.LastError != "" {
+		t.Fatalf("status after sync = %+v", status)
+	}
+}
+
+// TestSyncResume 区块体下载中断后已上链的区块和已下载的区块头保留，再次同步从断点继续
+func TestSyncResume(t *testing.T) {
+	const blocks, cut = 10, 4
+	transport := NewChanTransport()
+
+	ahead := newTestChain()
+	mineTo(ahead, blocks)
+	behind := newTestChain()
+
+	store := &cappedStore{Blockchain: ahead}
+	store.limit.Store(cut)
+	leader := newTestHost(t, transport, "leader", ahead)
+	NewSyncer(leader, store)
+	follower := newTestHost(t, transport, "follower", behind)
+	syncer := NewSyncer(follower, behind)
+	if _, err := follower.Connect(leader.Node().Address); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := syncer.Sync(); err == nil {
+		t.Fatal("sync against a peer that stops serving bodies succeeded")
+	}
+	if got := behind.Height(); got != cut {
+		t.Fatalf("height after interrupted sync = %d, want %d", got, cut)
+	}
+	if status := syncer.Status(); status.Syncing || status.LastError == "" || status.TargetHeight != blocks {
+		t.Fatalf("status after interrupted sync = %+v", status)
+	}
+	syncer.mu.Lock()
+	pending := len(syncer.headers)
+	syncer.mu.Unlock()
+	if pending != blocks-cut {
+		t.Fatalf("pending headers after interrupted sync = %d, want %d", pending, blocks-cut)
+	}
+
+	store.limit.Store(blocks)
+	if err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	assertSameChain(t, behind, ahead)
+	if status := syncer.Status(); status.CurrentHeight != blocks || status.Progress != 1 || status.LastError != "" {
+		t.Fatalf("status after resumed sync = %+v", status)
+	}
+}
+
+// TestSyncFork 本地链在共同祖先之后自行出块，同步时找到分叉点并切换到对端更长的链
+func TestSyncFork(t *testing.T) {
+	const shared, local, remote = 2, 4, 7
+	transport := NewChanTransport()
+
+	ahead := newTestChain()
+	mineTo(ahead, shared)
+	behind := newTestChain()
+	for i := 1; i <= shared; i++ {
+		b, _ := ahead.GetBlockByIndex(i)
+		if err := behind.AcceptBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mineTo(ahead, remote)
+	mineTo(behind, local)
+	mine, _ := behind.GetBlockByIndex(shared + 1)
+	theirs, _ := ahead.GetBlockByIndex(shared + 1)
+	if mine.Hash == theirs.Hash {
+		t.Fatal("chains did not fork")
+	}
+
+	leader := newTestHost(t, transport, "leader", ahead)
+	NewSyncer(leader, ahead)
+	follower := newTestHost(t, transport, "follower", behind)
+	syncer := NewSyncer(follower, behind)
+	if _, err := follower.Connect(leader.Node().Address); err != nil {
+		t.Fatal(err)
+	}
+	if err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertSameChain(t, behind, ahead)
+	status := syncer.Status()
+	if !status.Diverged || status.ForkHeight != shared || status.LastError != "" {
+		t.Fatalf("status after fork sync = %+v", status)
+	}
+}
@@ -0,0 +1,93 @@
+package network
+
+import (
+	"bufio"
+	"net"
+	"sync"
+
+	"blockchain/internal/compress"
+	"blockchain/pkg/config"
+)
+
+// TCPTransport 基于TCP的传输层，消息使用长度前缀帧编码
+type TCPTransport struct{}
+
+// NewTCPTransport 创建TCP传输层
+func NewTCPTransport() *TCPTransport {
+	return &TCPTransport{}
+}
+
+func (t *TCPTransport) Listen(addr string) (Listener, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &tcpListener{l: l}, nil
+}
+
+func (t *TCPTransport) Dial(addr string) (Conn, error) {
+	c, err := net.DialTimeout("tcp", addr, config.DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return newTCPConn(c), nil
+}
+
+type tcpListener struct {
+	l net.Listener
+}
+
+func (l *tcpListener) Accept() (Conn, error) {
+	c, err := l.l.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return newTCPConn(c), nil
+}
+
+func (l *tcpListener) Close() error {
+	return l.l.Close()
+}
+
+func (l *tcpListener) Addr() string {
+	return l.l.Addr().String()
+}
+
+type tcpConn struct {
+	c     net.Conn
+	r     *bufio.Reader
+	wmu   sync.Mutex
+	codec compress.Codec // 由wmu保护
+}
+
+func newTCPConn(c net.Conn) *tcpConn {
+	return &tcpConn{c: c, r: bufio.NewReader(c)}
+}
+
+func (c *tcpConn) Send(msg *Message) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return WriteFrameWith(c.c, msg, c.codec)
+}
+
+func (c *tcpConn) SetCodec(codec compress.Codec) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.codec = codec
+}
+
+func (c *tcpConn) Receive() (*Message, error) {
+	return ReadFrame(c.r)
+}
+
+func (c *tcpConn) Close() error {
+	return c.c.Close()
+}
+
+func (c *tcpConn) LocalAddr() string {
+	return c.c.LocalAddr().String()
+}
+
+func (c *tcpConn) RemoteAddr() string {
+	return c.c.RemoteAddr().String()
+}
@@ -0,0 +1,75 @@
+package network
+
+import (
+	"crypto/tls"
+	"net"
+
+	"blockchain/internal/security"
+	"blockchain/pkg/config"
+)
+
+// CertifiedConn 能够提供对端证书所绑定节点ID的连接，握手时与节点身份比对
+type CertifiedConn interface {
+	PeerNodeID() (string, error)
+}
+
+// TLSTransport 双向TLS传输层，帧格式与TCPTransport相同
+type TLSTransport struct {
+	server *tls.Config
+	client *tls.Config
+}
+
+// NewTLSTransport 使用服务端和客户端tls配置创建传输层
+func NewTLSTransport(server, client *tls.Config) *TLSTransport {
+	return &TLSTransport{server: server, client: client}
+}
+
+func (t *TLSTransport) Listen(addr string) (Listener, error) {
+	l, err := tls.Listen("tcp", addr, t.server)
+	if err != nil {
+		return nil, err
+	}
+	return &tlsListener{l: l}, nil
+}
+
+func (t *TLSTransport) Dial(addr string) (Conn, error) {
+	dialer := &net.Dialer{Timeout: config.DialTimeout}
+	c, err := tls.DialWithDialer(dialer, "tcp", addr, t.client)
+	if err != nil {
+		return nil, err
+	}
+	return &tlsConn{tcpConn: newTCPConn(c), tc: c}, nil
+}
+
+type tlsListener struct {
+	l net.Listener
+}
+
+func (l *tlsListener) Accept() (Conn, error) {
+	c, err := l.l.Accept()
+	if err != nil {
+		return nil, err
+	}
+	tc := c.(*tls.Conn)
+	return &tlsConn{tcpConn: newTCPConn(tc), tc: tc}, nil
+}
+
+func (l *tlsListener) Close() error {
+	return l.l.Close()
+}
+
+func (l *tlsListener) Addr() string {
+	return l.l.Addr().String()
+}
+
+type tlsConn struct {
+	*tcpConn
+	tc *tls.Conn
+}
+
+func (c *tlsConn) PeerNodeID() (string, error) {
+	if err := c.tc.Handshake(); err != nil {
+		return "", err
+	}
+	return security.PeerNodeID(c.tc.ConnectionState())
+}
@@ -0,0 +1,37 @@
+package network
+
+import (
+	"errors"
+
+	"blockchain/internal/compress"
+)
+
+var ErrConnClosed = errors.New("connection closed")
+
+// Conn 节点间的双向消息连接
+type Conn interface {
+	Send(msg *Message) error
+	Receive() (*Message, error)
+	Close() error
+	LocalAddr() string
+	RemoteAddr() string
+}
+
+// Listener 接受入站连接
+type Listener interface {
+	Accept() (Conn, error)
+	Close() error
+	Addr() string
+}
+
+// Transport 节点间传输层，TCP和进程内通道都实现该接口
+type Transport interface {
+	Listen(addr string) (Listener, error)
+	Dial(addr string) (Conn, error)
+}
+
+// CompressibleConn 按字节流传输的连接可在握手协商后启用压缩；
+// 进程内连接直接传递消息对象，无需实现
+type CompressibleConn interface {
+	SetCodec(codec compress.Codec)
+}
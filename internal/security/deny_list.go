@@ -0,0 +1,134 @@
+package security
+
+import (
+	"bufio"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DenyList 证书吊销名单，按证书序列号或节点ID吊销
+// 文件每行一条: "serial:<十六进制序列号>" 或 "node:<节点ID>"，#开头为注释
+type DenyList struct {
+	mu      sync.RWMutex
+	path    string
+	serials map[string]bool
+	nodes   map[string]bool
+}
+
+// NewDenyList 创建吊销名单，path为空时只保存在内存中
+func NewDenyList(path string) *DenyList {
+	return &DenyList{
+		path:    path,
+		serials: make(map[string]bool),
+		nodes:   make(map[string]bool),
+	}
+}
+
+// Reload 重新读取吊销名单文件，文件不存在时视为空名单
+func (d *DenyList) Reload() error {
+	if d.path == "" {
+		return nil
+	}
+	f, err := os.Open(d.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	serials := make(map[string]bool)
+	nodes := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kind, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("invalid deny list entry %q", line)
+		}
+		switch kind {
+		case "serial":
+			serials[strings.ToLower(value)] = true
+		case "node":
+			nodes[value] = true
+		default:
+			return fmt.Errorf("unknown deny list kind %q", kind)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.serials = serials
+	d.nodes = nodes
+	d.mu.Unlock()
+	return nil
+}
+
+// Watch 每隔interval重新读取吊销名单，直到stop关闭
+func (d *DenyList) Watch(interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := d.Reload(); err != nil {
+					log.Printf("[TLS] 重新加载吊销名单失败: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// RevokeSerial 吊销证书序列号
+func (d *DenyList) RevokeSerial(serial string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.serials[strings.ToLower(serial)] = true
+}
+
+// RevokeNode 吊销节点ID的所有证书
+func (d *DenyList) RevokeNode(nodeID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nodes[nodeID] = true
+}
+
+// Check 证书或其绑定的节点被吊销时返回错误
+func (d *DenyList) Check(cert *x509.Certificate) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	serial := cert.SerialNumber.Text(16)
+	if d.serials[serial] {
+		return fmt.Errorf("certificate %s is revoked", serial)
+	}
+	if id, err := NodeIDFromCert(cert); err == nil && d.nodes[id] {
+		return fmt.Errorf("node %s is revoked", id)
+	}
+	return nil
+}
+
+// AppendEntry 向吊销名单文件追加一条记录
+func AppendEntry(path, kind, value string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s:%s\n", kind, value)
+	return err
+}
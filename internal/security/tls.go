@@ -0,0 +1,99 @@
+package security
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSConfig 双向TLS配置，节点间连接和API共用
+type TLSConfig struct {
+	CAFile       string
+	CertFile     string
+	KeyFile      string
+	DenyListFile string
+}
+
+// Load 加载证书和吊销名单，返回服务端和客户端使用的tls配置
+func (c TLSConfig) Load() (server *tls.Config, client *tls.Config, deny *DenyList, err error) {
+	caPEM, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("read ca: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, nil, nil, errors.New("no ca certificate found")
+	}
+
+	pair, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("load node certificate: %w", err)
+	}
+
+	deny = NewDenyList(c.DenyListFile)
+	if err := deny.Reload(); err != nil {
+		return nil, nil, nil, fmt.Errorf("load deny list: %w", err)
+	}
+	verify := verifyNotRevoked(deny)
+
+	server = &tls.Config{
+		MinVersion:            tls.VersionTLS13,
+		Certificates:          []tls.Certificate{pair},
+		ClientCAs:             pool,
+		ClientAuth:            tls.RequireAndVerifyClientCert,
+		VerifyPeerCertificate: verify,
+	}
+	client = &tls.Config{
+		MinVersion:            tls.VersionTLS13,
+		Certificates:          []tls.Certificate{pair},
+		RootCAs:               pool,
+		VerifyPeerCertificate: verify,
+		// 节点地址多为IP且会变化，服务端身份通过证书中的节点ID与握手身份比对确认
+		InsecureSkipVerify: true,
+		VerifyConnection:   verifyChain(pool),
+	}
+	return server, client, deny, nil
+}
+
+// PeerNodeID 返回TLS连接对端证书绑定的节点ID
+func PeerNodeID(state tls.ConnectionState) (string, error) {
+	if len(state.PeerCertificates) == 0 {
+		return "", errors.New("peer presented no certificate")
+	}
+	return NodeIDFromCert(state.PeerCertificates[0])
+}
+
+// verifyNotRevoked 证书链校验通过后再检查吊销名单
+func verifyNotRevoked(deny *DenyList) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("peer presented no certificate")
+		}
+		cert, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+		return deny.Check(cert)
+	}
+}
+
+// verifyChain 跳过主机名检查时仍需校验对端证书由配置的CA签发
+func verifyChain(pool *x509.CertPool) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return errors.New("peer presented no certificate")
+		}
+		opts := x509.VerifyOptions{
+			Roots:         pool,
+			Intermediates: x509.NewCertPool(),
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+		for _, cert := range state.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := state.PeerCertificates[0].Verify(opts)
+		return err
+	}
+}
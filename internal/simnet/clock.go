@@ -0,0 +1,113 @@
+package simnet
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// VirtualClock 虚拟时钟，只有调用Advance时时间才会前进
+// 实现network.Clock，定时器和消息投递都作为事件按(时间, 序号)顺序在Advance的调用方协程中逐个执行，
+// 即单线程的事件循环，保证可复现
+type VirtualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	seq    uint64
+	events eventHeap
+}
+
+// NewVirtualClock 创建从start开始的虚拟时钟
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *VirtualClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.Schedule(d, func(now time.Time) { ch <- now })
+	return ch
+}
+
+// Schedule 在d之后执行fn，fn在Advance的调用方协程中执行
+func (c *VirtualClock) Schedule(d time.Duration, fn func(now time.Time)) {
+	if d < 0 {
+		d = 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	heap.Push(&c.events, &event{at: c.now.Add(d), seq: c.seq, fn: fn})
+}
+
+// Advance 将时间前进d，并按顺序执行期间到期的所有事件
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	c.mu.Unlock()
+
+	for {
+		c.mu.Lock()
+		if len(c.events) == 0 || c.events[0].at.After(target) {
+			c.now = target
+			c.mu.Unlock()
+			return
+		}
+		e := heap.Pop(&c.events).(*event)
+		c.now = e.at
+		c.mu.Unlock()
+
+		e.fn(e.at)
+	}
+}
+
+// RunFor 以step为步长推进d，每步之后短暂让出真实时间，使通过Transport接入的Host协程有机会处理消息；
+// 只使用Attach/Send的场景直接调用Advance即可
+func (c *VirtualClock) RunFor(d, step time.Duration) {
+	for elapsed := time.Duration(0); elapsed < d; elapsed += step {
+		c.Advance(step)
+		time.Sleep(yieldDelay)
+	}
+}
+
+// Pending 返回尚未执行的事件数
+func (c *VirtualClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.events)
+}
+
+// yieldDelay 每步推进后让出的真实时间
+const yieldDelay = time.Millisecond
+
+type event struct {
+	at  time.Time
+	seq uint64
+	fn  func(now time.Time)
+}
+
+type eventHeap []*event
+
+func (h eventHeap) Len() int { return len(h) }
+
+func (h eventHeap) Less(i, j int) bool {
+	if h[i].at.Equal(h[j].at) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].at.Before(h[j].at)
+}
+
+func (h eventHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *eventHeap) Push(x interface{}) { *h = append(*h, x.(*event)) }
+
+func (h *eventHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,415 @@
+package simnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"blockchain/internal/network"
+)
+
+// LinkConfig 单向链路的网络特性
+type LinkConfig struct {
+	Latency   time.Duration // 固定延迟
+	Jitter    time.Duration // 延迟抖动，在[-Jitter, +Jitter]内均匀分布
+	DropRate  float64       // 丢包率 0-1
+	Bandwidth int           // 带宽(字节/秒)，0表示不限
+}
+
+// Stats 模拟网络的消息统计
+type Stats struct {
+	Sent      int
+	Delivered int
+	Dropped   int
+}
+
+type linkKey struct {
+	from, to string
+}
+
+// Handler 模拟节点的消息处理函数，在虚拟时钟的事件循环中同步执行，可以在其中继续调用Send
+type Handler func(from string, msg *network.Message)
+
+// Network 确定性的模拟网络，节点之间的消息经过虚拟时钟按链路配置延迟、丢弃或被分区隔离
+// 通过Attach/Send收发的消息在Advance的事件循环中单线程处理，同一seed下丢包、延迟和处理顺序完全可复现；
+// Transport供真实的network.Host接入，Host在自己的协程中读取消息，处理顺序不保证可复现
+type Network struct {
+	clock *VirtualClock
+
+	mu          sync.Mutex
+	rng         *rand.Rand
+	defaultLink LinkConfig
+	links       map[linkKey]LinkConfig
+	busyUntil   map[linkKey]time.Time
+	partitions  map[string]map[string]int // 分区名 -> 节点 -> 所属组
+	listeners   map[string]*listener
+	handlers    map[string]Handler
+	stats       Stats
+}
+
+// New 创建模拟网络
+func New(seed int64, clock *VirtualClock) *Network {
+	return &Network{
+		clock:      clock,
+		rng:        rand.New(rand.NewSource(seed)),
+		links:      make(map[linkKey]LinkConfig),
+		busyUntil:  make(map[linkKey]time.Time),
+		partitions: make(map[string]map[string]int),
+		listeners:  make(map[string]*listener),
+		handlers:   make(map[string]Handler),
+	}
+}
+
+// Attach 把名为name的模拟节点接入网络，发给它的消息在事件循环中交给h处理
+func (n *Network) Attach(name string, h Handler) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.handlers[name] = h
+}
+
+// Send 从from向to发送消息，按链路特性和分区在虚拟时钟上安排投递；目标未接入时消息丢弃
+func (n *Network) Send(from, to string, msg *network.Message) {
+	cp := &network.Message{Type: msg.Type, Payload: append([]byte(nil), msg.Payload...)}
+	n.route(from, to, cp, func() bool {
+		n.mu.Lock()
+		h := n.handlers[to]
+		n.mu.Unlock()
+		if h == nil {
+			return false
+		}
+		h(from, cp)
+		return true
+	})
+}
+
+// Clock 返回模拟网络使用的虚拟时钟
+func (n *Network) Clock() *VirtualClock {
+	return n.clock
+}
+
+// SetDefaultLink 设置未单独配置的链路特性
+func (n *Network) SetDefaultLink(cfg LinkConfig) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.defaultLink = cfg
+}
+
+// SetLink 设置from到to方向的链路特性
+func (n *Network) SetLink(from, to string, cfg LinkConfig) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.links[linkKey{from, to}] = cfg
+}
+
+// SetLinkBoth 同时设置两个方向的链路特性
+func (n *Network) SetLinkBoth(a, b string, cfg LinkConfig) {
+	n.SetLink(a, b, cfg)
+	n.SetLink(b, a, cfg)
+}
+
+// Partition 创建命名分区，不同组的节点之间互相不可达，未列出的节点不受影响
+func (n *Network) Partition(name string, groups ...[]string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	members := make(map[string]int)
+	for i, g := range groups {
+		for _, node := range g {
+			members[node] = i
+		}
+	}
+	n.partitions[name] = members
+}
+
+// Heal 移除命名分区
+func (n *Network) Heal(name string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.partitions, name)
+}
+
+// HealAll 移除所有分区
+func (n *Network) HealAll() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.partitions = make(map[string]map[string]int)
+}
+
+// Reachable 判断a和b当前是否可以通信
+func (n *Network) Reachable(a, b string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.reachable(a, b)
+}
+
+// Components 按当前分区把节点划分为互相可达的组，组内和组间都按名字排序
+func (n *Network) Components(nodes []string) [][]string {
+	sorted := append([]string(nil), nodes...)
+	sort.Strings(sorted)
+
+	assigned := make(map[string]bool)
+	var groups [][]string
+	for _, a := range sorted {
+		if assigned[a] {
+			continue
+		}
+		group := []string{a}
+		assigned[a] = true
+		for _, b := range sorted {
+			if !assigned[b] && n.Reachable(a, b) {
+				group = append(group, b)
+				assigned[b] = true
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// Stats 返回消息统计
+func (n *Network) Stats() Stats {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.stats
+}
+
+// Transport 返回名为name的节点使用的传输层，监听地址即节点名
+func (n *Network) Transport(name string) network.Transport {
+	return &transport{net: n, name: name}
+}
+
+func (n *Network) reachable(a, b string) bool {
+	for _, members := range n.partitions {
+		ga, okA := members[a]
+		gb, okB := members[b]
+		if okA && okB && ga != gb {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *Network) link(from, to string) LinkConfig {
+	if cfg, ok := n.links[linkKey{from, to}]; ok {
+		return cfg
+	}
+	return n.defaultLink
+}
+
+// route 按链路特性决定消息是否丢弃以及投递时间，并在虚拟时钟上安排deliver
+func (n *Network) route(from, to string, msg *network.Message, deliver func() bool) {
+	size := 0
+	if data, err := json.Marshal(msg); err == nil {
+		size = len(data)
+	}
+
+	n.mu.Lock()
+	n.stats.Sent++
+	cfg := n.link(from, to)
+	if !n.reachable(from, to) || (cfg.DropRate > 0 && n.rng.Float64() < cfg.DropRate) {
+		n.stats.Dropped++
+		n.mu.Unlock()
+		return
+	}
+
+	now := n.clock.Now()
+	delay := cfg.Latency
+	if cfg.Jitter > 0 {
+		delay += time.Duration((n.rng.Float64()*2 - 1) * float64(cfg.Jitter))
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	if cfg.Bandwidth > 0 {
+		// 链路串行发送，排队等待前面的消息发送完毕
+		key := linkKey{from, to}
+		start := n.busyUntil[key]
+		if start.Before(now) {
+			start = now
+		}
+		done := start.Add(time.Duration(float64(size) / float64(cfg.Bandwidth) * float64(time.Second)))
+		n.busyUntil[key] = done
+		delay += done.Sub(now)
+	}
+	n.mu.Unlock()
+
+	n.clock.Schedule(delay, func(time.Time) {
+		if deliver() {
+			n.mu.Lock()
+			n.stats.Delivered++
+			n.mu.Unlock()
+		}
+	})
+}
+
+type transport struct {
+	net  *Network
+	name string
+}
+
+func (t *transport) Listen(addr string) (network.Listener, error) {
+	n := t.net
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, exists := n.listeners[t.name]; exists {
+		return nil, fmt.Errorf("node %s already listening", t.name)
+	}
+	l := &listener{net: n, name: t.name, accept: make(chan network.Conn, 64), done: make(chan struct{})}
+	n.listeners[t.name] = l
+	return l, nil
+}
+
+func (t *transport) Dial(addr string) (network.Conn, error) {
+	n := t.net
+	n.mu.Lock()
+	l, exists := n.listeners[addr]
+	reachable := n.reachable(t.name, addr)
+	n.mu.Unlock()
+
+	if !exists {
+		return nil, fmt.Errorf("no node listening at %s", addr)
+	}
+	if !reachable {
+		return nil, fmt.Errorf("%s unreachable from %s", addr, t.name)
+	}
+
+	local, remote := newConnPair(n, t.name, addr)
+
+	select {
+	case l.accept <- remote:
+		return local, nil
+	case <-l.done:
+		return nil, fmt.Errorf("node %s stopped listening", addr)
+	}
+}
+
+type listener struct {
+	net    *Network
+	name   string
+	accept chan network.Conn
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (l *listener) Accept() (network.Conn, error) {
+	select {
+	case c := <-l.accept:
+		return c, nil
+	case <-l.done:
+		return nil, io.EOF
+	}
+}
+
+func (l *listener) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+		l.net.mu.Lock()
+		delete(l.net.listeners, l.name)
+		l.net.mu.Unlock()
+	})
+	return nil
+}
+
+func (l *listener) Addr() string {
+	return l.name
+}
+
+// conn 模拟连接的一端，两端共享关闭信号
+type conn struct {
+	net    *Network
+	local  string
+	remote string
+	peer   *conn
+	inbox  *queue
+	done   chan struct{}
+	once   *sync.Once
+}
+
+// newConnPair 创建一对互相连接的模拟连接
+func newConnPair(n *Network, a, b string) (*conn, *conn) {
+	done := make(chan struct{})
+	once := &sync.Once{}
+	ca := &conn{net: n, local: a, remote: b, inbox: newQueue(done), done: done, once: once}
+	cb := &conn{net: n, local: b, remote: a, inbox: newQueue(done), done: done, once: once}
+	ca.peer, cb.peer = cb, ca
+	return ca, cb
+}
+
+func (c *conn) Send(msg *network.Message) error {
+	select {
+	case <-c.done:
+		return network.ErrConnClosed
+	default:
+	}
+	cp := &network.Message{Type: msg.Type, Payload: append([]byte(nil), msg.Payload...)}
+	peer := c.peer
+	c.net.route(c.local, c.remote, cp, func() bool { return peer.inbox.push(cp) })
+	return nil
+}
+
+func (c *conn) Receive() (*network.Message, error) {
+	return c.inbox.pop()
+}
+
+func (c *conn) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return nil
+}
+
+func (c *conn) LocalAddr() string {
+	return c.local
+}
+
+func (c *conn) RemoteAddr() string {
+	return c.remote
+}
+
+// queue 无界消息队列，投递事件在时钟协程中执行，不能因接收方处理慢而阻塞
+type queue struct {
+	mu     sync.Mutex
+	items  []*network.Message
+	notify chan struct{}
+	done   <-chan struct{}
+}
+
+func newQueue(done <-chan struct{}) *queue {
+	return &queue{notify: make(chan struct{}, 1), done: done}
+}
+
+func (q *queue) push(msg *network.Message) bool {
+	select {
+	case <-q.done:
+		return false
+	default:
+	}
+	q.mu.Lock()
+	q.items = append(q.items, msg)
+	q.mu.Unlock()
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+func (q *queue) pop() (*network.Message, error) {
+	for {
+		q.mu.Lock()
+		if len(q.items) > 0 {
+			msg := q.items[0]
+			q.items = q.items[1:]
+			q.mu.Unlock()
+			return msg, nil
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.notify:
+		case <-q.done:
+			return nil, io.EOF
+		}
+	}
+}
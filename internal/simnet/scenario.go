@@ -0,0 +1,106 @@
+package simnet
+
+import (
+	"sort"
+	"time"
+
+	"blockchain/internal/consensus"
+	"blockchain/internal/network"
+)
+
+// NewNode 创建评分固定的模拟节点，不读取本机性能指标
+func NewNode(id string, score float64) *network.Node {
+	return &network.Node{
+		ID:           id,
+		Score:        score,
+		NodeBlockMap: make(map[string][]string),
+		Labels:       make(map[string]string),
+	}
+}
+
+// NewRaft 按注入的评分选举锚节点的Raft实例
+func NewRaft() *consensus.Raft {
+	r := consensus.NewRaft()
+	r.FixedScores = true
+	return r
+}
+
+// ElectPerComponent 在每个互相可达的节点组内独立选举锚节点，用于复现分区下的双主(split-brain)场景
+// nodes以节点ID作为模拟网络中的名字，返回组序号到该组锚节点的映射
+func ElectPerComponent(n *Network, nodes []*network.Node, newRaft func() *consensus.Raft) map[int]*network.Node {
+	byID := make(map[string]*network.Node, len(nodes))
+	ids := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		byID[node.ID] = node
+		ids = append(ids, node.ID)
+	}
+
+	anchors := make(map[int]*network.Node)
+	for i, group := range n.Components(ids) {
+		members := make([]*network.Node, 0, len(group))
+		for _, id := range group {
+			members = append(members, byID[id])
+		}
+		if anchor := newRaft().ElectAnchor(members); anchor != nil {
+			anchors[i] = anchor
+		}
+	}
+	return anchors
+}
+
+// Flood 泛洪广播: 节点第一次收到某条消息时记录时间并转发给来源以外的所有节点，
+// 用于观察分区、丢包和延迟下消息能否到达以及到达时间
+type Flood struct {
+	net      *Network
+	nodes    []string
+	received map[string]map[string]time.Time // 消息ID -> 节点 -> 首次收到的虚拟时间
+}
+
+// NewFlood 把nodes接入模拟网络并参与泛洪
+func NewFlood(n *Network, nodes []string) *Flood {
+	f := &Flood{
+		net:      n,
+		nodes:    append([]string(nil), nodes...),
+		received: make(map[string]map[string]time.Time),
+	}
+	sort.Strings(f.nodes)
+	for _, name := range f.nodes {
+		name := name
+		n.Attach(name, func(from string, msg *network.Message) {
+			f.receive(name, from, string(msg.Payload))
+		})
+	}
+	return f
+}
+
+// Publish 由from发出一条新消息
+func (f *Flood) Publish(from, id string) {
+	f.receive(from, "", id)
+}
+
+// Received 返回各节点首次收到消息id的虚拟时间
+func (f *Flood) Received(id string) map[string]time.Time {
+	out := make(map[string]time.Time, len(f.received[id]))
+	for node, at := range f.received[id] {
+		out[node] = at
+	}
+	return out
+}
+
+func (f *Flood) receive(node, from, id string) {
+	seen := f.received[id]
+	if seen == nil {
+		seen = make(map[string]time.Time)
+		f.received[id] = seen
+	}
+	if _, ok := seen[node]; ok {
+		return
+	}
+	seen[node] = f.net.Clock().Now()
+	msg := &network.Message{Type: network.MsgInv, Payload: []byte(id)}
+	for _, to := range f.nodes {
+		if to != node && to != from {
+			f.net.Send(node, to, msg)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package simnet
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"blockchain/internal/network"
+)
+
+var (
+	start = time.Unix(1700000000, 0)
+	names = []string{"a", "b", "c", "d", "e"}
+)
+
+// floodOutcome 一次泛洪场景的结果，同一seed下每次运行必须完全相同
+type floodOutcome struct {
+	Partitioned map[string]time.Time
+	Healed      map[string]time.Time
+	Stats       Stats
+}
+
+// runFlood 在有丢包和抖动的网络上先分区后愈合，分别泛洪一条消息
+func runFlood(seed int64) floodOutcome {
+	n := New(seed, NewVirtualClock(start))
+	n.SetDefaultLink(LinkConfig{Latency: 50 * time.Millisecond, Jitter: 20 * time.Millisecond, DropRate: 0.2, Bandwidth: 64 * 1024})
+	f := NewFlood(n, names)
+
+	n.Partition("split", []string{"a", "b"}, []string{"c", "d", "e"})
+	f.Publish("a", "during")
+	n.Clock().Advance(5 * time.Second)
+
+	n.Heal("split")
+	f.Publish("a", "after")
+	n.Clock().Advance(5 * time.Second)
+
+	return floodOutcome{Partitioned: f.Received("during"), Healed: f.Received("after"), Stats: n.Stats()}
+}
+
+func TestFloodPartitionHeal(t *testing.T) {
+	out := runFlood(42)
+
+	for _, name := range []string{"c", "d", "e"} {
+		if _, ok := out.Partitioned[name]; ok {
+			t.Fatalf("%s received a message published across the partition", name)
+		}
+	}
+	if _, ok := out.Partitioned["b"]; !ok {
+		t.Fatal("b did not receive a message from its own side of the partition")
+	}
+	if len(out.Healed) != len(names) {
+		t.Fatalf("after heal %d of %d nodes received the message: %v", len(out.Healed), len(names), out.Healed)
+	}
+	if out.Stats.Dropped == 0 || out.Stats.Delivered+out.Stats.Dropped != out.Stats.Sent {
+		t.Fatalf("unexpected stats %+v", out.Stats)
+	}
+}
+
+func TestFloodDeterministic(t *testing.T) {
+	first := runFlood(7)
+	for i := 0; i < 5; i++ {
+		if again := runFlood(7); !reflect.DeepEqual(first, again) {
+			t.Fatalf("run %d differs:\n%+v\n%+v", i, first, again)
+		}
+	}
+	if other := runFlood(8); reflect.DeepEqual(first, other) {
+		t.Fatal("different seeds produced identical runs")
+	}
+}
+
+// runElection 分区时每组各选出一个锚节点，愈合后只剩一个
+func runElection(seed int64) (split, healed []string) {
+	n := New(seed, NewVirtualClock(start))
+	scores := map[string]float64{"a": 40, "b": 55, "c": 70, "d": 70, "e": 65}
+	var nodes []*network.Node
+	for _, name := range names {
+		nodes = append(nodes, NewNode(name, scores[name]))
+	}
+
+	n.Partition("split", []string{"a", "b"}, []string{"c", "d", "e"})
+	for i := 0; i < 2; i++ {
+		split = append(split, ElectPerComponent(n, nodes, NewRaft)[i].ID)
+	}
+	n.HealAll()
+	for _, anchor := range ElectPerComponent(n, nodes, NewRaft) {
+		healed = append(healed, anchor.ID)
+	}
+	return split, healed
+}
+
+func TestSplitBrainElection(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		split, healed := runElection(1)
+		if want := []string{"b", "c"}; !reflect.DeepEqual(split, want) {
+			t.Fatalf("anchors during partition = %v, want %v", split, want)
+		}
+		if want := []string{"c"}; !reflect.DeepEqual(healed, want) {
+			t.Fatalf("anchors after heal = %v, want %v", healed, want)
+		}
+	}
+}
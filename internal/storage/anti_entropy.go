@@ -0,0 +1,283 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	block_chain "blockchain/internal/blockchain"
+	"blockchain/internal/hash"
+	"blockchain/internal/merkle"
+	"blockchain/pkg/config"
+	"blockchain/service"
+)
+
+// RepairStats 反熵修复的统计指标
+type RepairStats struct {
+	Runs            int           `json:"runs"`
+	LastRun         time.Time     `json:"lastRun,omitempty"`
+	LastDuration    time.Duration `json:"lastDuration"`
+	NodesChecked    int           `json:"nodesChecked"`
+	NodesInSync     int           `json:"nodesInSync"`
+	Replicated      int           `json:"replicated"`
+	Deleted         int           `json:"deleted"`
+	CorruptRepaired int           `json:"corruptRepaired"`
+	ShardsRebuilt   int           `json:"shardsRebuilt"`
+	Failures        int           `json:"failures"`
+	Skipped         int           `json:"skipped"`
+}
+
+// AntiEntropy 周期性检查每个节点实际持有的区块与期望放置是否一致:
+// 先比较两者的Merkle根，不一致时再逐个比对，补齐缺失或损坏的副本，
+// 并删除节点上不再由其负责的区块
+type AntiEntropy struct {
+	rebalancer *Rebalancer
+
+	mu    sync.Mutex
+	stats RepairStats
+}
+
+// NewAntiEntropy 创建反熵任务，迁移进行中时跳过本轮检查
+func NewAntiEntropy(rebalancer *Rebalancer) *AntiEntropy {
+	return &AntiEntropy{rebalancer: rebalancer}
+}
+
+// Stats 返回修复指标的累计值
+func (ae *AntiEntropy) Stats() RepairStats {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+	return ae.stats
+}
+
+// Start 按 config.AntiEntropyInterval 周期运行
+func (ae *AntiEntropy) Start(stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(config.AntiEntropyInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				ae.RunOnce()
+			}
+		}
+	}()
+}
+
+// Summary 节点持有区块的Merkle摘要
+type Summary struct {
+	NodeID string   `json:"nodeId"`
+	Root   []byte   `json:"root"`
+	Hashes []string `json:"-"`
+}
+
+// NodeSummary 计算节点当前持有区块哈希(排序后)的Merkle摘要
+func NodeSummary(nodeID string) Summary {
+	mu.Lock()
+	hashes := make([]string, 0, len(data[nodeID]))
+	for _, b := range data[nodeID] {
+		hashes = append(hashes, b.Hash)
+	}
+	mu.Unlock()
+	return summarize(nodeID, hashes)
+}
+
+func summarize(nodeID string, hashes []string) Summary {
+	sort.Strings(hashes)
+	leaves := make([][]byte, len(hashes))
+	for i, h := range hashes {
+		leaves[i] = []byte(h)
+	}
+	return Summary{NodeID: nodeID, Root: merkle.Root(leaves), Hashes: hashes}
+}
+
+// expectedPlacement 按记录的放置(没有记录时按环)计算每个节点应持有的区块，
+// 已离开的节点由环上的后继节点补位
+func expectedPlacement() map[string][]string {
+	expected := make(map[string][]string)
+	for _, h := range storedHashes() {
+		for _, id := range desiredReplicas(h) {
+			expected[id] = append(expected[id], h)
+		}
+	}
+	return expected
+}
+
+func desiredReplicas(blockHash string) []string {
+	var want []string
+	if ids, ok := Placement(blockHash); ok {
+		for _, id := range ids {
+			if service.GetNodeByID(id) != nil {
+				want = append(want, id)
+			}
+		}
+	}
+	// 冷区块被淘汰后只保留在归档节点上，不再补足副本
+	if len(want) < config.ReplicationFactor && !archivedIn(want) {
+		ring, _ := hash.GetReplicas(blockHash, len(hash.Members()), service.NodeZone)
+		for _, id := range ring {
+			if len(want) == config.ReplicationFactor {
+				break
+			}
+			if !contains(want, id) {
+				want = append(want, id)
+			}
+		}
+		SetPlacement(blockHash, want)
+	}
+	return want
+}
+
+// RunOnce 执行一轮反熵检查与修复
+func (ae *AntiEntropy) RunOnce() {
+	if ae.rebalancer != nil && ae.rebalancer.Status().Running {
+		ae.mu.Lock()
+		ae.stats.Skipped++
+		ae.mu.Unlock()
+		return
+	}
+
+	start := time.Now()
+	expected := expectedPlacement()
+	nodeIDs := make(map[string]bool)
+	for id := range expected {
+		nodeIDs[id] = true
+	}
+	for id := range GetAllData() {
+		nodeIDs[id] = true
+	}
+
+	var run RepairStats
+	var extras []Move // 待删除的多余副本，From为持有节点
+	for id := range nodeIDs {
+		run.NodesChecked++
+		have := NodeSummary(id)
+		want := summarize(id, expected[id])
+		missing := difference(want.Hashes, have.Hashes)
+		corrupt := corruptOn(id, want.Hashes)
+		if bytes.Equal(have.Root, want.Root) && len(corrupt) == 0 {
+			run.NodesInSync++
+			continue
+		}
+
+		for _, h := range append(missing, corrupt...) {
+			if err := repairReplica(h, id); err != nil {
+				log.Printf("[反熵修复] 节点 %s 的区块 %s 修复失败: %v", id, short(h), err)
+				run.Failures++
+				continue
+			}
+			if contains(corrupt, h) {
+				run.CorruptRepaired++
+			} else {
+				run.Replicated++
+			}
+		}
+		if isArchival(id) {
+			continue // 归档节点保留全部历史区块
+		}
+		for _, h := range difference(have.Hashes, want.Hashes) {
+			extras = append(extras, Move{BlockHash: h, From: id})
+		}
+	}
+
+	// 期望的副本全部就位后才删除多余副本
+	for _, m := range extras {
+		if len(difference(desiredReplicas(m.BlockHash), Locate(m.BlockHash))) > 0 {
+			continue
+		}
+		if dropReplica(m.From, m.BlockHash) {
+			ClearCorrupt(m.BlockHash, m.From)
+			run.Deleted++
+		}
+	}
+
+	// 纠删码区块重建丢失的分片
+	for _, h := range erasureHashes() {
+		n, err := RepairShards(h)
+		if err != nil {
+			log.Printf("[反熵修复] 区块 %s 分片重建失败: %v", short(h), err)
+			run.Failures++
+			continue
+		}
+		run.ShardsRebuilt += n
+	}
+
+	ae.mu.Lock()
+	ae.stats.Runs++
+	ae.stats.LastRun = start
+	ae.stats.LastDuration = time.Since(start)
+	ae.stats.NodesChecked = run.NodesChecked
+	ae.stats.NodesInSync = run.NodesInSync
+	ae.stats.Replicated += run.Replicated
+	ae.stats.Deleted += run.Deleted
+	ae.stats.CorruptRepaired += run.CorruptRepaired
+	ae.stats.ShardsRebuilt += run.ShardsRebuilt
+	ae.stats.Failures += run.Failures
+	ae.mu.Unlock()
+
+	if run.Replicated+run.Deleted+run.CorruptRepaired+run.ShardsRebuilt+run.Failures > 0 {
+		log.Printf("[反熵修复] 补齐 %d, 删除 %d, 修复损坏 %d, 重建分片 %d, 失败 %d",
+			run.Replicated, run.Deleted, run.CorruptRepaired, run.ShardsRebuilt, run.Failures)
+	}
+}
+
+// corruptOn 返回节点上被标记损坏、且仍应由该节点持有的区块
+func corruptOn(nodeID string, hashes []string) []string {
+	var list []string
+	for _, h := range hashes {
+		if IsCorrupt(h, nodeID) {
+			list = append(list, h)
+		}
+	}
+	return list
+}
+
+// repairReplica 从其他副本取一份校验通过的区块写到目标节点，替换其损坏或缺失的副本
+func repairReplica(blockHash, nodeID string) error {
+	block, ok := goodCopy(blockHash, nodeID)
+	if !ok {
+		return errBlockNotFound
+	}
+	dropReplica(nodeID, blockHash)
+	if err := storeReplica(nodeID, block); err != nil {
+		return err
+	}
+	ClearCorrupt(blockHash, nodeID)
+	return nil
+}
+
+// goodCopy 从除exclude外的持有者中找一份有效副本；设置了主链时按主链校验
+func goodCopy(blockHash, exclude string) (block_chain.Block, bool) {
+	chain := canonicalChain()
+	var want block_chain.BlockHeader
+	if chain != nil {
+		var ok bool
+		if want, ok = chain.GetHeaderByHash(blockHash); !ok {
+			chain = nil
+		}
+	}
+	for _, id := range Locate(blockHash) {
+		if id == exclude || IsCorrupt(blockHash, id) {
+			continue
+		}
+		block, err := getBlock(id, blockHash)
+		if err != nil {
+			if errors.Is(err, errCorruptSegment) {
+				MarkCorrupt(blockHash, id, err.Error())
+			}
+			continue
+		}
+		if chain != nil {
+			if err := verifyBlock(chain, want, block); err != nil {
+				MarkCorrupt(blockHash, id, err.Error())
+				continue
+			}
+		}
+		return block, true
+	}
+	return block_chain.Block{}, false
+}
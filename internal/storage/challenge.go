@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	block_chain "blockchain/internal/blockchain"
+	"blockchain/internal/merkle"
+	"blockchain/pkg/config"
+	"blockchain/service"
+)
+
+var (
+	ErrChallengeFailed = errors.New("storage challenge failed")
+	ErrNoReference     = errors.New("no reference copy or commitment for block")
+)
+
+// Challenge 存储证明挑战: 要求节点对区块编码中第Chunk个字节范围连同随机数求哈希
+type Challenge struct {
+	BlockHash string `json:"blockHash"`
+	NodeID    string `json:"nodeId"`
+	Nonce     []byte `json:"nonce"`
+	Chunk     int    `json:"chunk"`
+}
+
+// ChallengeResponse 节点对挑战的应答，Data和Proof用于对照Merkle承诺校验
+type ChallengeResponse struct {
+	Digest []byte             `json:"digest"`
+	Data   []byte             `json:"data"`
+	Proof  []merkle.ProofStep `json:"proof"`
+}
+
+// Commitment 区块分发时记录的编码长度和分块Merkle根
+type Commitment struct {
+	Size int    `json:"size"`
+	Root []byte `json:"root"`
+}
+
+var (
+	commitMu    sync.Mutex
+	commitments = make(map[string]Commitment)
+)
+
+// chunks 把编码后的区块按 config.ChallengeChunkSize 切分
+func chunks(encoded []byte) [][]byte {
+	var list [][]byte
+	for off := 0; off < len(encoded); off += config.ChallengeChunkSize {
+		end := off + config.ChallengeChunkSize
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		list = append(list, encoded[off:end])
+	}
+	return list
+}
+
+// RecordCommitment 记录区块的Merkle承诺，没有本地副本时据此校验应答
+func RecordCommitment(block block_chain.Block) error {
+	encoded, err := block_chain.EncodeBlock(block)
+	if err != nil {
+		return err
+	}
+	commitMu.Lock()
+	defer commitMu.Unlock()
+	commitments[block.Hash] = Commitment{Size: len(encoded), Root: merkle.Root(chunks(encoded))}
+	return nil
+}
+
+// GetCommitment 返回区块的Merkle承诺
+func GetCommitment(blockHash string) (Commitment, bool) {
+	commitMu.Lock()
+	defer commitMu.Unlock()
+	c, ok := commitments[blockHash]
+	return c, ok
+}
+
+func challengeDigest(nonce, data []byte) []byte {
+	h := sha256.New()
+	h.Write(nonce)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// NewChallenge 为节点上的区块生成带新随机数、随机字节范围的挑战
+func NewChallenge(nodeID, blockHash string, size int) (Challenge, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return Challenge{}, err
+	}
+	n := (size + config.ChallengeChunkSize - 1) / config.ChallengeChunkSize
+	if n < 1 {
+		n = 1
+	}
+	chunk, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return Challenge{}, err
+	}
+	return Challenge{BlockHash: blockHash, NodeID: nodeID, Nonce: nonce, Chunk: int(chunk.Int64())}, nil
+}
+
+// Respond 被挑战节点用自己保存的副本计算应答
+func Respond(ch Challenge) (ChallengeResponse, error) {
+	block, err := getBlock(ch.NodeID, ch.BlockHash)
+	if err != nil {
+		return ChallengeResponse{}, err
+	}
+	encoded, err := block_chain.EncodeBlock(block)
+	if err != nil {
+		return ChallengeResponse{}, err
+	}
+	parts := chunks(encoded)
+	if ch.Chunk < 0 || ch.Chunk >= len(parts) {
+		return ChallengeResponse{}, fmt.Errorf("chunk %d out of range", ch.Chunk)
+	}
+	proof, err := merkle.New(parts).Proof(ch.Chunk)
+	if err != nil {
+		return ChallengeResponse{}, err
+	}
+	data := parts[ch.Chunk]
+	return ChallengeResponse{Digest: challengeDigest(ch.Nonce, data), Data: data, Proof: proof}, nil
+}
+
+// VerifyResponse 校验应答: 有主链副本时与自己计算的结果比较，否则对照分发时记录的Merkle承诺
+func VerifyResponse(ch Challenge, resp ChallengeResponse) error {
+	if chain := canonicalChain(); chain != nil {
+		if block, ok := chain.GetBlockByHash(ch.BlockHash); ok {
+			encoded, err := block_chain.EncodeBlock(block)
+			if err != nil {
+				return err
+			}
+			parts := chunks(encoded)
+			if ch.Chunk >= len(parts) || !bytes.Equal(resp.Digest, challengeDigest(ch.Nonce, parts[ch.Chunk])) {
+				return ErrChallengeFailed
+			}
+			return nil
+		}
+	}
+
+	c, ok := GetCommitment(ch.BlockHash)
+	if !ok {
+		return ErrNoReference
+	}
+	if !bytes.Equal(resp.Digest, challengeDigest(ch.Nonce, resp.Data)) {
+		return ErrChallengeFailed
+	}
+	if err := merkle.Verify(c.Root, resp.Data, resp.Proof); err != nil {
+		return ErrChallengeFailed
+	}
+	return nil
+}
+
+// ChallengeStats 存储证明挑战统计
+type ChallengeStats struct {
+	Issued int       `json:"issued"`
+	Passed int       `json:"passed"`
+	Failed int       `json:"failed"`
+	Last   time.Time `json:"last,omitempty"`
+}
+
+// Challenger 锚节点周期性地随机挑选节点及其应持有的区块发起挑战，
+// 未通过的节点扣减贡献值，副本标记为损坏交给反熵修复
+type Challenger struct {
+	anchorID func() string
+
+	mu    sync.Mutex
+	stats ChallengeStats
+}
+
+// NewChallenger 创建挑战者，anchorID返回当前锚节点ID，为空时不发起挑战
+func NewChallenger(anchorID func() string) *Challenger {
+	return &Challenger{anchorID: anchorID}
+}
+
+// Stats 返回挑战统计
+func (c *Challenger) Stats() ChallengeStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Start 按 config.ChallengeInterval 周期发起挑战
+func (c *Challenger) Start(stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(config.ChallengeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				c.RunOnce()
+			}
+		}
+	}()
+}
+
+// RunOnce 对每个持有区块的节点随机挑战一个其应持有的区块
+func (c *Challenger) RunOnce() {
+	anchor := c.anchorID()
+	if anchor == "" {
+		return
+	}
+	for nodeID, hashes := range expectedPlacement() {
+		if nodeID == anchor || len(hashes) == 0 {
+			continue
+		}
+		i, err := rand.Int(rand.Reader, big.NewInt(int64(len(hashes))))
+		if err != nil {
+			return
+		}
+		c.challenge(anchor, nodeID, hashes[i.Int64()])
+	}
+}
+
+func (c *Challenger) challenge(anchor, nodeID, blockHash string) {
+	size := 0
+	if commitment, ok := GetCommitment(blockHash); ok {
+		size = commitment.Size
+	} else if block, ok := goodCopy(blockHash, nodeID); ok {
+		encoded, _ := block_chain.EncodeBlock(block)
+		size = len(encoded)
+	}
+	ch, err := NewChallenge(nodeID, blockHash, size)
+	if err != nil {
+		return
+	}
+
+	resp, err := Respond(ch)
+	if err == nil {
+		err = VerifyResponse(ch, resp)
+	}
+	if errors.Is(err, ErrNoReference) {
+		return // 无法校验时不计入结果
+	}
+
+	passed := err == nil
+	service.RecordChallenge(nodeID, passed)
+
+	c.mu.Lock()
+	c.stats.Issued++
+	c.stats.Last = time.Now()
+	if passed {
+		c.stats.Passed++
+	} else {
+		c.stats.Failed++
+	}
+	c.mu.Unlock()
+
+	if !passed {
+		log.Printf("[存储证明] 锚节点 %s 挑战节点 %s 的区块 %s 未通过: %v", anchor, nodeID, short(blockHash), err)
+		MarkCorrupt(blockHash, nodeID, "storage challenge failed")
+	}
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"log"
+	"time"
+
+	"blockchain/internal/hash"
+	"blockchain/pkg/config"
+	"blockchain/service"
+)
+
+// Drain 把节点标记为排空并移出哈希环，不再接收新区块；
+// 环变化影响到的区块按正常迁移流程处理，节点上其余副本(有界负载或归档放置的)也迁移到其他节点
+func (rb *Rebalancer) Drain(nodeID string) error {
+	var err error
+	rb.OnMembershipChange(func() {
+		err = service.SetDraining(nodeID, true)
+	})
+	if err != nil {
+		return err
+	}
+	rb.evacuate(nodeID)
+	return nil
+}
+
+// Undrain 取消排空，节点重新加入哈希环并接管其负责的键范围
+func (rb *Rebalancer) Undrain(nodeID string) error {
+	var err error
+	rb.OnMembershipChange(func() {
+		err = service.SetDraining(nodeID, false)
+	})
+	return err
+}
+
+// Decommission 下线节点: 先排空，节点上的副本全部迁走后再从节点表删除。
+// force为true时立即删除节点并丢弃其上的副本，缺失的副本和分片由反熵任务补齐
+func (rb *Rebalancer) Decommission(nodeID string, force bool) error {
+	if force {
+		var err error
+		rb.OnMembershipChange(func() {
+			err = service.UnregisterNode(nodeID)
+		})
+		if err != nil {
+			return err
+		}
+		forgetNode(nodeID)
+		log.Printf("[节点下线] 节点 %s 已强制下线", nodeID)
+		return nil
+	}
+
+	if err := rb.Drain(nodeID); err != nil {
+		return err
+	}
+	go rb.awaitEmpty(nodeID)
+	return nil
+}
+
+// awaitEmpty 等待迁移完成，节点仍有副本时重新安排迁移，
+// 超过 config.DecommissionAttempts 轮仍未迁完则保持排空状态等待人工处理
+func (rb *Rebalancer) awaitEmpty(nodeID string) {
+	for attempt := 1; ; attempt++ {
+		for rb.Status().Running {
+			time.Sleep(time.Second)
+		}
+		blocks, held := NodeLoad(nodeID), len(NodeShards(nodeID))
+		if blocks == 0 && held == 0 {
+			break
+		}
+		if attempt >= config.DecommissionAttempts {
+			log.Printf("[节点下线] 节点 %s 仍有 %d 个区块和 %d 个分片未迁移，保持排空状态", nodeID, blocks, held)
+			return
+		}
+		rb.evacuate(nodeID)
+	}
+	if err := service.UnregisterNode(nodeID); err != nil {
+		log.Printf("[节点下线] 删除节点 %s 失败: %v", nodeID, err)
+		return
+	}
+	forgetNode(nodeID)
+	log.Printf("[节点下线] 节点 %s 的副本已全部迁移，节点已删除", nodeID)
+}
+
+// evacuate 为节点上的每个区块在其他节点补齐副本，补齐后删除该节点上的副本；
+// 纠删码分片直接移到其他节点，保持k+m的冗余
+func (rb *Rebalancer) evacuate(nodeID string) {
+	if n := MoveShards(nodeID); n > 0 {
+		log.Printf("[节点下线] 节点 %s 排空，已移走 %d 个分片", nodeID, n)
+	}
+
+	var moves []Move
+	retire := make(map[string][]string)
+	for _, held := range HeldBlocks(nodeID) {
+		h := held.Hash
+		holders := Locate(h)
+		want, ok := Placement(h)
+		if !ok {
+			want, _ = hash.GetReplicas(h, config.ReplicationFactor, service.NodeZone)
+		}
+		want = difference(want, []string{nodeID})
+		// 优先保留已有副本的节点，不足时按环上顺序补充
+		for _, id := range holders {
+			if len(want) >= config.ReplicationFactor {
+				break
+			}
+			if id != nodeID && !contains(want, id) && service.GetNodeByID(id) != nil {
+				want = append(want, id)
+			}
+		}
+		if len(want) < config.ReplicationFactor {
+			candidates, _ := hash.GetReplicas(h, len(hash.Members()), service.NodeZone)
+			for _, id := range candidates {
+				if len(want) >= config.ReplicationFactor {
+					break
+				}
+				if !contains(want, id) {
+					want = append(want, id)
+				}
+			}
+		}
+
+		for _, to := range difference(want, holders) {
+			moves = append(moves, Move{BlockHash: h, From: nodeID, To: to})
+		}
+		retire[h] = append(retire[h], nodeID)
+		SetPlacement(h, want)
+	}
+	if n := rb.enqueue(moves, retire, 0); n > 0 {
+		log.Printf("[节点下线] 节点 %s 排空，计划迁移 %d 个区块副本", nodeID, n)
+	}
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	block_chain "blockchain/internal/blockchain"
+	"blockchain/internal/hash"
+	"blockchain/pkg/config"
+	"blockchain/service"
+)
+
+var (
+	ErrNoCanonicalChain = errors.New("canonical chain is not set")
+	ErrNotCanonical     = errors.New("block is not on the canonical chain")
+	ErrNoValidReplica   = errors.New("no replica returned a valid block")
+)
+
+// CanonicalChain 读取区块时用于校验的本地主链，由*block_chain.Blockchain实现
+type CanonicalChain interface {
+	GetBlockByHash(hash string) (block_chain.Block, bool)
+	GetHeaderByHash(hash string) (block_chain.BlockHeader, bool)
+	ValidateBlockHash(block block_chain.Block) error
+}
+
+// CorruptReplica 校验失败、等待修复的副本
+type CorruptReplica struct {
+	BlockHash string `json:"blockHash"`
+	NodeID    string `json:"nodeId"`
+	Reason    string `json:"reason"`
+}
+
+var (
+	canonical CanonicalChain
+
+	corruptMu sync.Mutex
+	corrupt   = make(map[string]map[string]string) // 区块哈希 -> 节点 -> 原因
+)
+
+// SetCanonicalChain 设置读取区块时用于校验的主链
+func SetCanonicalChain(chain CanonicalChain) {
+	mu.Lock()
+	defer mu.Unlock()
+	canonical = chain
+}
+
+func canonicalChain() CanonicalChain {
+	mu.Lock()
+	defer mu.Unlock()
+	return canonical
+}
+
+// FetchBlock 从副本节点读取区块: 按就近(同故障域)和健康度排序依次尝试，
+// 校验区块哈希和区块头与主链一致，不一致时标记该副本待修复并换下一个副本；
+// 纠删码区块从任意k个分片还原后校验，返回的节点ID为空
+func FetchBlock(blockHash string) (block_chain.Block, string, error) {
+	chain := canonicalChain()
+	if chain == nil {
+		return block_chain.Block{}, "", ErrNoCanonicalChain
+	}
+	want, ok := chain.GetHeaderByHash(blockHash) // 本地区块体可能已被裁剪，只按区块头校验
+	if !ok {
+		return block_chain.Block{}, "", ErrNotCanonical
+	}
+
+	if IsErasureCoded(blockHash) {
+		block, err := ReadErasure(blockHash)
+		if err != nil {
+			return block_chain.Block{}, "", err
+		}
+		if err := verifyBlock(chain, want, block); err != nil {
+			return block_chain.Block{}, "", err
+		}
+		return block, "", nil
+	}
+
+	for _, nodeID := range fetchCandidates(blockHash) {
+		block, err := getBlock(nodeID, blockHash)
+		if err != nil {
+			// 段无法解码与内容校验失败一样，标记该副本待修复
+			if errors.Is(err, errCorruptSegment) {
+				log.Printf("[区块读取] 节点 %s 上的区块 %s 解码失败: %v", nodeID, short(blockHash), err)
+				MarkCorrupt(blockHash, nodeID, err.Error())
+			}
+			continue
+		}
+		if err := verifyBlock(chain, want, block); err != nil {
+			log.Printf("[区块读取] 节点 %s 上的区块 %s 校验失败: %v", nodeID, short(blockHash), err)
+			MarkCorrupt(blockHash, nodeID, err.Error())
+			continue
+		}
+		touch(nodeID, blockHash)
+		return block, nodeID, nil
+	}
+	return block_chain.Block{}, "", ErrNoValidReplica
+}
+
+// verifyBlock 检查副本内容的哈希正确且区块头与主链上的区块一致
+func verifyBlock(chain CanonicalChain, want block_chain.BlockHeader, got block_chain.Block) error {
+	if got.Hash != want.Hash {
+		return fmt.Errorf("hash %s does not match requested %s", short(got.Hash), short(want.Hash))
+	}
+	if err := chain.ValidateBlockHash(got); err != nil {
+		return err
+	}
+	if got.Header() != want {
+		return fmt.Errorf("header of block %d differs from canonical chain", got.Index)
+	}
+	return nil
+}
+
+// fetchCandidates 返回可能持有该区块的节点: 环上的副本节点和实际保存了该区块的节点，
+// 同故障域、未被标记损坏、健康评分高的节点排在前面
+func fetchCandidates(blockHash string) []string {
+	ids, _ := hash.GetReplicas(blockHash, config.ReplicationFactor, service.NodeZone)
+	for _, id := range Locate(blockHash) {
+		if !contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	type candidate struct {
+		id      string
+		local   bool
+		corrupt bool
+		health  float64
+	}
+	candidates := make([]candidate, 0, len(ids))
+	for _, id := range ids {
+		c := candidate{id: id, corrupt: IsCorrupt(blockHash, id)}
+		if node := service.GetNodeByID(id); node != nil {
+			c.local = config.LocalZone != "" && node.Zone == config.LocalZone
+			c.health = node.LastHealth.Score
+		}
+		candidates = append(candidates, c)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.corrupt != b.corrupt {
+			return !a.corrupt
+		}
+		if a.local != b.local {
+			return a.local
+		}
+		return a.health > b.health
+	})
+
+	ordered := make([]string, len(candidates))
+	for i, c := range candidates {
+		ordered[i] = c.id
+	}
+	return ordered
+}
+
+// MarkCorrupt 标记节点上的区块副本损坏，等待修复
+func MarkCorrupt(blockHash, nodeID, reason string) {
+	corruptMu.Lock()
+	defer corruptMu.Unlock()
+	if corrupt[blockHash] == nil {
+		corrupt[blockHash] = make(map[string]string)
+	}
+	corrupt[blockHash][nodeID] = reason
+}
+
+// ClearCorrupt 副本修复后清除损坏标记
+func ClearCorrupt(blockHash, nodeID string) {
+	corruptMu.Lock()
+	defer corruptMu.Unlock()
+	delete(corrupt[blockHash], nodeID)
+	if len(corrupt[blockHash]) == 0 {
+		delete(corrupt, blockHash)
+	}
+}
+
+// IsCorrupt 判断节点上的区块副本是否被标记损坏
+func IsCorrupt(blockHash, nodeID string) bool {
+	corruptMu.Lock()
+	defer corruptMu.Unlock()
+	_, ok := corrupt[blockHash][nodeID]
+	return ok
+}
+
+// CorruptReplicas 返回所有待修复的副本，按区块哈希和节点排序
+func CorruptReplicas() []CorruptReplica {
+	corruptMu.Lock()
+	defer corruptMu.Unlock()
+	var list []CorruptReplica
+	for h, nodes := range corrupt {
+		for id, reason := range nodes {
+			list = append(list, CorruptReplica{BlockHash: h, NodeID: id, Reason: reason})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].BlockHash != list[j].BlockHash {
+			return list[i].BlockHash < list[j].BlockHash
+		}
+		return list[i].NodeID < list[j].NodeID
+	})
+	return list
+}
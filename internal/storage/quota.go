@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	block_chain "blockchain/internal/blockchain"
+	"blockchain/internal/network"
+	"blockchain/pkg/config"
+	"blockchain/service"
+)
+
+var lastAccess = make(map[string]map[string]time.Time) // 节点 -> 区块哈希 -> 最近访问时间，由mu保护
+
+// touch 记录节点上区块的访问时间，供LRU淘汰使用
+func touch(nodeID, blockHash string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if lastAccess[nodeID] == nil {
+		lastAccess[nodeID] = make(map[string]time.Time)
+	}
+	lastAccess[nodeID][blockHash] = time.Now()
+}
+
+// storeReplica 按区块压缩编码后的实际大小计入目标节点配额并保存副本，
+// 配额不足时先按淘汰策略释放冷数据再重试
+func storeReplica(nodeID string, block block_chain.Block) error {
+	seg, err := encodeSegment(block)
+	if err != nil {
+		return err
+	}
+	if node := service.GetNodeByID(nodeID); node != nil {
+		size := int64(len(seg))
+		err := node.StoreBlock(block.Hash, size)
+		if errors.Is(err, network.ErrQuotaExceeded) && evict(node, size) {
+			err = node.StoreBlock(block.Hash, size)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	putSegment(nodeID, storedBlock{Hash: block.Hash, Index: block.Index, Segment: seg})
+	touch(nodeID, block.Hash)
+	return nil
+}
+
+// dropReplica 删除节点上的区块副本并释放其配额
+func dropReplica(nodeID, blockHash string) bool {
+	if !removeBlock(nodeID, blockHash) {
+		return false
+	}
+	mu.Lock()
+	delete(lastAccess[nodeID], blockHash)
+	mu.Unlock()
+	if node := service.GetNodeByID(nodeID); node != nil {
+		node.RemoveBlock(blockHash)
+	}
+	return true
+}
+
+// PlaceReplicas 按candidates顺序把区块保存到n个节点上，配额已满的节点被跳过，
+// 由后面的候选节点接替；归档节点额外各保存一份。返回实际保存的节点并记录放置
+func PlaceReplicas(block block_chain.Block, candidates []string, n int) []string {
+	placed := make([]string, 0, n)
+	for _, id := range candidates {
+		if len(placed) == n {
+			break
+		}
+		if contains(placed, id) {
+			continue
+		}
+		if err := storeReplica(id, block); err != nil {
+			log.Printf("[区块存储] 节点 %s 拒绝区块 %s: %v，改由下一个节点保存", id, short(block.Hash), err)
+			continue
+		}
+		placed = append(placed, id)
+	}
+	for _, node := range service.GetAllNodes() {
+		if !node.IsArchival() || node.Draining || contains(placed, node.ID) {
+			continue
+		}
+		if err := storeReplica(node.ID, block); err != nil {
+			log.Printf("[区块存储] 归档节点 %s 保存区块 %s 失败: %v", node.ID, short(block.Hash), err)
+			continue
+		}
+		placed = append(placed, node.ID)
+	}
+	if len(placed) > 0 {
+		SetPlacement(block.Hash, placed)
+	}
+	return placed
+}
+
+// evict 按 config.EvictionPolicy 淘汰非归档节点上的冷区块，直到腾出need字节；
+// 只淘汰已有归档节点保存的区块，保证数据不丢失
+func evict(node *network.Node, need int64) bool {
+	if node.IsArchival() || config.EvictionPolicy == "none" || node.QuotaBytes <= 0 {
+		return false
+	}
+
+	type victim struct {
+		hash   string
+		index  int
+		access time.Time
+	}
+	mu.Lock()
+	var victims []victim
+	for _, b := range data[node.ID] {
+		victims = append(victims, victim{hash: b.Hash, index: b.Index, access: lastAccess[node.ID][b.Hash]})
+	}
+	mu.Unlock()
+
+	switch config.EvictionPolicy {
+	case "oldest":
+		sort.Slice(victims, func(i, j int) bool { return victims[i].index < victims[j].index })
+	default:
+		sort.Slice(victims, func(i, j int) bool { return victims[i].access.Before(victims[j].access) })
+	}
+
+	for _, v := range victims {
+		if node.FreeBytes() >= need {
+			break
+		}
+		if !archived(v.hash, node.ID) {
+			continue
+		}
+		if dropReplica(node.ID, v.hash) {
+			if ids, ok := Placement(v.hash); ok {
+				SetPlacement(v.hash, difference(ids, []string{node.ID}))
+			}
+			log.Printf("[区块存储] 节点 %s 淘汰冷区块 %d (%s)", node.ID, v.index, short(v.hash))
+		}
+	}
+	return node.FreeBytes() >= need
+}
+
+// archived 判断除exclude外是否有归档节点保存了该区块
+func archived(blockHash, exclude string) bool {
+	for _, id := range Locate(blockHash) {
+		if id == exclude {
+			continue
+		}
+		if isArchival(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// archivedIn 判断nodeIDs中是否包含归档节点
+func archivedIn(nodeIDs []string) bool {
+	for _, id := range nodeIDs {
+		if isArchival(id) {
+			return true
+		}
+	}
+	return false
+}
+
+func isArchival(nodeID string) bool {
+	node := service.GetNodeByID(nodeID)
+	return node != nil && node.IsArchival()
+}
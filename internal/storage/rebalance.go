@@ -0,0 +1,417 @@
+package storage
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	block_chain "blockchain/internal/blockchain"
+	"blockchain/internal/hash"
+	"blockchain/pkg/config"
+	"blockchain/service"
+)
+
+// Move 一次区块迁移: 从From复制到To
+type Move struct {
+	BlockHash string `json:"blockHash"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+}
+
+// RebalanceStatus 迁移进度
+type RebalanceStatus struct {
+	Running    bool      `json:"running"`
+	Total      int       `json:"total"`
+	Completed  int       `json:"completed"`
+	Failed     int       `json:"failed"`
+	Ranges     int       `json:"movedRanges"`
+	Progress   float64   `json:"progress"`
+	StartedAt  time.Time `json:"startedAt,omitempty"`
+	FinishedAt time.Time `json:"finishedAt,omitempty"`
+	LastError  string    `json:"lastError,omitempty"`
+	// 新副本尚未全部就位而暂缓删除旧副本的区块数
+	PendingRetire int `json:"pendingRetire"`
+}
+
+// Rebalancer 哈希环成员变化后把受影响的区块迁移到新的副本节点
+// 迁移按 config.RebalanceRate 限速进行；迁移期间旧节点上的副本保留并继续提供读取，
+// 全部迁移完成后才从不再负责这些区块的旧节点上删除
+type Rebalancer struct {
+	mu      sync.Mutex
+	status  RebalanceStatus
+	queue   []Move
+	retire  map[string][]string // 区块哈希 -> 迁移完成后需删除副本的旧节点
+	wake    chan struct{}
+	running bool
+}
+
+// NewRebalancer 创建迁移器，需调用Start启动迁移任务
+func NewRebalancer() *Rebalancer {
+	return &Rebalancer{
+		retire: make(map[string][]string),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Status 返回当前迁移进度
+func (rb *Rebalancer) Status() RebalanceStatus {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	return rb.status
+}
+
+// OnMembershipChange 保存变更前的环，执行change(例如向环中加入或移除节点)，
+// 只处理落在易主键范围内、或记录的副本中有节点离开环的区块: 从记录的放置出发，
+// 只替换失去负责权或已离开的成员，其余副本(包括配额不足时改放的节点)保持不变
+func (rb *Rebalancer) OnMembershipChange(change func()) {
+	oldRing := hash.Default().Clone()
+	change()
+	newRing := hash.Default()
+
+	ranges, err := hash.Diff(oldRing, newRing, config.ReplicationFactor)
+	if err != nil {
+		log.Printf("[数据迁移] 比较新旧环失败: %v", err)
+	}
+	members := newRing.Members()
+
+	var moves []Move
+	retire := make(map[string][]string)
+	for _, h := range storedHashes() {
+		before, ok := Placement(h)
+		if !ok {
+			before, _ = oldRing.GetReplicas(h, config.ReplicationFactor, service.NodeZone)
+		}
+		after, changed := replan(newRing, h, before, rangeOf(ranges, newRing.KeyHash(h)), members)
+		if !changed {
+			continue
+		}
+		leaving := difference(before, after)
+		joining := difference(after, before)
+
+		holders := Locate(h)
+		for _, to := range joining {
+			if contains(holders, to) {
+				continue
+			}
+			from := pickSource(holders, leaving)
+			if from == "" {
+				log.Printf("[数据迁移] 区块 %s 没有可用的源副本", short(h))
+				continue
+			}
+			moves = append(moves, Move{BlockHash: h, From: from, To: to})
+		}
+		for _, id := range leaving {
+			if contains(holders, id) && !isArchival(id) {
+				retire[h] = append(retire[h], id)
+			}
+		}
+		SetPlacement(h, after)
+	}
+	if n := rb.enqueue(moves, retire, len(ranges)); n > 0 || len(retire) > 0 {
+		log.Printf("[数据迁移] 环成员变化，%d 段键范围易主，计划迁移 %d 个区块副本", len(ranges), n)
+	}
+}
+
+// rangeOf 返回包含环上位置pos的易主范围，不在任何范围内时返回nil
+func rangeOf(ranges []hash.RangeMove, pos uint32) *hash.RangeMove {
+	for i := range ranges {
+		if ranges[i].Contains(pos) {
+			return &ranges[i]
+		}
+	}
+	return nil
+}
+
+// replan 在记录的放置before上替换受影响的成员: 已离开环(移除或排空)的节点，
+// 以及区块所在范围中失去负责权的非归档节点。接替节点优先取该范围新增的负责节点，
+// 其次按新环上的有界负载顺序选择。归档节点不因范围易主被替换，
+// 因此淘汰后只剩归档节点的冷区块保持不变
+func replan(ring *hash.HashRing, blockHash string, before []string, moved *hash.RangeMove, members []string) ([]string, bool) {
+	var affected []string
+	for _, id := range before {
+		switch {
+		case !contains(members, id):
+			affected = append(affected, id)
+		case moved != nil && !isArchival(id) && contains(moved.From, id) && !contains(moved.To, id):
+			affected = append(affected, id)
+		}
+	}
+	if len(affected) == 0 {
+		return before, false
+	}
+
+	var candidates []string
+	if moved != nil {
+		candidates = append(candidates, difference(moved.To, moved.From)...)
+	}
+	bounded, _ := ring.GetReplicasBounded(blockHash, config.ReplicationFactor, service.NodeZone, NodeLoad)
+	candidates = append(candidates, bounded...)
+	all, _ := ring.GetN(blockHash, len(members))
+	candidates = append(candidates, all...)
+
+	after := difference(before, affected)
+	for range affected {
+		for _, id := range candidates {
+			if !contains(after, id) && !contains(affected, id) {
+				after = append(after, id)
+				break
+			}
+		}
+	}
+	return after, true
+}
+
+// enqueue 把迁移任务和待删除的旧副本加入队列并唤醒迁移任务，
+// 已在队列中的相同迁移不再重复加入，返回新加入的迁移数
+func (rb *Rebalancer) enqueue(moves []Move, retire map[string][]string, ranges int) int {
+	if len(moves) == 0 && len(retire) == 0 {
+		return 0
+	}
+
+	rb.mu.Lock()
+	if !rb.status.Running {
+		rb.status = RebalanceStatus{Running: true, StartedAt: time.Now()}
+	}
+	added := 0
+	for _, m := range moves {
+		if rb.queued(m) {
+			continue
+		}
+		rb.queue = append(rb.queue, m)
+		added++
+	}
+	for h, ids := range retire {
+		for _, id := range ids {
+			if !contains(rb.retire[h], id) {
+				rb.retire[h] = append(rb.retire[h], id)
+			}
+		}
+	}
+	rb.status.Total += added
+	rb.status.Ranges += ranges
+	rb.updateProgress()
+	rb.mu.Unlock()
+
+	select {
+	case rb.wake <- struct{}{}:
+	default:
+	}
+	return added
+}
+
+// queued 判断队列中是否已有把同一区块迁移到同一目标的任务，调用方需持有rb.mu
+func (rb *Rebalancer) queued(m Move) bool {
+	for _, q := range rb.queue {
+		if q.BlockHash == m.BlockHash && q.To == m.To {
+			return true
+		}
+	}
+	return false
+}
+
+// Start 启动迁移任务，每个tick最多执行一次迁移；暂缓删除的旧副本按反熵周期重试
+func (rb *Rebalancer) Start(stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(time.Second / time.Duration(config.RebalanceRate))
+		defer ticker.Stop()
+		retry := time.NewTicker(config.AntiEntropyInterval)
+		defer retry.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-retry.C:
+				rb.retryRetire()
+				continue
+			case <-rb.wake:
+			}
+			for rb.step() {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+				}
+			}
+		}
+	}()
+}
+
+// step 执行队列中的一次迁移，队列为空时完成收尾并返回false
+func (rb *Rebalancer) step() bool {
+	rb.mu.Lock()
+	if len(rb.queue) == 0 {
+		if rb.status.Running {
+			rb.finish()
+		}
+		rb.mu.Unlock()
+		return false
+	}
+	move := rb.queue[0]
+	rb.queue = rb.queue[1:]
+	rb.mu.Unlock()
+
+	err := copyBlock(move)
+
+	rb.mu.Lock()
+	if err != nil {
+		rb.status.Failed++
+		rb.status.LastError = err.Error()
+		log.Printf("[数据迁移] 区块 %s 从 %s 迁移到 %s 失败: %v", short(move.BlockHash), move.From, move.To, err)
+	} else {
+		rb.status.Completed++
+	}
+	rb.updateProgress()
+	rb.mu.Unlock()
+	return true
+}
+
+// finish 所有迁移完成后删除旧节点上的副本，调用方需持有rb.mu
+func (rb *Rebalancer) finish() {
+	rb.dropRetired()
+	rb.status.Running = false
+	rb.status.FinishedAt = time.Now()
+	log.Printf("[数据迁移] 迁移完成: 成功 %d, 失败 %d, 暂缓删除 %d", rb.status.Completed, rb.status.Failed, rb.status.PendingRetire)
+}
+
+// dropRetired 删除新副本已全部就位的区块在旧节点上的副本；
+// 新副本尚未就位的区块保留在rb.retire中等待下次重试，调用方需持有rb.mu
+func (rb *Rebalancer) dropRetired() {
+	for h, ids := range rb.retire {
+		// 新副本全部就位前保留旧副本，避免区块丢失
+		want, _ := Placement(h)
+		if len(difference(want, Locate(h))) > 0 {
+			continue
+		}
+		for _, id := range ids {
+			if contains(want, id) {
+				continue
+			}
+			dropReplica(id, h)
+		}
+		delete(rb.retire, h)
+	}
+	rb.status.PendingRetire = len(rb.retire)
+}
+
+// retryRetire 没有迁移进行时重试删除暂缓的旧副本，新副本可能已由反熵修复补齐
+func (rb *Rebalancer) retryRetire() {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if rb.status.Running || len(rb.retire) == 0 {
+		return
+	}
+	rb.dropRetired()
+	if rb.status.PendingRetire == 0 {
+		log.Printf("[数据迁移] 暂缓的旧副本已全部删除")
+	}
+}
+
+func (rb *Rebalancer) updateProgress() {
+	if rb.status.Total == 0 {
+		rb.status.Progress = 1
+		return
+	}
+	rb.status.Progress = float64(rb.status.Completed+rb.status.Failed) / float64(rb.status.Total)
+}
+
+// copyBlock 把区块从源节点复制到目标节点，源节点已删除该区块时改用其他有效副本
+func copyBlock(move Move) error {
+	block, err := getBlock(move.From, move.BlockHash)
+	if errors.Is(err, errCorruptSegment) {
+		MarkCorrupt(move.BlockHash, move.From, err.Error())
+	}
+	if err != nil {
+		var ok bool
+		if block, ok = goodCopy(move.BlockHash, move.To); !ok {
+			return errBlockNotFound
+		}
+	}
+	return storeReplica(move.To, block)
+}
+
+// pickSource 优先从即将不再负责该区块的节点复制，其次选择其他持有者
+func pickSource(holders, leaving []string) string {
+	for _, id := range holders {
+		if contains(leaving, id) && service.GetNodeByID(id) != nil {
+			return id
+		}
+	}
+	for _, id := range holders {
+		if service.GetNodeByID(id) != nil {
+			return id
+		}
+	}
+	return ""
+}
+
+// storedHashes 返回所有已保存区块的哈希，按哈希排序
+func storedHashes() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	seen := make(map[string]bool)
+	var hashes []string
+	for _, blocks := range data {
+		for _, b := range blocks {
+			if !seen[b.Hash] {
+				seen[b.Hash] = true
+				hashes = append(hashes, b.Hash)
+			}
+		}
+	}
+	sort.Strings(hashes)
+	return hashes
+}
+
+// getBlock 读取节点上保存的区块，节点没有该区块时返回errBlockNotFound，段损坏时返回errCorruptSegment
+func getBlock(nodeID, blockHash string) (block_chain.Block, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, sb := range data[nodeID] {
+		if sb.Hash == blockHash {
+			return sb.block()
+		}
+	}
+	return block_chain.Block{}, errBlockNotFound
+}
+
+func removeBlock(nodeID, blockHash string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	segments := data[nodeID]
+	for i, sb := range segments {
+		if sb.Hash == blockHash {
+			data[nodeID] = append(segments[:i:i], segments[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// difference 返回在a中但不在b中的元素
+func difference(a, b []string) []string {
+	var out []string
+	for _, x := range a {
+		if !contains(b, x) {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func short(h string) string {
+	if len(h) > 8 {
+		return h[:8]
+	}
+	return h
+}
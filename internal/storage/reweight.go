@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"log"
+	"time"
+
+	"blockchain/pkg/config"
+	"blockchain/service"
+)
+
+// StartReweight 周期性按评分和磁盘调整哈希环权重；
+// 贡献值和挑战结果频繁变化，只有偏差超过 config.RingWeightHysteresis 时才调整，调整经由OnMembershipChange迁移副本
+func (rb *Rebalancer) StartReweight(stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(config.RingWeightInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				rb.Reweight()
+			}
+		}
+	}()
+}
+
+// Reweight 立即检查一次环上权重，返回调整的节点数
+func (rb *Rebalancer) Reweight() int {
+	changes := service.RingWeightChanges(config.RingWeightHysteresis)
+	if len(changes) == 0 {
+		return 0
+	}
+	rb.OnMembershipChange(func() {
+		service.ApplyRingWeights(changes)
+	})
+	log.Printf("[数据迁移] 调整 %d 个节点的环上权重", len(changes))
+	return len(changes)
+}
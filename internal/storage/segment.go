@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	block_chain "blockchain/internal/blockchain"
+	"blockchain/internal/compress"
+	"blockchain/pkg/config"
+)
+
+// storedBlock 节点上保存的一个区块段，段头记录了压缩方式
+type storedBlock struct {
+	Hash    string
+	Index   int
+	Segment []byte
+}
+
+var (
+	codecMu sync.RWMutex
+	codec   = mustCodec(config.StorageCodec)
+)
+
+func mustCodec(name string) compress.Codec {
+	c, err := compress.ByName(name)
+	if err != nil {
+		log.Printf("[区块存储] 未知的压缩方式 %q，改为不压缩", name)
+		c, _ = compress.ByName("none")
+	}
+	return c
+}
+
+// SetCodec 设置新写入区块段使用的压缩方式，已保存的段按各自段头读取
+func SetCodec(name string) error {
+	c, err := compress.ByName(name)
+	if err != nil {
+		return err
+	}
+	codecMu.Lock()
+	defer codecMu.Unlock()
+	codec = c
+	return nil
+}
+
+func currentCodec() compress.Codec {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+	return codec
+}
+
+// encodeSegment 编码并压缩区块
+func encodeSegment(block block_chain.Block) ([]byte, error) {
+	raw, err := block_chain.EncodeBlock(block)
+	if err != nil {
+		return nil, err
+	}
+	return compress.EncodeSegment(currentCodec(), raw)
+}
+
+// decodeSegment 按段头解压并解码区块，兼容没有段头的旧数据
+func decodeSegment(seg []byte) (block_chain.Block, error) {
+	raw, err := compress.DecodeSegment(seg)
+	if err != nil {
+		return block_chain.Block{}, err
+	}
+	return block_chain.DecodeBlock(raw)
+}
+
+// block 解码区块段，段损坏时返回包装了errCorruptSegment的错误
+func (sb storedBlock) block() (block_chain.Block, error) {
+	b, err := decodeSegment(sb.Segment)
+	if err != nil {
+		return block_chain.Block{}, fmt.Errorf("%w: block %s: %v", errCorruptSegment, short(sb.Hash), err)
+	}
+	return b, nil
+}
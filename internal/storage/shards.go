@@ -0,0 +1,317 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	block_chain "blockchain/internal/blockchain"
+	"blockchain/internal/erasure"
+	"blockchain/internal/hash"
+	"blockchain/pkg/config"
+	"blockchain/service"
+)
+
+const (
+	ModeReplicate = "replicate" // 每个副本节点保存完整区块
+	ModeErasure   = "erasure"   // 区块经Reed–Solomon切分为k个数据分片和m个校验分片
+)
+
+var ErrNotEnoughNodes = errors.New("not enough nodes for erasure coded placement")
+
+// Shard 节点上保存的一个区块分片
+type Shard struct {
+	BlockHash string `json:"blockHash"`
+	Index     int    `json:"index"`
+	Size      int    `json:"size"` // 区块段(压缩编码后)的长度
+	Data      []byte `json:"data"`
+}
+
+// ShardLocation 分片所在节点
+type ShardLocation struct {
+	BlockHash string `json:"blockHash,omitempty"`
+	Index     int    `json:"index"`
+	NodeID    string `json:"nodeId"`
+	Parity    bool   `json:"parity"`
+}
+
+// shardLayout 纠删码区块的放置记录
+type shardLayout struct {
+	Size   int
+	K, M   int
+	Owners []string // 分片序号 -> 节点
+}
+
+var (
+	shardMu    sync.Mutex
+	shards     = make(map[string]map[string]Shard) // 节点 -> 区块哈希 -> 分片
+	shardPlans = make(map[string]shardLayout)      // 区块哈希 -> 放置记录
+)
+
+func newCoder() (*erasure.Coder, error) {
+	return erasure.New(config.ErasureDataShards, config.ErasureParityShards)
+}
+
+// StoreErasure 把区块编码后切分为k+m个分片，放到环上k+m个不同的后继节点，返回各分片所在节点
+func StoreErasure(block block_chain.Block) ([]string, error) {
+	coder, err := newCoder()
+	if err != nil {
+		return nil, err
+	}
+	total := coder.DataShards() + coder.ParityShards()
+	candidates, err := hash.GetReplicas(block.Hash, len(hash.Members()), service.NodeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := encodeSegment(block)
+	if err != nil {
+		return nil, err
+	}
+	parts := coder.Split(encoded)
+
+	// 依次放到环上的后继节点，配额不足的节点由下一个节点接替
+	owners := make([]string, 0, total)
+	for _, id := range candidates {
+		if len(owners) == total {
+			break
+		}
+		s := Shard{BlockHash: block.Hash, Index: len(owners), Size: len(encoded), Data: parts[len(owners)]}
+		if err := storeShard(id, s); err != nil {
+			continue
+		}
+		owners = append(owners, id)
+	}
+	if len(owners) < total {
+		for i, id := range owners {
+			dropShard(id, block.Hash, i)
+		}
+		return nil, fmt.Errorf("%w: need %d, have %d", ErrNotEnoughNodes, total, len(owners))
+	}
+
+	shardMu.Lock()
+	shardPlans[block.Hash] = shardLayout{Size: len(encoded), K: coder.DataShards(), M: coder.ParityShards(), Owners: owners}
+	shardMu.Unlock()
+	return owners, nil
+}
+
+func shardID(blockHash string, index int) string {
+	return fmt.Sprintf("%s#%d", blockHash, index)
+}
+
+// storeShard 按分片实际大小计入节点配额并保存分片
+func storeShard(nodeID string, s Shard) error {
+	if node := service.GetNodeByID(nodeID); node != nil {
+		if err := node.StoreBlock(shardID(s.BlockHash, s.Index), int64(len(s.Data))); err != nil {
+			return err
+		}
+	}
+	putShard(nodeID, s)
+	return nil
+}
+
+// dropShard 删除节点上的分片并释放配额
+func dropShard(nodeID, blockHash string, index int) {
+	shardMu.Lock()
+	delete(shards[nodeID], blockHash)
+	shardMu.Unlock()
+	if node := service.GetNodeByID(nodeID); node != nil {
+		node.RemoveBlock(shardID(blockHash, index))
+	}
+}
+
+func putShard(nodeID string, s Shard) {
+	shardMu.Lock()
+	defer shardMu.Unlock()
+	if shards[nodeID] == nil {
+		shards[nodeID] = make(map[string]Shard)
+	}
+	shards[nodeID][s.BlockHash] = s
+}
+
+func getShard(nodeID, blockHash string) (Shard, bool) {
+	shardMu.Lock()
+	defer shardMu.Unlock()
+	s, ok := shards[nodeID][blockHash]
+	return s, ok
+}
+
+// NodeShards 返回节点保存的分片位置，不含分片数据，按区块哈希排序
+func NodeShards(nodeID string) []ShardLocation {
+	shardMu.Lock()
+	defer shardMu.Unlock()
+	held := make([]ShardLocation, 0, len(shards[nodeID]))
+	for _, s := range shards[nodeID] {
+		plan := shardPlans[s.BlockHash]
+		held = append(held, ShardLocation{BlockHash: s.BlockHash, Index: s.Index, NodeID: nodeID, Parity: s.Index >= plan.K})
+	}
+	sort.Slice(held, func(i, j int) bool { return held[i].BlockHash < held[j].BlockHash })
+	return held
+}
+
+// IsErasureCoded 判断区块是否以纠删码方式保存
+func IsErasureCoded(blockHash string) bool {
+	shardMu.Lock()
+	defer shardMu.Unlock()
+	_, ok := shardPlans[blockHash]
+	return ok
+}
+
+// ShardLocations 返回纠删码区块各分片所在的节点
+func ShardLocations(blockHash string) []ShardLocation {
+	shardMu.Lock()
+	defer shardMu.Unlock()
+	plan, ok := shardPlans[blockHash]
+	if !ok {
+		return nil
+	}
+	locations := make([]ShardLocation, len(plan.Owners))
+	for i, id := range plan.Owners {
+		locations[i] = ShardLocation{Index: i, NodeID: id, Parity: i >= plan.K}
+	}
+	return locations
+}
+
+// collectShards 收集仍可读取的分片，缺失的位置为nil
+func collectShards(blockHash string) (shardLayout, [][]byte, error) {
+	shardMu.Lock()
+	plan, ok := shardPlans[blockHash]
+	shardMu.Unlock()
+	if !ok {
+		return shardLayout{}, nil, errBlockNotFound
+	}
+	parts := make([][]byte, len(plan.Owners))
+	for i, id := range plan.Owners {
+		if s, ok := getShard(id, blockHash); ok && s.Index == i && service.GetNodeByID(id) != nil {
+			parts[i] = s.Data
+		}
+	}
+	return plan, parts, nil
+}
+
+// ReadErasure 从任意k个分片还原区块
+func ReadErasure(blockHash string) (block_chain.Block, error) {
+	plan, parts, err := collectShards(blockHash)
+	if err != nil {
+		return block_chain.Block{}, err
+	}
+	coder, err := erasure.New(plan.K, plan.M)
+	if err != nil {
+		return block_chain.Block{}, err
+	}
+	if err := coder.Reconstruct(parts); err != nil {
+		return block_chain.Block{}, err
+	}
+	encoded, err := coder.Join(parts, plan.Size)
+	if err != nil {
+		return block_chain.Block{}, err
+	}
+	return decodeSegment(encoded)
+}
+
+// RepairShards 重建丢失的分片: 原节点仍在时写回原节点，否则放到环上未被占用的后继节点；
+// 返回重建的分片数
+func RepairShards(blockHash string) (int, error) {
+	plan, parts, err := collectShards(blockHash)
+	if err != nil {
+		return 0, err
+	}
+	var missing []int
+	for i, p := range parts {
+		if p == nil {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+
+	coder, err := erasure.New(plan.K, plan.M)
+	if err != nil {
+		return 0, err
+	}
+	if err := coder.Reconstruct(parts); err != nil {
+		return 0, err
+	}
+
+	owners := append([]string(nil), plan.Owners...)
+	candidates, _ := hash.GetReplicas(blockHash, len(hash.Members()), service.NodeZone)
+	rebuilt := 0
+	for _, i := range missing {
+		s := Shard{BlockHash: blockHash, Index: i, Size: plan.Size, Data: parts[i]}
+		if service.GetNodeByID(owners[i]) != nil && storeShard(owners[i], s) == nil {
+			rebuilt++
+			continue
+		}
+		// 原节点已离开或配额不足，放到环上未被占用的后继节点
+		for _, id := range candidates {
+			if contains(owners, id) || service.GetNodeByID(id) == nil {
+				continue
+			}
+			if storeShard(id, s) == nil {
+				owners[i] = id
+				rebuilt++
+				break
+			}
+		}
+	}
+
+	shardMu.Lock()
+	plan.Owners = owners
+	shardPlans[blockHash] = plan
+	shardMu.Unlock()
+
+	if rebuilt < len(missing) {
+		return rebuilt, ErrNotEnoughNodes
+	}
+	return rebuilt, nil
+}
+
+// MoveShards 把节点上的分片移到环上未持有该区块分片的节点并更新放置记录，
+// 用于排空节点；返回移走的分片数，没有可用节点的分片留在原节点
+func MoveShards(nodeID string) int {
+	moved := 0
+	for _, loc := range NodeShards(nodeID) {
+		s, ok := getShard(nodeID, loc.BlockHash)
+		if !ok {
+			continue
+		}
+		shardMu.Lock()
+		owners := append([]string(nil), shardPlans[s.BlockHash].Owners...)
+		shardMu.Unlock()
+
+		candidates, _ := hash.GetReplicas(s.BlockHash, len(hash.Members()), service.NodeZone)
+		for _, id := range candidates {
+			if id == nodeID || contains(owners, id) || service.GetNodeByID(id) == nil {
+				continue
+			}
+			if storeShard(id, s) != nil {
+				continue
+			}
+			shardMu.Lock()
+			plan := shardPlans[s.BlockHash]
+			if s.Index < len(plan.Owners) && plan.Owners[s.Index] == nodeID {
+				plan.Owners[s.Index] = id
+				shardPlans[s.BlockHash] = plan
+			}
+			shardMu.Unlock()
+			dropShard(nodeID, s.BlockHash, s.Index)
+			moved++
+			break
+		}
+	}
+	return moved
+}
+
+// erasureHashes 返回所有纠删码区块的哈希，按哈希排序
+func erasureHashes() []string {
+	shardMu.Lock()
+	defer shardMu.Unlock()
+	hashes := make([]string, 0, len(shardPlans))
+	for h := range shardPlans {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+	return hashes
+}
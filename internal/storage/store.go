@@ -2,58 +2,146 @@ package storage
 
 import (
 	block_chain "blockchain/internal/blockchain"
-	"blockchain/internal/hash"
-	"blockchain/service"
+	"errors"
 	"fmt"
-	"net/http"
+	"log"
+	"sort"
 	"sync"
 )
 
 var (
-	data = make(map[string][]block_chain.Block)
-	mu   sync.Mutex
+	errBlockNotFound  = errors.New("block not found on any replica")
+	errCorruptSegment = errors.New("stored segment cannot be decoded")
 )
 
-func HandleStoreData(w http.ResponseWriter, r *http.Request) {
-	key := r.URL.Query().Get("key")
-	if key == "" {
-		http.Error(w, "key is required", http.StatusBadRequest)
-		return
-	}
+var (
+	data      = make(map[string][]storedBlock) // 节点 -> 区块段
+	placement = make(map[string][]string)      // 区块哈希 -> 放置时选定的副本节点
+	mu        sync.Mutex
+)
 
-	nodeID, err := hash.GetNode(key)
+// StoreBlock 把区块压缩编码后保存到节点
+func StoreBlock(nodeID string, block *block_chain.Block) {
+	seg, err := encodeSegment(*block)
 	if err != nil {
-		http.Error(w, "failed to get node: "+err.Error(), http.StatusInternalServerError)
+		log.Printf("[区块存储] 区块 %s 编码失败: %v", short(block.Hash), err)
 		return
 	}
+	putSegment(nodeID, storedBlock{Hash: block.Hash, Index: block.Index, Segment: seg})
+}
 
-	service.AddContribution(nodeID, 1.0)
-	w.Write([]byte(fmt.Sprintf("Key %s stored on node %s\n", key, nodeID)))
+func putSegment(nodeID string, sb storedBlock) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, b := range data[nodeID] {
+		if b.Hash == sb.Hash {
+			return // 同一节点不重复保存副本
+		}
+	}
+	data[nodeID] = append(data[nodeID], sb)
 }
 
-func StoreBlock(nodeID string, block *block_chain.Block) {
+// SetPlacement 记录区块放置时选定的副本节点，有界负载可能使其偏离环上的顺序
+func SetPlacement(blockHash string, nodeIDs []string) {
 	mu.Lock()
 	defer mu.Unlock()
-	data[nodeID] = append(data[nodeID], *block)
+	placement[blockHash] = append([]string(nil), nodeIDs...)
+}
+
+// Placement 返回区块记录的副本节点
+func Placement(blockHash string) ([]string, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	ids, ok := placement[blockHash]
+	return append([]string(nil), ids...), ok
+}
+
+// NodeLoad 返回节点当前保存的区块数，用于有界负载放置
+func NodeLoad(nodeID string) int {
+	mu.Lock()
+	defer mu.Unlock()
+	return len(data[nodeID])
+}
+
+// HeldBlock 节点上保存的一个区块
+type HeldBlock struct {
+	Hash  string `json:"hash"`
+	Index int    `json:"index"`
+	Bytes int    `json:"bytes"` // 压缩编码后的大小
+}
+
+// HeldBlocks 返回节点保存的区块，按高度排序
+func HeldBlocks(nodeID string) []HeldBlock {
+	mu.Lock()
+	defer mu.Unlock()
+	held := make([]HeldBlock, 0, len(data[nodeID]))
+	for _, sb := range data[nodeID] {
+		held = append(held, HeldBlock{Hash: sb.Hash, Index: sb.Index, Bytes: len(sb.Segment)})
+	}
+	sort.Slice(held, func(i, j int) bool { return held[i].Index < held[j].Index })
+	return held
+}
+
+// forgetNode 删除已下线节点上的全部区块副本、分片和访问记录，
+// 分片由反熵任务在其他节点上重建
+func forgetNode(nodeID string) {
+	mu.Lock()
+	delete(data, nodeID)
+	delete(lastAccess, nodeID)
+	mu.Unlock()
+
+	shardMu.Lock()
+	delete(shards, nodeID)
+	shardMu.Unlock()
+}
+
+// Locate 返回实际保存了该区块的节点，按节点ID排序
+func Locate(blockHash string) []string {
+	mu.Lock()
+	defer mu.Unlock()
+	var holders []string
+	for nodeID, blocks := range data {
+		for _, b := range blocks {
+			if b.Hash == blockHash {
+				holders = append(holders, nodeID)
+				break
+			}
+		}
+	}
+	sort.Strings(holders)
+	return holders
 }
 
 func GetNodeBlocks(nodeID string) ([]block_chain.Block, error) {
 	mu.Lock()
 	defer mu.Unlock()
-	blocks, exists := data[nodeID]
+	segments, exists := data[nodeID]
 	if !exists {
 		return nil, fmt.Errorf("no blocks found for node %s", nodeID)
 	}
-	return blocks, nil
+	return decodeAll(segments), nil
 }
 
 func GetAllData() map[string][]block_chain.Block {
 	mu.Lock()
 	defer mu.Unlock()
-	// 返回一个副本，避免外部修改
+	// 返回解码后的副本，避免外部修改
 	dataCopy := make(map[string][]block_chain.Block)
 	for k, v := range data {
-		dataCopy[k] = append([]block_chain.Block{}, v...)
+		dataCopy[k] = decodeAll(v)
 	}
 	return dataCopy
 }
+
+func decodeAll(segments []storedBlock) []block_chain.Block {
+	blocks := make([]block_chain.Block, 0, len(segments))
+	for _, sb := range segments {
+		b, err := sb.block()
+		if err != nil {
+			log.Printf("[区块存储] %v", err)
+			continue
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
@@ -0,0 +1,56 @@
+package vrf
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+)
+
+// ed25519Domain VRF签名的域分隔前缀，避免与其他用途的签名混淆
+var ed25519Domain = []byte("block_chain/vrf/ed25519/v1:")
+
+// Ed25519Backend 基于ed25519确定性签名的测试用VRF后端
+// ed25519签名对同一私钥和消息是确定的，因此输出可复现且可被任何人验证；
+// 但恶意签名者可以构造不同的合法签名，不具备严格VRF的唯一性，仅用于测试和演示
+type Ed25519Backend struct{}
+
+// NewEd25519Backend 创建ed25519测试VRF后端
+func NewEd25519Backend() *Ed25519Backend {
+	return &Ed25519Backend{}
+}
+
+func (e *Ed25519Backend) Name() string {
+	return "ed25519-test"
+}
+
+func (e *Ed25519Backend) Prove(privateKey, alpha []byte) ([]byte, []byte, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, nil, ErrInvalidKey
+	}
+	pi := ed25519.Sign(ed25519.PrivateKey(privateKey), e.message(alpha))
+	return e.hash(pi), pi, nil
+}
+
+func (e *Ed25519Backend) Verify(publicKey, alpha, beta, pi []byte) error {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return ErrInvalidKey
+	}
+	if !ed25519.Verify(ed25519.PublicKey(publicKey), e.message(alpha), pi) {
+		return ErrInvalidProof
+	}
+	if !bytes.Equal(beta, e.hash(pi)) {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+func (e *Ed25519Backend) message(alpha []byte) []byte {
+	msg := make([]byte, 0, len(ed25519Domain)+len(alpha))
+	msg = append(msg, ed25519Domain...)
+	return append(msg, alpha...)
+}
+
+func (e *Ed25519Backend) hash(pi []byte) []byte {
+	h := sha256.Sum256(pi)
+	return h[:]
+}
@@ -0,0 +1,41 @@
+package vrf
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var (
+	ErrInvalidProof = errors.New("vrf proof verification failed")
+	ErrInvalidKey   = errors.New("vrf key has invalid length")
+)
+
+// Backend 可插拔的VRF实现
+type Backend interface {
+	// Name 返回后端名称，写入选举结果便于验证方选择相同实现
+	Name() string
+	// Prove 使用私钥对输入alpha计算VRF输出beta及证明pi
+	Prove(privateKey, alpha []byte) (beta, pi []byte, err error)
+	// Verify 使用公钥验证beta与pi是否为alpha的合法VRF输出
+	Verify(publicKey, alpha, beta, pi []byte) error
+}
+
+// Uniform 将VRF输出映射到(0,1)区间的均匀分布
+func Uniform(beta []byte) float64 {
+	if len(beta) < 8 {
+		return 0.5
+	}
+	// 取前53位作为浮点尾数，避免精度丢失
+	v := binary.BigEndian.Uint64(beta[:8]) >> 11
+	return (float64(v) + 0.5) / float64(uint64(1)<<53)
+}
+
+// Ticket 根据VRF输出和权重计算抽签值，值越小越优先
+// 使用指数竞争: -ln(u)/w，获胜概率与权重成正比
+func Ticket(beta []byte, weight float64) float64 {
+	if weight <= 0 {
+		return math.Inf(1)
+	}
+	return -math.Log(Uniform(beta)) / weight
+}
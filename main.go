@@ -1,14 +1,31 @@
 package main
 
 import (
+	"blockchain/api/handler"
+	"blockchain/api/middleware"
+	"blockchain/api/router"
+	"blockchain/api/rpc"
+	"blockchain/api/stream"
 	"blockchain/global"
+	"blockchain/internal/archive"
 	bc "blockchain/internal/blockchain"
 	"blockchain/internal/consensus"
-	"blockchain/internal/hash"
+	"blockchain/internal/events"
 	"blockchain/internal/network"
+	"blockchain/internal/security"
+	"blockchain/internal/storage"
+	"blockchain/pkg/config"
+	"blockchain/service"
+	"blockchain/web"
+	"context"
 	"fmt"
 	"log"
 	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 )
 
@@ -20,6 +37,51 @@ func main() {
 
 	// 创建区块链
 	blockchain := bc.NewBlockchain()
+	storage.SetCanonicalChain(blockchain)
+	if config.SeedArchivePath != "" {
+		seedChain(blockchain, config.SeedArchivePath)
+	}
+
+	// 启动网络层，节点通过种子节点和地址交换发现对端
+	stopNet := make(chan struct{})
+
+	// 环成员变化时迁移区块副本
+	rebalancer := storage.NewRebalancer()
+	rebalancer.Start(stopNet)
+	rebalancer.StartReweight(stopNet)
+	// 周期性补齐缺失副本、清理多余副本
+	antiEntropy := storage.NewAntiEntropy(rebalancer)
+	antiEntropy.Start(stopNet)
+	// 锚节点周期性挑战节点，确认其确实保存了分配的区块
+	challenger := storage.NewChallenger(func() string {
+		if global.AnchorNode == nil {
+			return ""
+		}
+		return global.AnchorNode.ID
+	})
+	challenger.Start(stopNet)
+	// 锚节点管理器保证同一时间只有一个锚节点监听器，节点加入、下线或排空时重新选举
+	// 已连接的对端经网络层接收分配，进程内节点经分配通道
+	sender := network.RoutedAssignSender{
+		Host:  func() *network.Host { return global.Host },
+		Local: network.ChanAssignSender(global.BlockAssignChan),
+		Sent:  publishAssignment,
+	}
+	anchors := consensus.NewAnchorManager(consensus.NewConfiguredRaft(), sender)
+	nodeController := handler.NewNodeController(rebalancer, anchors)
+
+	initializeNetwork(blockchain, nodeController, stopNet)
+	if global.Syncer != nil {
+		// 先追赶对端的链再挖矿，同步落后时暂停出块
+		if err := global.Syncer.CatchUp(config.InitialSyncWait); err != nil {
+			log.Printf("[同步] 启动追赶失败: %v", err)
+		}
+		blockchain.CanMine = func() bool { return !global.Syncer.Status().Syncing }
+	}
+	// 事件总线接在广播器之后，订阅接口推送新区块、交易和节点变化
+	global.Events = events.NewBus()
+	global.Events.AttachChain(blockchain)
+	service.WatchNodes(global.Events, config.NodeWatchInterval, stopNet)
 
 	stopTG := make(chan struct{})
 	stopM := make(chan struct{})
@@ -34,8 +96,8 @@ func main() {
 	close(stopTG)
 	log.Println("[交易生成器] 停止交易生成")
 
-	for len(blockchain.PendingTx) >= 3 {
-		log.Printf("等待矿工处理剩余交易: %d 笔...\n", len(blockchain.PendingTx))
+	for blockchain.PendingCount() >= config.MinTxToMine {
+		log.Printf("等待矿工处理剩余交易: %d 笔...\n", blockchain.PendingCount())
 		time.Sleep(2 * time.Second) // 每隔 2 秒检查一次
 	}
 
@@ -47,57 +109,211 @@ func main() {
 	fmt.Println("\n区块链状态:")
 	blockchain.PrintBlockchain()
 
-	//nodeController := handler.NewNodeController()
-
-	//log.Println("服务启动: http://localhost:8080")
-
 	// 创建初始节点并启动锚节点监听器
-	initializeNodes()
+	initializeNodes(anchors)
 
-	//http.HandleFunc("/add", nodeController.HandleAddNode)
-	//http.HandleFunc("/store", storage.HandleStoreData)
-	//http.HandleFunc("/list", nodeController.HandleListNodes)
-	//http.HandleFunc("/query", nodeController.HandleQueryNode)
-	//
-	//web.StartWebServer()
+	web.StartWebServer()
 
-	//_ = http.ListenAndServe(":8080", nil)
+	// 启动/api/v1接口，页面和接口共用默认路由
+	chainService := service.NewChainService(blockchain)
+	api := router.New(chainService)
+	// 所有路由统一经过请求ID、访问日志、异常恢复，以及按路由配置的认证、角色和限流
+	auth, err := middleware.NewAuthenticator(config.APIKeysFile, config.JWTSecretFile)
+	if err != nil {
+		log.Fatalf("[API] 加载API密钥失败: %v", err)
+	}
+	policy, err := middleware.NewPolicy(auth, middleware.DefaultRules)
+	if err != nil {
+		log.Fatalf("[API] 加载访问策略失败: %v", err)
+	}
+	rpcServer := rpc.NewServer(chainService)
+	// 每次方法调用和每条WebSocket消息都单独检查角色并计入限流
+	rpcServer.SetAuthorizer(policy.RPCAuthorizer(middleware.RPCMethodRoutes))
+	rpcServer.SetMessageLimiter(policy.MessageLimiter("POST " + router.Prefix + "/rpc"))
+	api.Handle("POST /rpc", rpcServer)
+	api.Handle("GET /rpc", rpcServer) // WebSocket
+	// 节点管理: 添加、下线、排空、修改属性、重新评分和查看节点保存的区块
+	api.Handle("POST /nodes", http.HandlerFunc(nodeController.HandleAddNode))
+	api.Handle("DELETE /nodes/{id}", http.HandlerFunc(nodeController.HandleRemoveNode))
+	api.Handle("PATCH /nodes/{id}", http.HandlerFunc(nodeController.HandleUpdateNode))
+	api.Handle("POST /nodes/{id}/drain", http.HandlerFunc(nodeController.HandleDrainNode))
+	api.Handle("DELETE /nodes/{id}/drain", http.HandlerFunc(nodeController.HandleUndrainNode))
+	api.Handle("POST /nodes/{id}/rescore", http.HandlerFunc(nodeController.HandleRescoreNode))
+	api.Handle("GET /nodes/{id}/blocks", http.HandlerFunc(nodeController.HandleNodeBlocks))
+	api.Handle("GET /anchor/election", http.HandlerFunc(nodeController.HandleElection))
+	// 从副本读取并校验区块、查看区块的副本位置
+	blockController := handler.NewBlockController()
+	api.Handle("GET /storage/blocks/{hash}", http.HandlerFunc(blockController.HandleFetchBlock))
+	api.Handle("GET /storage/blocks/{hash}/replicas", http.HandlerFunc(blockController.HandleBlockReplicas))
+	// 导出和导入链归档
+	archiveController := handler.NewArchiveController(blockchain)
+	api.Handle("GET /chain/export", http.HandlerFunc(archiveController.HandleExport))
+	api.Handle("POST /chain/import", http.HandlerFunc(archiveController.HandleImport))
+	api.Handle("GET /rebalance", http.HandlerFunc(handler.NewRebalanceController(rebalancer).HandleRebalanceStatus))
+	api.Handle("GET /repair", http.HandlerFunc(handler.NewRepairController(antiEntropy, challenger).HandleRepairStatus))
+	streamHandler := stream.New(chainService, global.Events)
+	api.Handle("GET /subscribe", streamHandler)
+	api.Handle("GET /sync", http.HandlerFunc(handler.NewSyncController(global.Syncer).HandleSyncStatus))
+	http.Handle(router.Prefix+"/", api)
+	handler := middleware.Chain(http.DefaultServeMux,
+		middleware.RequestID, middleware.AccessLog, middleware.Recover, policy.Handler)
+	server := router.NewServer(config.APIAddr, handler)
+	server.OnShutdown(streamHandler.Close)
+	if err := server.Start(); err != nil {
+		log.Fatalf("[API] 启动失败: %v", err)
+	}
+	log.Printf("[API] 服务启动: %s%s", server.Addr(), router.Prefix)
 
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
 	for {
-		time.Sleep(10 * time.Second) // 模拟持续运行
-		log.Println("系统运行中...")
+		select {
+		case <-ticker.C:
+			log.Println("系统运行中...")
+		case sig := <-sigCh:
+			log.Printf("收到信号 %v，开始关闭", sig)
+			ctx, cancel := context.WithTimeout(context.Background(), config.APIShutdownTimeout)
+			if err := server.Shutdown(ctx); err != nil {
+				log.Printf("[API] 关闭超时: %v", err)
+			}
+			cancel()
+			close(stopNet)
+			return
+		}
+	}
+}
+
+// seedChain 从归档导入链，失败时保留已成功导入的区块
+func seedChain(chain *bc.Blockchain, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("[初始化] 打开归档失败: %v", err)
+		return
+	}
+	defer f.Close()
+	result, err := archive.Import(f, chain, archive.ImportOptions{})
+	if err != nil {
+		log.Printf("[初始化] 导入归档失败，当前高度 %d: %v", chain.Height(), err)
+		return
 	}
+	log.Printf("[初始化] 已从归档导入 %d 个区块，当前高度 %d", result.Imported, result.Height)
 }
 
 // initializeNodes 初始化节点并启动锚节点监听器
-func initializeNodes() {
+func initializeNodes(anchors *consensus.AnchorManager) {
 	// 创建初始节点
 	//initialNodes := []string{"node1", "node2", "node3", "node4", "node5", "node6", "node7", "node8", "node9", "node10"}
 
 	initialNodes := []string{"node1", "node2", "node3", "node4", "node5"}
-	for _, nodeID := range initialNodes {
-		node := network.NewNode(nodeID)
+	for _, name := range initialNodes {
+		// 节点ID由持久化的身份公钥派生，重启后保持不变
+		identity, err := network.LoadOrCreateIdentity(filepath.Join(config.KeyDir, name+".key"))
+		if err != nil {
+			log.Printf("[初始化] 加载节点 %s 身份失败: %v", name, err)
+			continue
+		}
+		node := network.NewNode(identity)
 		node.CalculateScore(node)
 		log.Printf("[初始化] 创建节点: %s, 节点信息：cpu: %f, memory: %f, disk: %f, bindwitdth: %f\n", node.ID, node.CPU, node.Memory, node.Disk, node.Bandwidth)
-		// 添加到全局节点映射
-		global.NodesMap[nodeID] = node
-		hash.AddNode(nodeID)
-		// 启动每个节点监听分配通道
-		go node.ListenBlockAssign(global.BlockAssignChan)
+		// 添加到全局节点映射和一致性哈希环
+		if err := service.RegisterNode(node); err != nil {
+			log.Printf("[初始化] 登记节点 %s 失败: %v", node.ID, err)
+			continue
+		}
 	}
 
-	// 创建Raft实例并选举锚节点
-	rf := consensus.NewRaft()
-	var nodes []*network.Node
-	for _, node := range global.NodesMap {
-		nodes = append(nodes, node)
-	}
+	// 多个副本的分配信息共用一个通道，由分发协程按目标节点投递，避免被其他节点的监听协程取走
+	go dispatchBlockAssign(global.BlockAssignChan)
 
-	anchor := rf.ElectAnchor(nodes)
-	if anchor != nil {
+	// 在全部已登记节点中选举锚节点
+	if anchor := anchors.Elect(); anchor != nil {
 		log.Printf("[初始化] 锚节点 %s 已选举，开始监听区块池", anchor.ID)
-		rf.StartAnchorListener(anchor.ID, global.BlockAssignChan)
+	}
+}
+
+// dispatchBlockAssign 将分配通道中的信息投递给进程内的目标节点；
+// 远端节点的记录只有公钥，未连接时分配无法送达，丢弃并记录日志
+func dispatchBlockAssign(assignChan chan network.BlockAssignInfo) {
+	for info := range assignChan {
+		node := service.GetNodeByID(info.TargetNodeID)
+		if node == nil {
+			continue
+		}
+		if !node.CanSign() {
+			log.Printf("[锚节点分发] 远端节点 %s 未连接，区块 %d 的分配未送达", info.TargetNodeID, info.Block.Index)
+			continue
+		}
+		node.HandleBlockAssign(info)
+		publishAssignment(info)
+	}
+}
+
+// publishAssignment 向事件总线发布区块分配事件
+func publishAssignment(info network.BlockAssignInfo) {
+	if global.Events != nil {
+		global.Events.PublishAssignment(info)
+	}
+}
+
+// initializeNetwork 启动本节点的网络层、交易区块广播、链同步和节点发现
+func initializeNetwork(chain *bc.Blockchain, nodeController *handler.NodeController, stop <-chan struct{}) {
+	identity, err := network.LoadOrCreateIdentity(config.NodeKeyPath)
+	if err != nil {
+		log.Printf("[初始化] 加载节点身份失败: %v", err)
+		return
+	}
+	local := network.NewNode(identity)
+	cfg := network.DefaultHostConfig()
+	cfg.Height = chain.Height
+
+	var transport network.Transport = network.NewTCPTransport()
+	if config.TLSEnabled {
+		tlsTransport, err := newTLSTransport(stop)
+		if err != nil {
+			log.Printf("[初始化] 加载TLS证书失败: %v", err)
+			return
+		}
+		transport = tlsTransport
+	}
+
+	host := network.NewHost(local, transport, cfg)
+	if err := host.Start(config.DefaultP2PAddr); err != nil {
+		log.Printf("[初始化] 网络层启动失败: %v", err)
+		return
+	}
+
+	network.NewGossiper(host, chain).Attach(chain)
+	syncer := network.NewSyncer(host, chain)
+	syncer.Start(stop)
+
+	book := network.NewAddrBook(config.AddrBookPath)
+	if err := book.Load(); err != nil {
+		log.Printf("[初始化] 加载地址簿失败: %v", err)
+	}
+	discovery := network.NewDiscovery(host, book, config.SeedNodes)
+	host.OnPeer(nodeController.RegisterPeer, nil)
+	discovery.Start(stop)
+
+	global.Host = host
+	global.Syncer = syncer
+	global.Discovery = discovery
+}
+
+// newTLSTransport 加载双向TLS证书，并定期重新读取吊销名单
+func newTLSTransport(stop <-chan struct{}) (*network.TLSTransport, error) {
+	tlsConfig := security.TLSConfig{
+		CAFile:       config.TLSCAFile,
+		CertFile:     config.TLSCertFile,
+		KeyFile:      config.TLSKeyFile,
+		DenyListFile: config.TLSDenyList,
+	}
+	server, client, deny, err := tlsConfig.Load()
+	if err != nil {
+		return nil, err
 	}
 
-	global.AnchorNode = anchor
+	deny.Watch(config.TLSDenyListReload, stop)
+	return network.NewTLSTransport(server, client), nil
 }
@@ -0,0 +1,47 @@
+package config
+
+import "time"
+
+const (
+	APIAddr            = ":8080"          // HTTP API监听地址
+	APIPageSize        = 20               // 列表接口默认每页条数
+	APIMaxPageSize     = 100              // 列表接口每页最大条数
+	APIMaxBodyBytes    = 1 << 20          // 请求体最大字节数
+	APIReadTimeout     = 15 * time.Second // 读取请求超时时间
+	APIWriteTimeout    = 30 * time.Second // 写响应超时时间
+	APIShutdownTimeout = 10 * time.Second // 优雅关闭时等待进行中请求的最长时间
+)
+
+const (
+	ArchiveMaxBytes = 8 << 30          // 导入链归档的请求体最大字节数
+	ArchiveTimeout  = 30 * time.Minute // 导出和导入链归档的读写超时，替代服务器默认的读写超时
+)
+
+const (
+	RPCMaxBatch   = 100              // JSON-RPC批量请求最多包含的请求数
+	WSIdleTimeout = 60 * time.Second // WebSocket连接无消息超过该时间后断开
+)
+
+const (
+	EventBufferSize   = 256              // 每个订阅未消费事件的上限，超过后断开该订阅
+	StreamKeepAlive   = 15 * time.Second // 订阅连接的保活间隔
+	MaxReplayBlocks   = 1000             // 断线重连时最多补发的区块数
+	NodeWatchInterval = 2 * time.Second  // 检查节点得分、健康状态和锚节点变化的间隔
+)
+
+const (
+	APIKeysFile      = "data/api/keys.list"  // API密钥文件，每行 "<名称> <角色> <密钥SHA-256>"
+	JWTSecretFile    = "data/api/jwt.secret" // JWT HS256签名密钥，文件不存在时不接受JWT
+	JWTIssuer        = "blockchain"          // 签发和校验JWT的iss
+	JWTLeeway        = 30 * time.Second      // 校验exp和nbf时允许的时钟偏差
+	APIAnonymousRead = true                  // 未认证的请求是否按只读角色处理
+	APITrustProxy    = false                 // 是否按X-Forwarded-For识别客户端IP，仅在反向代理后开启
+)
+
+const (
+	RateLimitPerIP  = 20.0             // 每个IP每秒请求数
+	RateBurstPerIP  = 40               // 每个IP的突发请求数
+	RateLimitPerKey = 50.0             // 每个API密钥或JWT主体每秒请求数
+	RateBurstPerKey = 100              // 每个API密钥或JWT主体的突发请求数
+	RateLimitIdle   = 10 * time.Minute // 令牌桶闲置超过该时间后回收
+)
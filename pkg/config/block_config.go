@@ -4,6 +4,65 @@ import "time"
 
 const (
 	MinTxToMine     = 3               // 最小交易数，挖矿时需要至少3笔交易
+	MaxTxPerBlock   = 3               // 每个区块最多打包的交易数，不含奖励交易
 	TxGenInterval   = 1 * time.Second // 交易生成间隔
 	MinerCheckDelay = 2 * time.Second // 矿工检查间隔
+
+	GenesisTimestamp = 1700000000 // 创世区块固定时间戳，保证各节点创世哈希一致
+
+	ReplicationFactor = 3  // 每个区块保存的副本数
+	LocalZone         = "" // 本进程节点所在故障域
+
+	NodeQuotaBytes = 1 << 30    // 每个节点默认存储配额(字节)，0表示不限
+	ArchivalLabel  = "archival" // 节点标签为"true"时作为归档节点，不淘汰冷数据
+	EvictionPolicy = "lru"      // 非归档节点配额不足时的淘汰策略: lru / oldest / none
+
+	StorageCodec        = "flate"     // 区块段压缩方式: none / flate / gzip
+	StorageMode         = "replicate" // 区块存储方式: replicate 完整副本 / erasure 纠删码
+	ErasureDataShards   = 4           // 纠删码数据分片数k
+	ErasureParityShards = 2           // 纠删码校验分片数m
+)
+
+const (
+	DefaultVirtualNodes = 20    // 默认虚拟节点数
+	MinVirtualNodes     = 4     // 单个节点最少虚拟节点数
+	MaxVirtualNodes     = 400   // 单个节点最多虚拟节点数
+	ReferenceDiskGB     = 100.0 // 可用磁盘为该值时虚拟节点数为默认值
+	LoadBoundEpsilon    = 0.25  // 有界负载系数ε，节点负载不超过(1+ε)倍按权重的平均值
+)
+
+const (
+	RingHashFunc = "crc32" // 一致性哈希环使用的哈希函数，可选 crc32 / fnv1a
+
+	RebalanceRate       = 20               // 数据迁移限速，每秒最多迁移的区块副本数
+	AntiEntropyInterval = 30 * time.Second // 反熵检查周期
+
+	RingWeightInterval   = time.Minute // 按评分和磁盘重新计算虚拟节点数的周期
+	RingWeightHysteresis = 0.2         // 新权重与环上权重相差超过该比例时才调整，避免评分小幅波动引起迁移
+
+	ChallengeInterval    = 20 * time.Second // 锚节点发起存储证明挑战的周期
+	ChallengeChunkSize   = 256              // 挑战的字节范围(Merkle叶子)大小
+	ChallengePenalty     = 5.0              // 挑战失败扣减的贡献值，与保存副本的奖励相同
+	MaxChallengeFailures = 3                // 连续失败达到该次数的节点被标记为不健康
+)
+
+// NodeQuotas 按节点ID覆盖默认存储配额(字节)
+var NodeQuotas = map[string]int64{}
+
+const (
+	PruningEnabled    = false // 非归档节点开启裁剪，只保留最近的区块体
+	PruneKeepBlocks   = 100   // 裁剪模式下保留区块体的数量
+	SnapshotInterval  = 50    // 每隔多少个区块生成一次状态快照
+	SnapshotsRetained = 2     // 保留的状态快照数量
+)
+
+// SeedArchivePath 非空时节点启动前从该归档导入链，用于从生产快照初始化测试环境
+const SeedArchivePath = ""
+
+// AnchorElection 锚节点选举方式: score 按节点得分排序 / vrf 按本地记账的贡献值加权VRF抽签
+const AnchorElection = "score"
+
+const (
+	HealthHistorySize    = 60 // 每个节点保留的健康记录条数
+	DecommissionAttempts = 3  // 下线节点时迁移副本的最大尝试轮数
 )
@@ -0,0 +1,54 @@
+package config
+
+import "time"
+
+const (
+	ChainID          = "block_chain-dev" // 链标识，握手时不一致的节点拒绝连接
+	ProtocolVersion  = 1                 // 节点间消息协议版本
+	MaxPeers         = 16                // 单个节点最大连接数
+	MaxFrameSize     = 16<<20 - 1        // 单条消息最大字节数，帧头长度只占低24位
+	PingInterval     = 15 * time.Second  // 心跳间隔
+	PeerTimeout      = 45 * time.Second  // 超过该时间未收到消息则断开
+	HandshakeTimeout = 5 * time.Second   // 握手超时时间
+	DialTimeout      = 5 * time.Second   // 建立TCP连接超时时间
+	DefaultP2PAddr   = "127.0.0.1:0"     // 默认监听地址，端口由系统分配
+)
+
+const (
+	GossipFanout      = 8                // 每次转发的最大对端数
+	SeenCacheSize     = 10000            // 已见消息缓存容量
+	SeenCacheTTL      = 10 * time.Minute // 已见消息保留时间
+	GetDataRetryDelay = 5 * time.Second  // 同一条目重复请求的最小间隔
+)
+
+const (
+	SyncInterval       = 10 * time.Second // 检查是否需要同步的间隔
+	InitialSyncWait    = 5 * time.Second  // 启动时等待第一个对端连接后再追赶链，超时则从本地链开始挖矿
+	SyncRequestTimeout = 10 * time.Second // 单次同步请求超时时间
+	HeaderBatchSize    = 500              // 单次请求的区块头数量
+	BodyWindowSize     = 16               // 单个下载窗口的区块体数量
+	BodyBatchSize      = 4                // 单次向一个对端请求的区块体数量
+	MaxReorgDepth      = 100              // 切换到更长分叉链时最多回滚的本地区块数，更深的分叉只报告分歧
+)
+
+const (
+	TargetPeers       = 8                    // 节点期望维持的连接数
+	DiscoveryInterval = 30 * time.Second     // 检查连接数并补充拨号的间隔
+	AddrBookPath      = "data/addrbook.json" // 地址簿持久化路径
+	MaxAddrPerMessage = 100                  // 单条addr消息最多携带的地址数
+	MaxAddrFailures   = 10                   // 非种子地址连续失败超过该次数后移出地址簿
+	MaxDialBackoff    = 1 * time.Hour        // 连续失败后的最长重试间隔
+	BaseDialBackoff   = 10 * time.Second     // 首次失败后的重试间隔
+	NodeKeyPath       = "data/node.key"      // 本进程节点身份私钥
+	KeyDir            = "data/keys"          // 模拟节点的身份私钥目录
+)
+
+// SeedNodes 种子节点地址，新节点启动时首先连接这些地址获取更多对端
+var SeedNodes = []string{}
+
+const (
+	CompressMinSize = 512 // 消息体超过该字节数才压缩
+)
+
+// WireCodecs 本节点支持的连接压缩方式，按偏好排序，握手时与对端协商
+var WireCodecs = []string{"flate", "gzip"}
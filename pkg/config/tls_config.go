@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+const (
+	TLSEnabled   = false                  // 是否启用节点间和API的双向TLS
+	TLSCAFile    = "data/certs/ca.pem"    // CA证书
+	TLSCertFile  = "data/certs/node.pem"  // 本节点证书
+	TLSKeyFile   = "data/certs/node.key"  // 本节点证书私钥
+	TLSDenyList  = "data/certs/deny.list" // 吊销名单
+	CertValidity = 365 * 24 * 3600        // 证书默认有效期(秒)
+
+	TLSDenyListReload = time.Minute // 节点间和API服务器重新读取吊销名单的周期
+)
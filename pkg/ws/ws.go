@@ -0,0 +1,401 @@
+// Package ws 实现RFC 6455 WebSocket协议中API需要的部分:
+// 服务端握手升级、客户端拨号、文本/二进制消息、分片重组以及ping/pong/close控制帧
+package ws
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 帧操作码
+const (
+	OpContinuation = 0x0
+	OpText         = 0x1
+	OpBinary       = 0x2
+	OpClose        = 0x8
+	OpPing         = 0x9
+	OpPong         = 0xA
+)
+
+// 关闭状态码
+const (
+	CloseNormal        = 1000
+	CloseGoingAway     = 1001
+	CloseProtocolError = 1002
+	CloseTooLarge      = 1009
+	ClosePolicy        = 1008
+)
+
+// acceptGUID 握手时与客户端密钥拼接后计算Sec-WebSocket-Accept
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// DefaultReadLimit 单条消息默认最大字节数
+const DefaultReadLimit = 1 << 20
+
+var (
+	ErrBadHandshake = errors.New("websocket: bad handshake")
+	ErrClosed       = errors.New("websocket: connection closed")
+	ErrTooLarge     = errors.New("websocket: message too large")
+	ErrProtocol     = errors.New("websocket: protocol error")
+)
+
+// Conn WebSocket连接，读操作只允许一个协程调用，写操作可并发
+type Conn struct {
+	conn      net.Conn
+	br        *bufio.Reader
+	client    bool // 客户端发出的帧必须加掩码
+	readLimit int64
+	idle      time.Duration // 大于0时每读一帧前刷新读超时，ping帧也算作活动
+	writeWait time.Duration // 大于0时每写一帧前设置写超时
+
+	writeMu sync.Mutex
+	closed  bool
+}
+
+// IsUpgrade 判断请求是否为WebSocket升级请求
+func IsUpgrade(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") && headerContains(r.Header, "Upgrade", "websocket")
+}
+
+// Upgrade 完成服务端握手并接管底层连接
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet || !IsUpgrade(r) {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, ErrBadHandshake
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, ErrBadHandshake
+	}
+	netConn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// 升级后不再受HTTP服务器读写超时约束
+	netConn.SetDeadline(time.Time{})
+
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := netConn.Write([]byte(resp)); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return &Conn{conn: netConn, br: rw.Reader, readLimit: DefaultReadLimit}, nil
+}
+
+// Dial 连接ws://地址，供工具和测试使用
+func Dial(rawURL string, header http.Header) (*Conn, error) {
+	hostPath := strings.TrimPrefix(rawURL, "ws://")
+	if hostPath == rawURL {
+		return nil, fmt.Errorf("websocket: unsupported url %s", rawURL)
+	}
+	host, _, _ := strings.Cut(hostPath, "/")
+	u, err := url.Parse("http://" + hostPath)
+	if err != nil {
+		return nil, err
+	}
+	netConn, err := net.DialTimeout("tcp", host, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Host:       host,
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	if err := req.Write(netConn); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		netConn.Close()
+		return nil, fmt.Errorf("%w: status %s", ErrBadHandshake, resp.Status)
+	}
+	return &Conn{conn: netConn, br: br, client: true, readLimit: DefaultReadLimit}, nil
+}
+
+// SetReadLimit 设置单条消息最大字节数
+func (c *Conn) SetReadLimit(n int64) {
+	c.readLimit = n
+}
+
+// SetIdleTimeout 设置空闲超时，超过d未收到任何帧时读操作返回错误
+func (c *Conn) SetIdleTimeout(d time.Duration) {
+	c.idle = d
+}
+
+// SetWriteTimeout 设置单帧写超时，对端长时间不读时写操作返回错误
+func (c *Conn) SetWriteTimeout(d time.Duration) {
+	c.writeWait = d
+}
+
+// RemoteAddr 返回对端地址
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// ReadMessage 读取一条完整的数据消息，自动应答ping并处理close，对端关闭时返回ErrClosed
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	var (
+		op  int
+		msg []byte
+	)
+	for {
+		fin, frameOp, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch frameOp {
+		case OpPing:
+			if err := c.writeFrame(OpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case OpPong:
+			continue
+		case OpClose:
+			code := CloseNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			c.CloseWithCode(code, "")
+			return 0, nil, ErrClosed
+		case OpText, OpBinary:
+			if msg != nil {
+				return 0, nil, c.fail(CloseProtocolError, ErrProtocol)
+			}
+			op = frameOp
+			msg = payload
+		case OpContinuation:
+			if msg == nil {
+				return 0, nil, c.fail(CloseProtocolError, ErrProtocol)
+			}
+			msg = append(msg, payload...)
+		default:
+			return 0, nil, c.fail(CloseProtocolError, ErrProtocol)
+		}
+		if int64(len(msg)) > c.readLimit {
+			return 0, nil, c.fail(CloseTooLarge, ErrTooLarge)
+		}
+		if fin {
+			if msg == nil {
+				msg = []byte{}
+			}
+			return op, msg, nil
+		}
+	}
+}
+
+// WriteMessage 以单帧发送一条消息
+func (c *Conn) WriteMessage(op int, data []byte) error {
+	return c.writeFrame(op, data)
+}
+
+// Ping 发送ping帧，用于保活
+func (c *Conn) Ping() error {
+	return c.writeFrame(OpPing, nil)
+}
+
+// Close 以正常状态码关闭连接
+func (c *Conn) Close() error {
+	return c.CloseWithCode(CloseNormal, "")
+}
+
+// CloseWithCode 发送close帧后关闭底层连接，重复调用无副作用
+func (c *Conn) CloseWithCode(code int, reason string) error {
+	payload := make([]byte, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	copy(payload[2:], reason)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	c.writeFrameLocked(OpClose, payload)
+	return c.conn.Close()
+}
+
+func (c *Conn) fail(code int, err error) error {
+	c.CloseWithCode(code, "")
+	return err
+}
+
+// readFrame 读取一帧: 2字节头 + 扩展长度 + 可选的4字节掩码 + 负载
+func (c *Conn) readFrame() (bool, int, []byte, error) {
+	if c.idle > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.idle))
+	}
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, c.readErr(err)
+	}
+	fin := head[0]&0x80 != 0
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, ErrProtocol) // 未协商扩展，RSV位必须为0
+	}
+	op := int(head[0] & 0x0F)
+	masked := head[1]&0x80 != 0
+	if masked == c.client {
+		// 客户端发来的帧必须加掩码，服务端发来的帧不能加掩码
+		return false, 0, nil, c.fail(CloseProtocolError, ErrProtocol)
+	}
+
+	length := int64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, c.readErr(err)
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, c.readErr(err)
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if op >= OpClose && (length > 125 || !fin) {
+		return false, 0, nil, c.fail(CloseProtocolError, ErrProtocol)
+	}
+	if length < 0 || length > c.readLimit {
+		return false, 0, nil, c.fail(CloseTooLarge, ErrTooLarge)
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return false, 0, nil, c.readErr(err)
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, c.readErr(err)
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+func (c *Conn) readErr(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF || errors.Is(err, net.ErrClosed) {
+		return ErrClosed
+	}
+	return err
+}
+
+func (c *Conn) writeFrame(op int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	return c.writeFrameLocked(op, data)
+}
+
+func (c *Conn) writeFrameLocked(op int, data []byte) error {
+	frame := make([]byte, 0, 14+len(data))
+	frame = append(frame, 0x80|byte(op))
+	maskBit := byte(0)
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(data); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	if c.client {
+		var mask [4]byte
+		rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, data...)
+		for i := start; i < len(frame); i++ {
+			frame[i] ^= mask[(i-start)%4]
+		}
+	} else {
+		frame = append(frame, data...)
+	}
+	if c.writeWait > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.writeWait))
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
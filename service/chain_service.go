@@ -0,0 +1,272 @@
+package service
+
+import (
+	"blockchain/global"
+	bc "blockchain/internal/blockchain"
+	"blockchain/internal/hash"
+	"blockchain/internal/network"
+	"blockchain/pkg/config"
+	"errors"
+	"fmt"
+)
+
+// 服务层错误分类，REST和RPC接口据此映射状态码或错误码
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrInvalidArgument = errors.New("invalid argument")
+	ErrConflict        = errors.New("conflict")
+	ErrPruned          = errors.New("block body has been pruned")
+	ErrUnavailable     = errors.New("service unavailable")
+)
+
+// Page 分页参数，Offset从最新的记录开始计数
+type Page struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// normalize 补全默认每页条数并限制最大值
+func (p Page) normalize() Page {
+	if p.Offset < 0 {
+		p.Offset = 0
+	}
+	if p.Limit <= 0 {
+		p.Limit = config.APIPageSize
+	}
+	if p.Limit > config.APIMaxPageSize {
+		p.Limit = config.APIMaxPageSize
+	}
+	return p
+}
+
+// slice 返回[offset, offset+limit)与[0, total)的交集
+func (p Page) slice(total int) (int, int) {
+	start := p.Offset
+	if start > total {
+		start = total
+	}
+	end := start + p.Limit
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+// ChainInfo 链概况
+type ChainInfo struct {
+	ChainID     string  `json:"chainId"`
+	Height      int     `json:"height"`
+	TipHash     string  `json:"tipHash"`
+	GenesisHash string  `json:"genesisHash"`
+	StateRoot   string  `json:"stateRoot"`
+	Difficulty  int     `json:"difficulty"`
+	Reward      float64 `json:"reward"`
+	PendingTxs  int     `json:"pendingTxs"`
+	PrunedBelow int     `json:"prunedBelow,omitempty"`
+	Syncing     bool    `json:"syncing"`
+}
+
+// BlockPage 区块头分页结果，按高度从新到旧
+type BlockPage struct {
+	Page
+	Total  int              `json:"total"`
+	Blocks []bc.BlockHeader `json:"blocks"`
+}
+
+// TxPage 交易分页结果
+type TxPage struct {
+	Page
+	Total        int            `json:"total"`
+	Transactions []bc.LocatedTx `json:"transactions"`
+}
+
+// TxStatus 交易及其状态: pending表示在交易池中，confirmed表示已上链
+type TxStatus struct {
+	bc.LocatedTx
+	Status string `json:"status"`
+}
+
+// RingInfo 一致性哈希环的成员和快照
+type RingInfo struct {
+	Members  []string      `json:"members"`
+	Snapshot hash.Snapshot `json:"snapshot"`
+}
+
+// ChainService 链查询和交易提交，REST和RPC接口共用
+type ChainService struct {
+	chain *bc.Blockchain
+}
+
+// NewChainService 创建链服务
+func NewChainService(chain *bc.Blockchain) *ChainService {
+	return &ChainService{chain: chain}
+}
+
+// Chain 返回服务使用的链
+func (s *ChainService) Chain() *bc.Blockchain {
+	return s.chain
+}
+
+// Info 返回链概况
+func (s *ChainService) Info() ChainInfo {
+	tip := s.chain.LastBlock()
+	genesis, _ := s.chain.GetBlockByIndex(0)
+	info := ChainInfo{
+		ChainID:     config.ChainID,
+		Height:      tip.Index,
+		TipHash:     tip.Hash,
+		GenesisHash: genesis.Hash,
+		StateRoot:   s.chain.State().Root(),
+		Difficulty:  s.chain.Difficulty,
+		Reward:      s.chain.Reward,
+		PendingTxs:  len(s.chain.PendingTransactions()),
+	}
+	if below := s.chain.PrunedBelow(); below > 1 {
+		info.PrunedBelow = below
+	}
+	if global.Syncer != nil {
+		info.Syncing = global.Syncer.Status().Syncing
+	}
+	return info
+}
+
+// Blocks 分页返回区块头，最新的区块在前
+func (s *ChainService) Blocks(p Page) BlockPage {
+	p = p.normalize()
+	height := s.chain.Height()
+	total := height + 1
+	start, end := p.slice(total)
+	page := BlockPage{Page: p, Total: total, Blocks: []bc.BlockHeader{}}
+	for i := start; i < end; i++ {
+		if h := s.chain.GetHeaders(height-i, 1); len(h) == 1 {
+			page.Blocks = append(page.Blocks, h[0])
+		}
+	}
+	return page
+}
+
+// BlockByHeight 按高度返回完整区块
+func (s *ChainService) BlockByHeight(height int) (bc.Block, error) {
+	if height < 0 || height > s.chain.Height() {
+		return bc.Block{}, fmt.Errorf("%w: block at height %d", ErrNotFound, height)
+	}
+	block, ok := s.chain.GetBlockByIndex(height)
+	if !ok {
+		return bc.Block{}, fmt.Errorf("%w: height %d", ErrPruned, height)
+	}
+	return block, nil
+}
+
+// BlockByHash 按哈希返回完整区块
+func (s *ChainService) BlockByHash(blockHash string) (bc.Block, error) {
+	if block, ok := s.chain.GetBlockByHash(blockHash); ok {
+		return block, nil
+	}
+	if s.chain.HasBlock(blockHash) {
+		return bc.Block{}, fmt.Errorf("%w: %s", ErrPruned, blockHash)
+	}
+	return bc.Block{}, fmt.Errorf("%w: block %s", ErrNotFound, blockHash)
+}
+
+// Transaction 按ID查找交易，先查交易池再查链
+func (s *ChainService) Transaction(id string) (TxStatus, error) {
+	if tx, ok := s.chain.GetTransaction(id); ok {
+		return TxStatus{LocatedTx: bc.LocatedTx{Transaction: tx}, Status: "pending"}, nil
+	}
+	if tx, ok := s.chain.FindTransaction(id); ok {
+		return TxStatus{LocatedTx: tx, Status: "confirmed"}, nil
+	}
+	return TxStatus{}, fmt.Errorf("%w: transaction %s", ErrNotFound, id)
+}
+
+// SubmitTransaction 校验签名交易并加入交易池，随后广播给对端
+func (s *ChainService) SubmitTransaction(tx bc.Transaction) (string, error) {
+	if !tx.IsSigned() {
+		return "", fmt.Errorf("%w: transaction must be signed", ErrInvalidArgument)
+	}
+	if err := s.chain.AcceptTransaction(tx); err != nil {
+		if errors.Is(err, bc.ErrDuplicateTx) || errors.Is(err, bc.ErrStaleNonce) || errors.Is(err, bc.ErrNonceGap) {
+			return "", fmt.Errorf("%w: %v", ErrConflict, err)
+		}
+		return "", fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	return tx.ID, nil
+}
+
+// Mempool 分页返回交易池中的交易，按进入交易池的顺序
+func (s *ChainService) Mempool(p Page) TxPage {
+	p = p.normalize()
+	pending := s.chain.PendingTransactions()
+	start, end := p.slice(len(pending))
+	page := TxPage{Page: p, Total: len(pending), Transactions: []bc.LocatedTx{}}
+	for _, tx := range pending[start:end] {
+		page.Transactions = append(page.Transactions, bc.LocatedTx{Transaction: tx})
+	}
+	return page
+}
+
+// Account 返回账户余额和nonce
+func (s *ChainService) Account(address string) bc.Account {
+	return s.chain.AccountOf(address)
+}
+
+// AccountHistory 分页返回账户相关的已上链交易，最新的在前
+func (s *ChainService) AccountHistory(address string, p Page) TxPage {
+	p = p.normalize()
+	txs := s.chain.TransactionsOf(address)
+	start, end := p.slice(len(txs))
+	page := TxPage{Page: p, Total: len(txs), Transactions: txs[start:end]}
+	if page.Transactions == nil {
+		page.Transactions = []bc.LocatedTx{}
+	}
+	return page
+}
+
+// Nodes 返回所有节点的只读副本，按ID排序
+func (s *ChainService) Nodes() []network.NodeView {
+	return NodeViews()
+}
+
+// Node 按ID返回节点的只读副本
+func (s *ChainService) Node(id string) (network.NodeView, error) {
+	return ViewNode(id)
+}
+
+// NodeHealth 返回节点最近的健康记录
+func (s *ChainService) NodeHealth(id string) ([]HealthSample, error) {
+	return HealthHistory(id)
+}
+
+// Anchor 返回当前锚节点的只读副本
+func (s *ChainService) Anchor() (network.NodeView, error) {
+	anchor := global.AnchorNode
+	if anchor == nil {
+		return network.NodeView{}, fmt.Errorf("%w: no anchor has been elected", ErrNotFound)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return anchor.View(), nil
+}
+
+// Ring 返回一致性哈希环
+func (s *ChainService) Ring() RingInfo {
+	return RingInfo{Members: hash.Members(), Snapshot: hash.TakeSnapshot()}
+}
+
+// Locate 返回键在一致性哈希环上的n个副本节点，第一个为主副本
+func (s *ChainService) Locate(key string, n int) ([]string, error) {
+	if key == "" {
+		return nil, fmt.Errorf("%w: key is required", ErrInvalidArgument)
+	}
+	if n <= 0 {
+		n = config.ReplicationFactor
+	}
+	ids, err := hash.GetReplicas(key, n, NodeZone)
+	if len(ids) == 0 {
+		if err == nil {
+			err = errors.New("ring is empty")
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return ids, nil
+}
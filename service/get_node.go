@@ -2,11 +2,21 @@ package service
 
 import (
 	"blockchain/global"
+	"blockchain/internal/hash"
 	"blockchain/internal/network"
+	"blockchain/pkg/config"
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
 	"sync"
 )
 
-var mu sync.Mutex
+var (
+	mu sync.Mutex
+
+	ErrNodeExists = errors.New("node already registered")
+)
 
 func GetNodeByID(nodeID string) *network.Node {
 	if n, ok := global.NodesMap[nodeID]; ok {
@@ -15,11 +25,55 @@ func GetNodeByID(nodeID string) *network.Node {
 	return nil
 }
 
+// NodeZone 返回节点所在故障域，节点不存在时返回空串
+func NodeZone(nodeID string) string {
+	if n := GetNodeByID(nodeID); n != nil {
+		return n.Zone
+	}
+	return ""
+}
+
 func IsCurrentNodeAnchor(nodeID string) bool {
+	mu.Lock()
+	defer mu.Unlock()
 	n := GetNodeByID(nodeID)
 	return n != nil && n.IsAnchor
 }
 
+// SetAnchor 把nodeID标记为唯一的锚节点，其余节点的锚节点标记全部清除；nodeID为空表示没有锚节点
+func SetAnchor(nodeID string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for id, n := range global.NodesMap {
+		n.IsAnchor = id == nodeID
+	}
+}
+
+// RegisterNode 校验节点ID由其公钥派生后，加入全局节点表和一致性哈希环
+func RegisterNode(node *network.Node) error {
+	if err := network.VerifyNodeID(node.ID, node.PublicKey); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if existing, exists := global.NodesMap[node.ID]; exists {
+		if !bytes.Equal(existing.PublicKey, node.PublicKey) {
+			return network.ErrIdentityMismatch
+		}
+		return ErrNodeExists
+	}
+	if quota, ok := config.NodeQuotas[node.ID]; ok {
+		node.QuotaBytes = quota
+	}
+	global.NodesMap[node.ID] = node
+	node.CalculateScore(node)
+	recordHealth(node)
+	hash.AddNode(node.ID)
+	refreshRingWeights()
+	return nil
+}
+
 // GetAllNodes 获取所有节点
 func GetAllNodes() []*network.Node {
 	mu.Lock()
@@ -32,12 +86,71 @@ func GetAllNodes() []*network.Node {
 	return nodes
 }
 
+// NodeViews 在节点表锁内复制所有节点，按ID排序
+func NodeViews() []network.NodeView {
+	mu.Lock()
+	defer mu.Unlock()
+	views := make([]network.NodeView, 0, len(global.NodesMap))
+	for _, n := range global.NodesMap {
+		views = append(views, n.View())
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].ID < views[j].ID })
+	return views
+}
+
+// ViewNode 在节点表锁内复制一个节点
+func ViewNode(nodeID string) (network.NodeView, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	n, ok := global.NodesMap[nodeID]
+	if !ok {
+		return network.NodeView{}, fmt.Errorf("%w: node %s", ErrNotFound, nodeID)
+	}
+	return n.View(), nil
+}
+
 // AddContribution adds contribution to a node
 func AddContribution(nodeID string, delta float64) {
 	mu.Lock()
 	defer mu.Unlock()
 	if n, ok := global.NodesMap[nodeID]; ok {
 		n.Contribution += delta
-		n.CalculateScore(n)
+		n.CalculateScore(n) // 环上权重由迁移器周期性调整，这里只更新评分
 	}
 }
+
+// RecordChallenge 记录节点存储证明挑战的结果: 失败时扣减贡献值，
+// 连续失败达到 config.MaxChallengeFailures 次后节点被暂停(见Node.Suspended)，通过时清零失败计数
+func RecordChallenge(nodeID string, passed bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	n, ok := global.NodesMap[nodeID]
+	if !ok {
+		return
+	}
+	if passed {
+		if !n.Suspended() {
+			n.ChallengeFailures = 0
+			return
+		}
+		n.ChallengeFailures = 0
+	} else {
+		n.ChallengeFailures++
+		n.Contribution -= config.ChallengePenalty
+	}
+	n.LastHealth = n.CheckHealth()
+	n.CalculateScore(n)
+	recordHealth(n)
+}
+
+// suspended 判断节点是否因挑战失败被暂停，作为默认环放置时的排除条件
+func suspended(nodeID string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	n, ok := global.NodesMap[nodeID]
+	return ok && n.Suspended()
+}
+
+func init() {
+	hash.SetExclude(suspended)
+}
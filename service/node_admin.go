@@ -0,0 +1,127 @@
+package service
+
+import (
+	"blockchain/global"
+	"blockchain/internal/hash"
+	"blockchain/internal/network"
+	"blockchain/pkg/config"
+	"fmt"
+	"time"
+)
+
+// HealthSample 一次健康检查的结果
+type HealthSample struct {
+	Time   int64                `json:"time"`
+	Health network.HealthStatus `json:"health"`
+	Score  float64              `json:"score"`
+}
+
+// NodeSpec 节点可修改的属性，空字段表示不修改；Labels中值为空串的键被删除
+type NodeSpec struct {
+	Zone   *string           `json:"zone,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Weight *int              `json:"weight,omitempty"` // 0表示恢复自动计算
+}
+
+var healthHistory = make(map[string][]HealthSample) // 节点ID -> 健康记录，由mu保护
+
+// recordHealth 追加一条健康记录，调用方需持有mu
+func recordHealth(n *network.Node) {
+	h := append(healthHistory[n.ID], HealthSample{Time: time.Now().Unix(), Health: n.LastHealth, Score: n.Score})
+	if len(h) > config.HealthHistorySize {
+		h = h[len(h)-config.HealthHistorySize:]
+	}
+	healthHistory[n.ID] = h
+}
+
+// HealthHistory 返回节点的健康记录，按时间从旧到新
+func HealthHistory(nodeID string) ([]HealthSample, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := global.NodesMap[nodeID]; !ok {
+		return nil, fmt.Errorf("%w: node %s", ErrNotFound, nodeID)
+	}
+	return append([]HealthSample{}, healthHistory[nodeID]...), nil
+}
+
+// SetDraining 排空时把节点移出哈希环，不再接收新区块；取消排空时重新加入
+func SetDraining(nodeID string, draining bool) error {
+	mu.Lock()
+	defer mu.Unlock()
+	n, ok := global.NodesMap[nodeID]
+	if !ok {
+		return fmt.Errorf("%w: node %s", ErrNotFound, nodeID)
+	}
+	if n.Draining == draining {
+		return nil
+	}
+	n.Draining = draining
+	if draining {
+		hash.RemoveNode(nodeID)
+	} else {
+		hash.AddNode(nodeID)
+	}
+	refreshRingWeights()
+	return nil
+}
+
+// UnregisterNode 把节点从全局节点表和哈希环中删除
+func UnregisterNode(nodeID string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := global.NodesMap[nodeID]; !ok {
+		return fmt.Errorf("%w: node %s", ErrNotFound, nodeID)
+	}
+	delete(global.NodesMap, nodeID)
+	delete(healthHistory, nodeID)
+	hash.RemoveNode(nodeID)
+	refreshRingWeights()
+	return nil
+}
+
+// UpdateNode 修改节点的故障域、标签和容量权重，返回修改后的节点
+func UpdateNode(nodeID string, spec NodeSpec) (network.NodeView, error) {
+	if spec.Weight != nil && (*spec.Weight < 0 || *spec.Weight > config.MaxVirtualNodes) {
+		return network.NodeView{}, fmt.Errorf("%w: weight must be between 0 and %d", ErrInvalidArgument, config.MaxVirtualNodes)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	n, ok := global.NodesMap[nodeID]
+	if !ok {
+		return network.NodeView{}, fmt.Errorf("%w: node %s", ErrNotFound, nodeID)
+	}
+	if spec.Zone != nil {
+		n.Zone = *spec.Zone
+	}
+	if n.Labels == nil && len(spec.Labels) > 0 {
+		n.Labels = make(map[string]string)
+	}
+	for k, v := range spec.Labels {
+		if v == "" {
+			delete(n.Labels, k)
+		} else {
+			n.Labels[k] = v
+		}
+	}
+	if spec.Weight != nil {
+		n.Weight = *spec.Weight
+	}
+	refreshRingWeights()
+	return n.View(), nil
+}
+
+// RescoreNode 重新检查节点健康状态并计算评分，同时更新其在哈希环上的权重
+func RescoreNode(nodeID string) (network.NodeView, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	n, ok := global.NodesMap[nodeID]
+	if !ok {
+		return network.NodeView{}, fmt.Errorf("%w: node %s", ErrNotFound, nodeID)
+	}
+	n.LastHealth = n.CheckHealth()
+	n.CalculateScore(n)
+	recordHealth(n)
+	refreshRingWeights()
+	return n.View(), nil
+}
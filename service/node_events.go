@@ -0,0 +1,79 @@
+package service
+
+import (
+	"blockchain/global"
+	"blockchain/internal/events"
+	"time"
+)
+
+// NodeUpdate 节点得分或健康状态变化事件
+type NodeUpdate struct {
+	NodeID       string  `json:"nodeId"`
+	Score        float64 `json:"score"`
+	Contribution float64 `json:"contribution"`
+	Health       string  `json:"health"`
+	HealthScore  float64 `json:"healthScore"`
+	IsAnchor     bool    `json:"isAnchor"`
+	Removed      bool    `json:"removed,omitempty"`
+}
+
+// AnchorChange 锚节点变化事件
+type AnchorChange struct {
+	Previous string `json:"previous,omitempty"`
+	Current  string `json:"current,omitempty"`
+}
+
+// WatchNodes 周期性比较节点状态和锚节点，有变化时发布到事件总线
+func WatchNodes(bus *events.Bus, interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		last := make(map[string]NodeUpdate)
+		anchor := ""
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			last, anchor = publishNodeChanges(bus, last, anchor)
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// publishNodeChanges 发布与上一轮相比的变化，返回本轮状态
+func publishNodeChanges(bus *events.Bus, last map[string]NodeUpdate, anchor string) (map[string]NodeUpdate, string) {
+	current := make(map[string]NodeUpdate)
+	mu.Lock()
+	for id, n := range global.NodesMap {
+		current[id] = NodeUpdate{
+			NodeID:       id,
+			Score:        n.Score,
+			Contribution: n.Contribution,
+			Health:       n.LastHealth.Status,
+			HealthScore:  n.LastHealth.Score,
+			IsAnchor:     n.IsAnchor,
+		}
+	}
+	mu.Unlock()
+
+	for id, u := range current {
+		if prev, ok := last[id]; !ok || prev != u {
+			bus.Publish(events.Event{Topic: events.TopicNodes, Data: u, Node: id})
+		}
+	}
+	for id := range last {
+		if _, ok := current[id]; !ok {
+			bus.Publish(events.Event{Topic: events.TopicNodes, Data: NodeUpdate{NodeID: id, Removed: true}, Node: id})
+		}
+	}
+
+	now := ""
+	if a := global.AnchorNode; a != nil {
+		now = a.ID
+	}
+	if now != anchor {
+		bus.Publish(events.Event{Topic: events.TopicAnchor, Data: AnchorChange{Previous: anchor, Current: now}})
+	}
+	return current, now
+}
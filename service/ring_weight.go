@@ -0,0 +1,75 @@
+package service
+
+import (
+	"blockchain/global"
+	"blockchain/internal/hash"
+	"blockchain/internal/network"
+	"blockchain/pkg/config"
+	"math"
+)
+
+// VirtualNodes 根据节点可用磁盘和相对评分计算其在哈希环上的虚拟节点数:
+// 磁盘按 config.ReferenceDiskGB 线性缩放，评分相对全网平均值缩放并限制在[0.5, 2]；
+// 手动设置了Weight的节点直接使用该值
+func VirtualNodes(node *network.Node, meanScore float64) int {
+	if node.Weight > 0 {
+		return int(math.Min(math.Max(float64(node.Weight), config.MinVirtualNodes), config.MaxVirtualNodes))
+	}
+	weight := float64(config.DefaultVirtualNodes) * node.Disk / config.ReferenceDiskGB
+	if meanScore > 0 {
+		weight *= math.Min(math.Max(node.Score/meanScore, 0.5), 2)
+	}
+	return int(math.Min(math.Max(math.Round(weight), config.MinVirtualNodes), config.MaxVirtualNodes))
+}
+
+// refreshRingWeights 按当前磁盘与评分重新计算所有节点的虚拟节点数，调用方需持有mu
+func refreshRingWeights() {
+	if len(global.NodesMap) == 0 {
+		return
+	}
+	total := 0.0
+	for _, n := range global.NodesMap {
+		total += n.Score
+	}
+	mean := total / float64(len(global.NodesMap))
+	for id, n := range global.NodesMap {
+		hash.SetWeight(id, VirtualNodes(n, mean))
+	}
+}
+
+// RingWeightChanges 返回按当前评分和磁盘计算的新虚拟节点数与环上权重相差超过hysteresis比例(至少1个)的节点
+func RingWeightChanges(hysteresis float64) map[string]int {
+	mu.Lock()
+	defer mu.Unlock()
+	if len(global.NodesMap) == 0 {
+		return nil
+	}
+	total := 0.0
+	for _, n := range global.NodesMap {
+		total += n.Score
+	}
+	mean := total / float64(len(global.NodesMap))
+
+	changes := make(map[string]int)
+	for id, n := range global.NodesMap {
+		if n.Draining {
+			continue
+		}
+		current, proposed := hash.Weight(id), VirtualNodes(n, mean)
+		if math.Abs(float64(proposed-current)) >= math.Max(1, hysteresis*float64(current)) {
+			changes[id] = proposed
+		}
+	}
+	return changes
+}
+
+// ApplyRingWeights 设置节点在哈希环上的虚拟节点数，应在迁移器的OnMembershipChange中调用
+func ApplyRingWeights(weights map[string]int) {
+	mu.Lock()
+	defer mu.Unlock()
+	for id, w := range weights {
+		if n, ok := global.NodesMap[id]; ok && !n.Draining {
+			hash.SetWeight(id, w)
+		}
+	}
+}
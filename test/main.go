@@ -2,27 +2,38 @@ package main
 
 import (
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
+	"os"
 	"strconv"
 )
 
 func main() {
-	// 定义请求URL
-	baseurl := "http://localhost:8080/add?id=Node_"
+	// 定义请求URL，添加节点需要operator角色的API密钥
+	baseurl := "http://localhost:8080/api/v1/nodes?addr=127.0.0.1:900"
+	apiKey := os.Getenv("API_KEY")
 
 	for i := 1; i <= 5; i++ {
 		url := baseurl + strconv.Itoa(i)
-		// 发送GET请求
-		resp, err := http.Get(url)
+		req, err := http.NewRequest(http.MethodPost, url, nil)
 		if err != nil {
-			fmt.Printf("Error making GET request: %v\n", err)
+			fmt.Printf("Error creating request: %v\n", err)
+			return
+		}
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+
+		// 发送POST请求
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			fmt.Printf("Error making POST request: %v\n", err)
 			return
 		}
-		defer resp.Body.Close()
 
 		// 读取响应体
-		body, err := ioutil.ReadAll(resp.Body)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
 		if err != nil {
 			fmt.Printf("Error reading response body: %v\n", err)
 			return
@@ -6,14 +6,14 @@ function renderCharts(data) {
     const contrib = [];
     const bg = [];
 
-    Object.entries(data).forEach(([nodeId, node]) => {
+    Object.values(data).forEach(node => {
         const score = typeof node.score === 'number' ? node.score.toFixed(2) : "0.00";
         const contribution = typeof node.contribution === 'number' ? node.contribution.toFixed(2) : "0.00";
 
-        labels.push(nodeId);
+        labels.push(node.nodeId);
         scores.push(score);
         contrib.push(contribution);
-        bg.push(node.is_anchor ? 'rgba(255,99,132,0.7)' : 'rgba(54,162,235,0.7)');
+        bg.push(node.isAnchor ? 'rgba(255,99,132,0.7)' : 'rgba(54,162,235,0.7)');
     });
 
     if (window.scoreChart && typeof window.scoreChart.destroy === 'function' && window.scoreChart instanceof Chart) {
@@ -48,6 +48,10 @@ function renderCharts(data) {
         }
     });
 
+    if (window.contribChart && typeof window.contribChart.destroy === 'function' && window.contribChart instanceof Chart) {
+        window.contribChart.destroy();
+    }
+
     window.contribChart = new Chart(document.getElementById("contribChart"), {
         type: 'bar',
         data: {
@@ -71,12 +75,59 @@ function renderCharts(data) {
     });
 }
 
-function fetchAndRender() {
-    fetch("/list")
+// 节点ID -> 节点状态，初始数据来自/api/v1/nodes，之后由订阅推送的变化更新
+const nodes = {};
+let renderPending = false;
+
+function scheduleRender() {
+    if (renderPending) {
+        return;
+    }
+    renderPending = true;
+    requestAnimationFrame(() => {
+        renderPending = false;
+        renderCharts(nodes);
+    });
+}
+
+function loadNodes() {
+    return fetch("/api/v1/nodes")
         .then(res => res.json())
-        .then(data => renderCharts(data))
+        .then(list => {
+            Object.keys(nodes).forEach(id => delete nodes[id]);
+            list.forEach(n => {
+                nodes[n.id] = {
+                    nodeId: n.id,
+                    score: n.score,
+                    contribution: n.contribution,
+                    isAnchor: n.isAnchor,
+                };
+            });
+            scheduleRender();
+        })
         .catch(err => console.error("Fetch error:", err));
 }
 
-fetchAndRender();
-setInterval(fetchAndRender, 5000);
\ No newline at end of file
+function subscribe() {
+    const source = new EventSource("/api/v1/subscribe?topics=nodes,anchor");
+    source.addEventListener("nodes", e => {
+        const update = JSON.parse(e.data).data;
+        if (update.removed) {
+            delete nodes[update.nodeId];
+        } else {
+            nodes[update.nodeId] = update;
+        }
+        scheduleRender();
+    });
+    source.addEventListener("anchor", e => {
+        const change = JSON.parse(e.data).data;
+        Object.values(nodes).forEach(n => { n.isAnchor = n.nodeId === change.current; });
+        scheduleRender();
+    });
+    source.addEventListener("error", () => {
+        // 浏览器会自动重连，重连期间可能错过变化，连上后重新拉取全量数据
+        source.addEventListener("open", loadNodes, { once: true });
+    });
+}
+
+loadNodes().then(subscribe);